@@ -0,0 +1,31 @@
+package ctxdb
+
+import (
+	"context"
+	"sync"
+)
+
+// TryAcquire reserves a connection slot of the pool, waiting for one until ctx
+// is done, so a scheduler can check the capacity before starting expensive
+// work. ok is false if no slot is acquired. The slot must be released with the
+// returned func, which is idempotent, whether the work is done or skipped.
+//
+// The reservation only holds the slot, operations of the DB run with their
+// own slots, so the work should release it before using the DB, or account for
+// it in the pool size.
+func (db *DB) TryAcquire(ctx context.Context) (release func(), ok bool) {
+	if db.check() != nil || db.getConns() == nil {
+		return func() {}, false
+	}
+
+	if err := db.acquire(ctx); err != nil {
+		return func() {}, false
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			db.release()
+		})
+	}, true
+}
@@ -0,0 +1,45 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTryAcquire(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	release, ok := db.TryAcquire(ctx)
+	if !ok {
+		t.Fatal("expected a slot to be acquired")
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*20)
+	defer cancel()
+
+	if _, ok := db.TryAcquire(timeoutCtx); ok {
+		t.Fatal("expected no slot while the pool is reserved")
+	}
+
+	release()
+	release() // idempotent
+
+	if len(db.sem) != 1 {
+		t.Fatalf("expected 1 free slot, got: %d", len(db.sem))
+	}
+
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
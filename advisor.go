@@ -0,0 +1,261 @@
+package ctxdb
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimeoutAdvisor configures the timeout suggestions of the labels, computed
+// from their observed latencies, set with WithTimeoutAdvisor.
+type TimeoutAdvisor struct {
+	// Factor multiplies the p99.9 latency of a label into its suggested
+	// timeout, defaults to 2.
+	Factor float64
+
+	// Window is the number of the latest latencies kept per label, defaults
+	// to 1000.
+	Window int
+
+	// MinSamples is the min number of the latencies of a label before a
+	// timeout is suggested for it, defaults to 100.
+	MinSamples int
+
+	// Min and Max bound the suggested timeouts, if they are set.
+	Min time.Duration
+	Max time.Duration
+
+	// ApplyEvery applies the suggested timeouts to the policy on every
+	// interval, if it is set. Retries of the label policies are kept.
+	ApplyEvery time.Duration
+}
+
+// TimeoutSuggestion is the suggested timeout of a label.
+type TimeoutSuggestion struct {
+	Label     string        // empty for the operations without a label
+	Samples   int           // number of the latencies
+	P999      time.Duration // 99.9th percentile of the latencies
+	Suggested time.Duration // P999 times the factor, within the bounds
+	Current   time.Duration // timeout of the label in the policy, zero if none
+}
+
+// WithTimeoutAdvisor records the latencies of Exec, Query and QueryRow by
+// their labels, set with WithLabel, and suggests their timeouts, see
+// SuggestedTimeouts. Failed operations are not recorded, neither the timed out
+// ones, since their latencies are the timeouts themselves and applying them
+// would grow the timeouts on every interval. Suggestions are applied to the
+// policy by ApplySuggestedTimeouts, and on every interval if ApplyEvery is
+// set, until the DB is closed.
+func WithTimeoutAdvisor(a TimeoutAdvisor) Option {
+	return func(db *DB) {
+		if a.Factor <= 0 {
+			a.Factor = 2
+		}
+
+		if a.Window <= 0 {
+			a.Window = 1000
+		}
+
+		if a.MinSamples <= 0 {
+			a.MinSamples = 100
+		}
+
+		db.timeoutAdvisor = &timeoutAdvisor{
+			config:    a,
+			latencies: make(map[string]*latencyWindow),
+			stop:      make(chan struct{}),
+		}
+	}
+}
+
+// timeoutAdvisor holds the latencies of the labels
+type timeoutAdvisor struct {
+	config TimeoutAdvisor
+	stop   chan struct{} // closed by Close
+
+	mu        sync.Mutex
+	latencies map[string]*latencyWindow
+}
+
+// latencyWindow is a ring buffer of the latest latencies of a label
+type latencyWindow struct {
+	samples []time.Duration
+	next    int // index of the next sample
+}
+
+// SuggestedTimeouts returns the timeout suggestions of the labels having
+// enough latencies, sorted by their labels. It is nil if the advisor is not
+// enabled.
+func (db *DB) SuggestedTimeouts() []TimeoutSuggestion {
+	if db == nil || db.timeoutAdvisor == nil {
+		return nil
+	}
+
+	a := db.timeoutAdvisor
+
+	a.mu.Lock()
+	labels := make([]string, 0, len(a.latencies))
+	for label, w := range a.latencies {
+		if len(w.samples) >= a.config.MinSamples {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+
+	suggestions := make([]TimeoutSuggestion, len(labels))
+	for i, label := range labels {
+		samples := a.latencies[label].samples
+		p999 := percentile(samples, 0.999)
+		suggestions[i] = TimeoutSuggestion{
+			Label:     label,
+			Samples:   len(samples),
+			P999:      p999,
+			Suggested: a.bound(time.Duration(float64(p999) * a.config.Factor)),
+		}
+	}
+	a.mu.Unlock()
+
+	for i := range suggestions {
+		if lp, ok := db.labelPolicy(WithLabel(context.Background(), suggestions[i].Label)); ok {
+			suggestions[i].Current = time.Duration(lp.Timeout)
+		}
+	}
+
+	return suggestions
+}
+
+// ApplySuggestedTimeouts sets the suggested timeouts as the timeouts of the
+// labels in a copy of the policy, and returns the applied suggestions. The
+// suggestion of the operations without a label is set as the default
+// timeout of the policy.
+func (db *DB) ApplySuggestedTimeouts() []TimeoutSuggestion {
+	suggestions := db.SuggestedTimeouts()
+	if len(suggestions) == 0 {
+		return nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	p := &Policy{Labels: make(map[string]LabelPolicy)}
+	if db.policy != nil {
+		p.Default = db.policy.Default
+		for label, lp := range db.policy.Labels {
+			p.Labels[label] = lp
+		}
+	}
+
+	for _, s := range suggestions {
+		if s.Label == "" {
+			p.Default.Timeout = Duration(s.Suggested)
+			continue
+		}
+
+		lp, ok := p.Labels[s.Label]
+		if !ok {
+			// retries of the default are kept for the new labels
+			lp = p.Default
+		}
+
+		lp.Timeout = Duration(s.Suggested)
+		p.Labels[s.Label] = lp
+	}
+
+	db.policy = p
+	return suggestions
+}
+
+// recordLatency records the latency of a successful operation, if the advisor
+// is enabled
+func (db *DB) recordLatency(ctx context.Context, op string, d time.Duration, err error) {
+	a := db.timeoutAdvisor
+	if a == nil || err != nil {
+		return
+	}
+
+	switch op {
+	case "Exec", "Query", "QueryRow":
+	default:
+		// policies are applied to the operations of the DB
+		return
+	}
+
+	label := LabelFromContext(ctx)
+
+	a.mu.Lock()
+	w, ok := a.latencies[label]
+	if !ok {
+		w = &latencyWindow{}
+		a.latencies[label] = w
+	}
+
+	if len(w.samples) < a.config.Window {
+		w.samples = append(w.samples, d)
+	} else {
+		w.samples[w.next] = d
+		w.next = (w.next + 1) % len(w.samples)
+	}
+	a.mu.Unlock()
+}
+
+// applySuggestions applies the suggested timeouts on every interval until
+// the advisor is stopped
+func (db *DB) applySuggestions() {
+	a := db.timeoutAdvisor
+
+	ticker := time.NewTicker(a.config.ApplyEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+		}
+
+		db.ApplySuggestedTimeouts()
+	}
+}
+
+// stopTimeoutAdvisor stops applying the suggestions if the advisor is enabled
+func (db *DB) stopTimeoutAdvisor() {
+	if db.timeoutAdvisor != nil {
+		close(db.timeoutAdvisor.stop)
+	}
+}
+
+// bound clamps d into the bounds of the advisor
+func (a *timeoutAdvisor) bound(d time.Duration) time.Duration {
+	if a.config.Min > 0 && d < a.config.Min {
+		return a.config.Min
+	}
+
+	if a.config.Max > 0 && d > a.config.Max {
+		return a.config.Max
+	}
+
+	return d
+}
+
+// percentile returns the pth percentile of the given latencies, by the
+// nearest rank
+func percentile(samples []time.Duration, p float64) time.Duration {
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Sort(durations(sorted))
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
@@ -0,0 +1,99 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSuggestedTimeouts(t *testing.T) {
+	db := &DB{}
+	WithTimeoutAdvisor(TimeoutAdvisor{
+		Factor:     2,
+		Window:     1000,
+		MinSamples: 10,
+		Max:        time.Second,
+	})(db)
+
+	db.SetPolicy(&Policy{
+		Labels: map[string]LabelPolicy{
+			"users/get": {Timeout: Duration(time.Second), Retries: 2},
+		},
+	})
+
+	ctx := WithLabel(context.Background(), "users/get")
+	for i := 1; i <= 1000; i++ {
+		db.recordLatency(ctx, "Query", time.Duration(i)*time.Millisecond, nil)
+	}
+
+	// failed, timed out operations and the operations of other labels are
+	// not recorded
+	db.recordLatency(ctx, "Query", time.Hour, errors.New("syntax error"))
+	db.recordLatency(ctx, "Query", time.Hour, context.DeadlineExceeded)
+	db.recordLatency(ctx, "Tx.Query", time.Hour, nil)
+
+	slow := WithLabel(context.Background(), "reports")
+	for i := 0; i < 10; i++ {
+		db.recordLatency(slow, "Exec", time.Minute, nil)
+	}
+
+	timedOut := WithLabel(context.Background(), "exports")
+	for i := 0; i < 10; i++ {
+		db.recordLatency(timedOut, "Exec", time.Minute, context.DeadlineExceeded)
+	}
+
+	db.recordLatency(context.Background(), "Exec", time.Millisecond, nil)
+
+	suggestions := db.SuggestedTimeouts()
+	if len(suggestions) != 2 {
+		t.Fatalf("expected 2 suggestions, got: %+v", suggestions)
+	}
+
+	reports, users := suggestions[0], suggestions[1]
+	if reports.Label != "reports" || reports.Suggested != time.Second {
+		t.Fatalf("expected the max timeout for reports, got: %+v", reports)
+	}
+
+	if users.P999 != 999*time.Millisecond || users.Suggested != time.Second || users.Current != time.Second {
+		t.Fatalf("expected the p99.9 of users/get, got: %+v", users)
+	}
+
+	db.timeoutAdvisor.config.Max = 0
+	db.ApplySuggestedTimeouts()
+
+	lp, _ := db.labelPolicy(ctx)
+	if time.Duration(lp.Timeout) != 1998*time.Millisecond || lp.Retries != 2 {
+		t.Fatalf("expected the suggested timeout to be applied, got: %+v", lp)
+	}
+
+	if lp, _ := db.labelPolicy(slow); time.Duration(lp.Timeout) != 2*time.Minute {
+		t.Fatalf("expected the suggested timeout for the new label, got: %+v", lp)
+	}
+}
+
+func TestWithTimeoutAdvisor(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithTimeoutAdvisor(TimeoutAdvisor{MinSamples: 5, Min: time.Millisecond * 50, ApplyEvery: time.Millisecond * 50}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := WithLabel(context.Background(), "ping")
+	for i := 0; i < 5; i++ {
+		if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	if lp, ok := db.labelPolicy(ctx); !ok || lp.Timeout < Duration(time.Millisecond*50) {
+		t.Fatalf("expected the suggestion to be applied, got: %+v", lp)
+	}
+}
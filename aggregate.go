@@ -0,0 +1,146 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// reduceCheckInterval is the number of rows processed between two ctx checks
+// while reducing the rows
+const reduceCheckInterval = 100
+
+// ScanFunc copies the columns in the current row into the values pointed at by
+// dest.
+type ScanFunc func(dest ...interface{}) error
+
+// Reduce iterates over the rows, calling f for each row with a ScanFunc for the
+// current row. Iteration stops on the first error returned from f. Rows are
+// closed after the iteration.
+//
+// Rows are iterated in one go, not calling Next for every row, given ctx is
+// checked periodically in the meantime. On timeout or cancel case, closes the
+// underlying connection.
+func (rs *Rows) Reduce(ctx context.Context, f func(scan ScanFunc) error) error {
+	if err := rs.check(); err != nil {
+		return err
+	}
+
+	if err := rs.guard.enter(); err != nil {
+		return err
+	}
+	defer rs.guard.leave()
+
+	rs.checkExpired()
+	if rs.err != nil {
+		return rs.err
+	}
+
+	done := make(chan struct{}, 1)
+	var err error
+	g := func() {
+		err = rs.reduce(ctx, f)
+		close(done)
+	}
+
+	if opErr := rs.handle(ctx, g, done); opErr != nil {
+		rs.err = opErr
+		return rs.release(opErr)
+	}
+
+	closeErr := rs.close(ctx)
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
+
+func (rs *Rows) reduce(ctx context.Context, f func(scan ScanFunc) error) error {
+	scan := func(dest ...interface{}) error {
+		return rs.rows.Scan(prepareDest(dest)...)
+	}
+
+	for i := 1; rs.rows.Next(); i++ {
+		if i%reduceCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if rs.origin != nil && rs.origin.Err() != nil {
+				return rs.origin.Err()
+			}
+
+			if rs.isExpired() {
+				return ErrIterationTimeout
+			}
+		}
+
+		if err := f(scan); err != nil {
+			return err
+		}
+	}
+
+	return rs.rows.Err()
+}
+
+// Count consumes the rows and returns the number of them.
+func (rs *Rows) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := rs.Reduce(ctx, func(scan ScanFunc) error {
+		count++
+		return nil
+	})
+
+	return count, err
+}
+
+// Sum consumes the rows and returns the sum of their values. Rows must have a
+// single numeric column, NULL values are skipped.
+func (rs *Rows) Sum(ctx context.Context) (float64, error) {
+	var sum float64
+	err := rs.Reduce(ctx, func(scan ScanFunc) error {
+		var val sql.NullFloat64
+		if err := scan(&val); err != nil {
+			return err
+		}
+
+		if val.Valid {
+			sum += val.Float64
+		}
+
+		return nil
+	})
+
+	return sum, err
+}
+
+// GroupReduce consumes the rows, grouping them with the key scanned by key func
+// and reducing each row into the accumulator of its group with reduce func.
+// Accumulator is nil for the first row of every group. Returns accumulators by
+// their keys.
+func (rs *Rows) GroupReduce(
+	ctx context.Context,
+	key func(scan ScanFunc) (interface{}, error),
+	reduce func(acc interface{}, scan ScanFunc) (interface{}, error),
+) (map[interface{}]interface{}, error) {
+	groups := make(map[interface{}]interface{})
+	err := rs.Reduce(ctx, func(scan ScanFunc) error {
+		k, err := key(scan)
+		if err != nil {
+			return err
+		}
+
+		acc, err := reduce(groups[k], scan)
+		if err != nil {
+			return err
+		}
+
+		groups[k] = acc
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
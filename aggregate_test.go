@@ -0,0 +1,132 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func prepareAggregateData(t *testing.T, db *DB) {
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	// prepare data set
+	for i := 1; i < 5; i++ {
+		if _, err := db.Exec(ctx, insertSQLStatement, i, nil, i%2); err != nil {
+			t.Fatalf("err while adding null item: %s", err.Error())
+		}
+	}
+}
+
+func TestRowsCount(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	prepareAggregateData(t, db)
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT int64_val FROM nullable")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	count, err := rows.Count(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 4 {
+		t.Fatalf("expected 4, got: %d", count)
+	}
+}
+
+func TestRowsSum(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	prepareAggregateData(t, db)
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT int64_val FROM nullable")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	sum, err := rows.Sum(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if sum != 10 {
+		t.Fatalf("expected 10, got: %f", sum)
+	}
+}
+
+func TestRowsGroupReduce(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	prepareAggregateData(t, db)
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT float64_val, int64_val FROM nullable")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	key := func(scan ScanFunc) (interface{}, error) {
+		var k, v int64
+		err := scan(&k, &v)
+		return k, err
+	}
+
+	reduce := func(acc interface{}, scan ScanFunc) (interface{}, error) {
+		var k, v int64
+		if err := scan(&k, &v); err != nil {
+			return nil, err
+		}
+
+		if acc == nil {
+			return v, nil
+		}
+
+		return acc.(int64) + v, nil
+	}
+
+	groups, err := rows.GroupReduce(ctx, key, reduce)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if groups[int64(0)] != int64(6) {
+		t.Fatalf("expected 6, got: %+v", groups[int64(0)])
+	}
+
+	if groups[int64(1)] != int64(4) {
+		t.Fatalf("expected 4, got: %+v", groups[int64(1)])
+	}
+}
+
+func TestRowsReduceWithTimeout(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	prepareAggregateData(t, db)
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT int64_val FROM nullable")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	timeout := time.Millisecond * 10
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = rows.Reduce(ctx, func(scan ScanFunc) error {
+		time.Sleep(timeout * 2)
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %s", err)
+	}
+}
@@ -0,0 +1,155 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Statement is a statement of a batch executed by Tx.ExecBatch.
+type Statement struct {
+	Query string
+	Args  []interface{}
+}
+
+// BatchError is returned by Tx.ExecBatch when a statement of the batch fails.
+type BatchError struct {
+	Index int // index of the failed statement in the batch
+	Query string
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("statement %d of the batch: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the error of the statement.
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// ExecBatch executes the statements in order on the connection of the
+// transaction, holding its lock once for the batch and waiting for the whole
+// batch with a single watch of the ctx, which is much cheaper than an Exec per
+// statement for the migration-style workloads. Returns the results of the
+// statements, or the results of the ones executed before the first failure
+// with a *BatchError. Transaction is not rolled back on a statement failure.
+// Named args and rebinding apply to the statements like Exec, but the
+// middlewares do not, since they wrap the single statements.
+//
+// If previous operations caused a sticky error returns it otherwise uses the
+// given ctx and its deadline to signal timeouts. On timeout or cancel case,
+// first tries to rollback the transaction then closes the underlying
+// connection, and no result is returned.
+func (tx *Tx) ExecBatch(ctx context.Context, statements []Statement) ([]*Result, error) {
+	if err := tx.check(); err != nil {
+		return nil, err
+	}
+
+	ctx, end := tx.db.startOp(ctx, tx.spanCtx, "Tx.ExecBatch", "", nil)
+	results, err := tx.execBatch(ctx, statements)
+	end(-1, err)
+
+	return results, err
+}
+
+func (tx *Tx) execBatch(ctx context.Context, statements []Statement) ([]*Result, error) {
+	tx.db.observe(ctx, "Tx.ExecBatch")
+
+	if err := tx.guard.enter(); err != nil {
+		return nil, err
+	}
+	defer tx.guard.leave()
+
+	bound := make([]Statement, len(statements))
+	for i, s := range statements {
+		query, args, err := tx.db.bindQueryArgs(s.Query, s.Args)
+		if err != nil {
+			return nil, &BatchError{Index: i, Query: s.Query, Err: err}
+		}
+
+		s = Statement{Query: query, Args: args}
+		bound[i] = s
+
+		if err := prepareArgs(s.Args); err != nil {
+			return nil, err
+		}
+
+		if err := tx.db.guardDDL(ctx, s.Query); err != nil {
+			return nil, err
+		}
+	}
+
+	statements = bound
+
+	if err := tx.db.guardDryRun(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.db.misuseNoDeadline(ctx, "Tx.ExecBatch"); err != nil {
+		return nil, err
+	}
+
+	tx.Lock()
+	defer tx.Unlock()
+
+	if err := tx.misuseTxDone("Tx.ExecBatch"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.sticky(); err != nil {
+		return nil, err
+	}
+
+	tx.idle.stop()
+	defer tx.idle.reset()
+
+	done := make(chan struct{}, 1)
+
+	res := make([]sql.Result, 0, len(statements))
+	var err error
+
+	go func() {
+		for _, s := range statements {
+			var r sql.Result
+			if r, err = txExec(ctx, tx.tx, tagQuery(ctx, s.Query), s.Args); err != nil {
+				break
+			}
+
+			res = append(res, r)
+		}
+		close(done)
+	}()
+
+	tx.watch(done)
+
+	opErr := wait(ctx, done)
+	if opErr == nil && err != nil && err == ctx.Err() {
+		// stopped by the driver on timeout or cancel case
+		opErr = err
+	}
+
+	if opErr != nil {
+		// statements executed before the timeout are not known
+		for _, s := range statements {
+			tx.record(s.Query, s.Args, opErr)
+		}
+
+		return nil, tx.fail(opErr)
+	}
+
+	results := make([]*Result, len(res))
+	for i, r := range res {
+		s := statements[i]
+		tx.record(s.Query, s.Args, nil)
+		results[i] = &Result{id: tx.id, res: r, query: s.Query}
+	}
+
+	if err != nil {
+		s := statements[len(res)]
+		tx.record(s.Query, s.Args, err)
+		return results, &BatchError{Index: len(res), Query: s.Query, Err: tx.db.mapDriverError(err)}
+	}
+
+	return results, nil
+}
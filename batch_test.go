@@ -0,0 +1,76 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTxExecBatch(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	results, err := tx.ExecBatch(ctx, []Statement{
+		{Query: "CREATE TEMP TABLE ctxdb_batch (id int)"},
+		{Query: "INSERT INTO ctxdb_batch VALUES ($1), ($2)", Args: []interface{}{1, 2}},
+		{Query: "DELETE FROM ctxdb_batch WHERE id = $1", Args: []interface{}{1}},
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got: %d", len(results))
+	}
+
+	if n, err := results[1].RowsAffected(ctx); err != nil || n != 2 {
+		t.Fatalf("expected 2 rows affected, got: %d, %v", n, err)
+	}
+
+	results, err = tx.ExecBatch(ctx, []Statement{
+		{Query: "INSERT INTO ctxdb_batch VALUES (3)"},
+		{Query: "INSERT INTO ctxdb_batch_missing VALUES (4)"},
+		{Query: "INSERT INTO ctxdb_batch VALUES (5)"},
+	})
+
+	batchErr, ok := err.(*BatchError)
+	if !ok || batchErr.Index != 1 {
+		t.Fatalf("expected the second statement to fail, got: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got: %d", len(results))
+	}
+}
+
+func TestTxExecBatchTimeout(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	tx, err := db.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	_, err = tx.ExecBatch(ctx, []Statement{
+		{Query: "SELECT 1"},
+		{Query: "SELECT pg_sleep(1)"},
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	if _, err := tx.Exec(context.Background(), "SELECT 1"); err != context.DeadlineExceeded {
+		t.Fatalf("expected the sticky error, got: %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package ctxdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrMixedArgs is returned by the operations given both the named and the
+// positional args.
+var ErrMixedArgs = errors.New("named and positional args can not be mixed")
+
+// Dialect is the SQL dialect of a database, it decides the placeholders which
+// the named args are rewritten to.
+type Dialect int
+
+const (
+	// DialectUnknown leaves the named args to the driver.
+	DialectUnknown Dialect = iota
+
+	// DialectPostgres uses the $1..$n placeholders.
+	DialectPostgres
+
+	// DialectMySQL uses the ? placeholders, like sqlite.
+	DialectMySQL
+)
+
+// DialectOf returns the dialect of the driver registered with the given name,
+// DialectUnknown if it is not known.
+func DialectOf(driver string) Dialect {
+	switch driver {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		return DialectPostgres
+	case "mysql", "sqlite3", "sqlite":
+		return DialectMySQL
+	default:
+		return DialectUnknown
+	}
+}
+
+// WithDialect sets the dialect of the database. Open detects it from the
+// driver name, NewWithDB and OpenWithFactory default to DialectUnknown.
+//
+// Exec, Query and QueryRow of the DB and its transactions, given only
+// sql.Named args, rewrite the :name params of the query to the positional
+// placeholders of the dialect, e.g.
+//
+//     db.Exec(ctx, "UPDATE users SET name = :name WHERE id = :id",
+//         sql.Named("id", id), sql.Named("name", name))
+//
+// Statements prepared with a named query can be executed with either the
+// named or the positional args. Params are not looked for in the quoted
+// strings and the comments.
+func WithDialect(d Dialect) Option {
+	return func(db *DB) {
+		db.dialect = d
+	}
+}
+
+// bind runs f with the query and the args of the operation rewritten to the
+// positional ones if it has named args, and rebound if rebinding is enabled
+func (db *DB) bind(f Executor) Executor {
+	return func(ctx context.Context, op *Op) error {
+		query, args, err := db.bindQueryArgs(op.Query, op.Args)
+		if err != nil {
+			return err
+		}
+
+		op.Query, op.Args = query, args
+		return f(ctx, op)
+	}
+}
+
+// bindQueryArgs rewrites the query and the args to the positional ones if the
+// args are named, and rebinds them if rebinding is enabled
+func (db *DB) bindQueryArgs(query string, args []interface{}) (string, []interface{}, error) {
+	query, args, err := bindNamed(db.dialect, query, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if db.rebind {
+		query, args = rebindArgs(db.dialect, query, args)
+	}
+
+	return query, args, nil
+}
+
+// bindStmt returns the query of a statement with its named params rewritten
+// to the positional ones, and rebound if rebinding is enabled, with the names
+// of the params
+func (db *DB) bindStmt(query string) (string, []string) {
+	query, names := bindQuery(db.dialect, query)
+	if db.rebind {
+		query = Rebind(db.dialect, query)
+	}
+
+	return query, names
+}
+
+// bindNamed rewrites the named params of the query and the named args to the
+// positional ones of the dialect, if the args are named
+func bindNamed(d Dialect, query string, args []interface{}) (string, []interface{}, error) {
+	if d == DialectUnknown {
+		return query, args, nil
+	}
+
+	values, err := namedValues(args)
+	if values == nil || err != nil {
+		return query, args, err
+	}
+
+	query, names := bindQuery(d, query)
+	args, err = bindArgs(names, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return query, args, nil
+}
+
+// namedValues returns the values of the named args by their names, nil if
+// none of the args are named
+func namedValues(args []interface{}) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	for i, arg := range args {
+		name, value, ok := namedArg(arg)
+		if !ok {
+			if values != nil {
+				return nil, ErrMixedArgs
+			}
+
+			continue
+		}
+
+		if values == nil {
+			if i > 0 {
+				return nil, ErrMixedArgs
+			}
+
+			values = make(map[string]interface{}, len(args))
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// bindQuery rewrites the named params of the query to the positional
+// placeholders of the dialect, and returns the names of the params in the
+// order of the placeholders. Params reused in a postgres query share their
+// placeholders.
+func bindQuery(d Dialect, query string) (string, []string) {
+	if d == DialectUnknown {
+		return query, nil
+	}
+
+	var names []string
+	indexes := make(map[string]int)
+	query = scanNamed(query, func(name string) string {
+		if d != DialectPostgres {
+			names = append(names, name)
+			return "?"
+		}
+
+		n, ok := indexes[name]
+		if !ok {
+			names = append(names, name)
+			n = len(names)
+			indexes[name] = n
+		}
+
+		return "$" + strconv.Itoa(n)
+	})
+
+	return query, names
+}
+
+// bindArgs returns the values of the named params in order
+func bindArgs(names []string, values map[string]interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		value, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("named arg %s is missing", name)
+		}
+
+		args[i] = value
+	}
+
+	return args, nil
+}
+
+// scanNamed replaces the :name params of the query with the results of f,
+// skipping the quoted strings, the comments and the :: casts
+func scanNamed(query string, f func(name string) string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(query); i++ {
+		if n := skipped(query, i); n > 0 {
+			b.WriteString(query[i : i+n])
+			i += n - 1
+			continue
+		}
+
+		c := query[i]
+		if c == ':' && i+1 < len(query) && isNameStart(query[i+1]) &&
+			// slices like a[lo:hi] are not params
+			(i == 0 || !isNameChar(query[i-1])) {
+			j := i + 1
+			for j < len(query) && isNameChar(query[j]) {
+				j++
+			}
+
+			b.WriteString(f(query[i+1 : j]))
+			i = j - 1
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// skipped returns the length of the quoted string, the comment or the :: cast
+// starting at i of the query, the length of the rest of the query if it is
+// unterminated, 0 if there is none
+func skipped(query string, i int) int {
+	c := query[i]
+
+	var end int
+	switch {
+	case c == '\'' || c == '"' || c == '`':
+		if end = strings.IndexByte(query[i+1:], c); end >= 0 {
+			return end + 2
+		}
+	case strings.HasPrefix(query[i:], "--"):
+		if end = strings.IndexByte(query[i:], '\n'); end >= 0 {
+			return end
+		}
+	case strings.HasPrefix(query[i:], "/*"):
+		if end = strings.Index(query[i+2:], "*/"); end >= 0 {
+			return end + 4
+		}
+	case strings.HasPrefix(query[i:], "::"):
+		return 2
+	default:
+		return 0
+	}
+
+	return len(query) - i
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || ('0' <= c && c <= '9')
+}
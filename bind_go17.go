@@ -0,0 +1,8 @@
+// +build !go1.8
+
+package ctxdb
+
+// namedArg reports false, sql.NamedArg is added in Go 1.8
+func namedArg(arg interface{}) (name string, value interface{}, ok bool) {
+	return "", nil, false
+}
@@ -0,0 +1,15 @@
+// +build go1.8
+
+package ctxdb
+
+import "database/sql"
+
+// namedArg returns the name and the value of arg if it is a sql.NamedArg
+func namedArg(arg interface{}) (name string, value interface{}, ok bool) {
+	n, ok := arg.(sql.NamedArg)
+	if !ok {
+		return "", nil, false
+	}
+
+	return n.Name, n.Value, true
+}
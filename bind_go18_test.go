@@ -0,0 +1,73 @@
+// +build go1.8
+
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestBindNamed(t *testing.T) {
+	query, args, err := bindNamed(DialectPostgres, "SELECT :a, :b, :a",
+		[]interface{}{sql.Named("b", 2), sql.Named("a", 1)})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if query != "SELECT $1, $2, $1" || len(args) != 2 || args[0] != 1 || args[1] != 2 {
+		t.Fatalf("expected the positional args, got: %q, %v", query, args)
+	}
+
+	if _, _, err := bindNamed(DialectPostgres, "SELECT :a, :b", []interface{}{sql.Named("a", 1)}); err == nil {
+		t.Fatal("expected the missing arg to fail")
+	}
+
+	if _, _, err := bindNamed(DialectPostgres, "SELECT :a, $2", []interface{}{sql.Named("a", 1), 2}); err != ErrMixedArgs {
+		t.Fatalf("expected ErrMixedArgs, got: %v", err)
+	}
+
+	// positional args are left as they are
+	query, args, err = bindNamed(DialectPostgres, "SELECT $1", []interface{}{1})
+	if err != nil || query != "SELECT $1" || len(args) != 1 {
+		t.Fatalf("expected the query as it is, got: %q, %v, %v", query, args, err)
+	}
+}
+
+func TestNamedArgs(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	var a, b int
+	err := db.QueryRow(ctx, "SELECT :a::int, :b::int", sql.Named("b", 2), sql.Named("a", 1)).Scan(ctx, &a, &b)
+	if err != nil || a != 1 || b != 2 {
+		t.Fatalf("expected 1 and 2, got: %d, %d, %v", a, b, err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT :a::int", sql.Named("a", 1)); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	stmt, err := db.Prepare(ctx, "SELECT :a::int + :b::int")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer stmt.Close(ctx)
+
+	var sum int
+	if err := stmt.QueryRow(ctx, sql.Named("b", 2), sql.Named("a", 1)).Scan(ctx, &sum); err != nil || sum != 3 {
+		t.Fatalf("expected 3, got: %d, %v", sum, err)
+	}
+
+	if err := tx.Stmt(ctx, stmt).QueryRow(ctx, 3, 4).Scan(ctx, &sum); err != nil || sum != 7 {
+		t.Fatalf("expected 7, got: %d, %v", sum, err)
+	}
+}
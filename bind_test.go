@@ -0,0 +1,74 @@
+package ctxdb
+
+import "testing"
+
+func TestBindQuery(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		query   string
+		want    string
+		names   []string
+	}{
+		{
+			dialect: DialectPostgres,
+			query:   "SELECT * FROM users WHERE id = :id AND (name = :name OR nick = :name)",
+			want:    "SELECT * FROM users WHERE id = $1 AND (name = $2 OR nick = $2)",
+			names:   []string{"id", "name"},
+		},
+		{
+			dialect: DialectMySQL,
+			query:   "SELECT * FROM users WHERE id = :id AND (name = :name OR nick = :name)",
+			want:    "SELECT * FROM users WHERE id = ? AND (name = ? OR nick = ?)",
+			names:   []string{"id", "name", "name"},
+		},
+		{
+			dialect: DialectPostgres,
+			query:   "SELECT ':id', \":id\", a[lo:hi], :id::text -- :id\n/* :id */ FROM t",
+			want:    "SELECT ':id', \":id\", a[lo:hi], $1::text -- :id\n/* :id */ FROM t",
+			names:   []string{"id"},
+		},
+		{
+			dialect: DialectPostgres,
+			query:   "SELECT 'unterminated :id",
+			want:    "SELECT 'unterminated :id",
+		},
+		{
+			dialect: DialectUnknown,
+			query:   "SELECT :id",
+			want:    "SELECT :id",
+		},
+	}
+
+	for _, test := range tests {
+		query, names := bindQuery(test.dialect, test.query)
+		if query != test.want {
+			t.Errorf("expected %q, got: %q", test.want, query)
+		}
+
+		if len(names) != len(test.names) {
+			t.Errorf("expected %v, got: %v", test.names, names)
+			continue
+		}
+
+		for i, name := range names {
+			if name != test.names[i] {
+				t.Errorf("expected %v, got: %v", test.names, names)
+				break
+			}
+		}
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	if d := DialectOf("postgres"); d != DialectPostgres {
+		t.Fatalf("expected DialectPostgres, got: %d", d)
+	}
+
+	if d := DialectOf("mysql"); d != DialectMySQL {
+		t.Fatalf("expected DialectMySQL, got: %d", d)
+	}
+
+	if d := DialectOf("odbc"); d != DialectUnknown {
+		t.Fatalf("expected DialectUnknown, got: %d", d)
+	}
+}
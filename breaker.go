@@ -0,0 +1,110 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by the operations while the circuit breaker is
+// open, without waiting for a connection.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// WithCircuitBreaker enables the circuit breaker for Exec, Query, QueryRow and
+// Ping. Breaker opens after the given number of consecutive failures, transient
+// errors and timeouts, then the operations fail fast with ErrCircuitOpen
+// instead of burning their ctx timeouts. After every cooldown period a probe
+// ping is run in the background, its success closes the breaker, its failure
+// keeps the breaker open for another cooldown period.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(db *DB) {
+		db.breaker = &breaker{
+			threshold: threshold,
+			cooldown:  cooldown,
+		}
+	}
+}
+
+// breaker holds the state of the circuit breaker
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int       // consecutive failures
+	open     bool      // operations fail fast
+	openedAt time.Time // start of the current cooldown
+	probing  bool      // probe ping is running
+}
+
+// isFailure reports whether err counts as a failure for the breaker
+func isFailure(err error) bool {
+	return isBrokenConn(err) || err == context.DeadlineExceeded
+}
+
+// guardBreaker returns ErrCircuitOpen if the circuit breaker is open, starting
+// a probe if the cooldown period is over.
+func (db *DB) guardBreaker() error {
+	b := db.breaker
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+
+	if !b.probing && time.Since(b.openedAt) >= b.cooldown {
+		b.probing = true
+		go db.probe()
+	}
+
+	return ErrCircuitOpen
+}
+
+// probe pings the database, closing the breaker on success
+func (db *DB) probe() {
+	b := db.breaker
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.cooldown)
+	err := db.ping(ctx)
+	cancel()
+
+	b.mu.Lock()
+	b.probing = false
+	if err == nil {
+		b.open = false
+		b.failures = 0
+	} else {
+		b.openedAt = time.Now()
+	}
+	b.mu.Unlock()
+}
+
+// recordBreaker records the result of an operation on the circuit breaker,
+// other errors mean the database is reachable, so they count as successes.
+func (db *DB) recordBreaker(err error) {
+	b := db.breaker
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !isFailure(err) {
+		b.failures = 0
+		b.open = false
+		return
+	}
+
+	b.failures++
+	if !b.open && b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,48 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+	WithCircuitBreaker(2, time.Millisecond*50)(db)
+	ctx := context.Background()
+
+	db.recordBreaker(driver.ErrBadConn)
+	if err := db.guardBreaker(); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// errors of a reachable database reset the failures
+	db.recordBreaker(errors.New("syntax error"))
+	db.recordBreaker(driver.ErrBadConn)
+	if err := db.guardBreaker(); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	db.recordBreaker(context.DeadlineExceeded)
+	if err := db.Ping(ctx); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got: %v", err)
+	}
+
+	if _, err := db.Exec(ctx, "SELECT 1"); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got: %v", err)
+	}
+
+	// probe closes the breaker after the cooldown
+	time.Sleep(time.Millisecond * 60)
+	if err := db.Ping(ctx); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got: %v", err)
+	}
+
+	time.Sleep(time.Millisecond * 50)
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
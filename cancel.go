@@ -0,0 +1,134 @@
+package ctxdb
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// cancelTimeout is the maximum duration waited for a query to stop after its
+// cancel request is sent.
+const cancelTimeout = time.Second
+
+// Canceler cancels the query running on a connection of the pool, on behalf of
+// the operations timed out or cancelled while racing against their ctx.
+type Canceler struct {
+	// IDQuery is run on every new connection, it returns the server side id
+	// of the connection, e.g. SELECT pg_backend_pid().
+	IDQuery string
+
+	// Cancel cancels the query running on the connection with the given id,
+	// using the given side connection.
+	Cancel func(side *sql.DB, id int64) error
+}
+
+// PostgresCanceler cancels the queries with pg_cancel_backend.
+var PostgresCanceler = Canceler{
+	IDQuery: backendPIDSQLStatement,
+	Cancel: func(side *sql.DB, id int64) error {
+		_, err := side.Exec(cancelBackendSQLStatement, id)
+		return err
+	},
+}
+
+// MySQLCanceler cancels the queries with KILL QUERY.
+var MySQLCanceler = Canceler{
+	IDQuery: connectionIDSQLStatement,
+	Cancel: func(side *sql.DB, id int64) error {
+		_, err := side.Exec(fmt.Sprintf("KILL QUERY %d", id))
+		return err
+	},
+}
+
+// WithCanceler makes the operations timed out or cancelled while racing
+// against their ctx cancel the running query with the given canceler, over a
+// side connection created by the factory, instead of closing the connection
+// and leaving the query running on the server. If the query stops within a
+// second, the connection is put back to the pool, otherwise it's closed as
+// before.
+func WithCanceler(c Canceler) Option {
+	return func(db *DB) {
+		db.canceler = &c
+	}
+}
+
+// WithServerCancel is WithCanceler with the PostgresCanceler.
+func WithServerCancel() Option {
+	return WithCanceler(PostgresCanceler)
+}
+
+// cancelQuery sends a cancel request for the query running on the given conn,
+// and waits for the operation signalling done to stop. Returns true if it
+// stops in time, the conn is marked to be put back in that case.
+func (db *DB) cancelQuery(conn *sql.DB, done chan struct{}) bool {
+	db.mu.Lock()
+	factory := db.factory
+	var id int64
+	if info := db.infos[conn]; info != nil {
+		id = info.backendID
+	}
+	db.mu.Unlock()
+
+	c := db.canceler
+	if c == nil || factory == nil || id == 0 {
+		return false
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		side, err := factory()
+		if err != nil {
+			sent <- err
+			return
+		}
+		defer side.Close()
+
+		sent <- c.Cancel(side, id)
+	}()
+
+	timeout := time.NewTimer(cancelTimeout)
+	defer timeout.Stop()
+
+	select {
+	case err := <-sent:
+		if err != nil {
+			return false
+		}
+	case <-timeout.C:
+		return false
+	}
+
+	select {
+	case <-done:
+	case <-timeout.C:
+		return false
+	}
+
+	db.mu.Lock()
+	if info := db.infos[conn]; info != nil {
+		info.cancelled = true
+	}
+	db.mu.Unlock()
+
+	return true
+}
+
+// takeCancelled reports whether the query on conn is cancelled on the server,
+// so conn can be reused; the mark is cleared.
+func (db *DB) takeCancelled(conn *sql.DB) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	info := db.infos[conn]
+	if info == nil || !info.cancelled {
+		return false
+	}
+
+	info.cancelled = false
+	return true
+}
+
+const (
+	cancelBackendSQLStatement = `SELECT pg_cancel_backend($1)`
+	connectionIDSQLStatement  = `SELECT CONNECTION_ID()`
+)
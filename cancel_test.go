@@ -0,0 +1,84 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithServerCancel(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+		WithServerCancel(),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	conn := <-db.conns
+	db.conns <- conn
+
+	timedoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := db.Query(timedoutCtx, "SELECT pg_sleep(5)"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > cancelTimeout {
+		t.Fatalf("expected the query to be cancelled, took: %s", elapsed)
+	}
+
+	// query is cancelled on the server, connection is put back
+	if len(db.conns) != 1 || <-db.conns != conn {
+		t.Fatal("expected the connection to be put back to the pool")
+	}
+}
+
+func TestWithCanceler(t *testing.T) {
+	var ids []int64
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithCanceler(Canceler{
+			IDQuery: PostgresCanceler.IDQuery,
+			Cancel: func(side *sql.DB, id int64) error {
+				ids = append(ids, id)
+				return PostgresCanceler.Cancel(side, id)
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	var pid int64
+	if err := db.QueryRow(ctx, "SELECT pg_backend_pid()").Scan(ctx, &pid); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	timedoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel()
+
+	if _, err := db.Query(timedoutCtx, "SELECT pg_sleep(5)"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	if len(ids) != 1 || ids[0] != pid {
+		t.Fatalf("expected cancel of %d, got: %v", pid, ids)
+	}
+}
@@ -0,0 +1,137 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Change holds the rows changed by an UPDATE or DELETE statement on a table
+// registered with CaptureChanges.
+type Change struct {
+	Table   string
+	Verb    string // UPDATE or DELETE
+	Query   string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// CaptureChanges registers f to be called with the changed rows of the UPDATE
+// and DELETE statements executed by Exec on the given tables. Statements are
+// rewritten with a trailing RETURNING * clause, so the database must support
+// it, e.g. postgres. Statements executed within transactions are not captured.
+func (db *DB) CaptureChanges(f func(c *Change), tables ...string) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	if db.captures == nil {
+		db.captures = make(map[string]func(c *Change))
+	}
+
+	for _, table := range tables {
+		db.captures[table] = f
+	}
+	db.mu.Unlock()
+}
+
+// getCapture returns the capture func of the given statement, if the statement
+// should be captured.
+func (db *DB) getCapture(query string) (func(c *Change), string, string) {
+	db.mu.Lock()
+	empty := len(db.captures) == 0
+	db.mu.Unlock()
+
+	if empty {
+		return nil, "", ""
+	}
+
+	verb, table := parseStatement(query)
+	if verb != "UPDATE" && verb != "DELETE" {
+		return nil, "", ""
+	}
+
+	if hasReturning(query) {
+		return nil, "", ""
+	}
+
+	db.mu.Lock()
+	f := db.captures[table]
+	db.mu.Unlock()
+
+	return f, verb, table
+}
+
+// hasReturning reports whether the statement has a RETURNING clause of its
+// own, RETURNING in the quoted strings, the identifiers and the subqueries is
+// not a clause
+func hasReturning(query string) bool {
+	for _, w := range topLevelWords(query) {
+		if strings.EqualFold(w.text, "RETURNING") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// execCapture executes the query with a returning clause, and passes the
+// changed rows to f. Rows are queried with query, since Exec already runs
+// the middlewares, the span and the logging of the operation.
+func (db *DB) execCapture(ctx context.Context, f func(c *Change), verb, table, query string, args ...interface{}) (*Result, error) {
+	returning := strings.TrimRight(strings.TrimSpace(query), ";") + " RETURNING *"
+
+	rows, err := db.query(ctx, returning, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := rows.Columns(ctx)
+	if err != nil {
+		rows.drop(ctx)
+		return nil, err
+	}
+
+	c := &Change{
+		Table:   table,
+		Verb:    verb,
+		Query:   query,
+		Columns: columns,
+	}
+
+	err = rows.Reduce(ctx, func(scan ScanFunc) error {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := scan(dest...); err != nil {
+			return err
+		}
+
+		c.Rows = append(c.Rows, values)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f(c)
+
+	return &Result{id: nextOperationID(), res: capturedResult(len(c.Rows)), query: query}, nil
+}
+
+var errNoLastInsertID = errors.New("LastInsertId is not supported by captured statements")
+
+// capturedResult is the result of a captured statement
+type capturedResult int64
+
+func (r capturedResult) LastInsertId() (int64, error) {
+	return 0, errNoLastInsertID
+}
+
+func (r capturedResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
@@ -0,0 +1,78 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCaptureChanges(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	for i := 1; i < 3; i++ {
+		if _, err := db.Exec(ctx, insertSQLStatement, i, nil, 42); err != nil {
+			t.Fatalf("err while adding null item: %s", err.Error())
+		}
+	}
+
+	var change *Change
+	db.CaptureChanges(func(c *Change) {
+		change = c
+	}, "nullable")
+
+	res, err := db.Exec(ctx, "UPDATE nullable SET string_val = $1 WHERE int64_val = $2", "CHANGED", 1)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	affected, err := res.RowsAffected(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if affected != 1 {
+		t.Fatalf("expected 1 affected row, got: %d", affected)
+	}
+
+	if change == nil {
+		t.Fatalf("change should not be nil")
+	}
+
+	if change.Verb != "UPDATE" || change.Table != "nullable" {
+		t.Fatalf("expected UPDATE on nullable, got: %s on %s", change.Verb, change.Table)
+	}
+
+	if len(change.Rows) != 1 || len(change.Rows[0]) != len(change.Columns) {
+		t.Fatalf("expected 1 changed row, got: %+v", change.Rows)
+	}
+
+	if _, err := db.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	if len(change.Rows) != 2 || change.Verb != "DELETE" {
+		t.Fatalf("expected 2 deleted rows, got: %+v", change.Rows)
+	}
+}
+
+func TestHasReturning(t *testing.T) {
+	tests := map[string]bool{
+		"DELETE FROM users RETURNING id":                                true,
+		"UPDATE users SET name = 'x' returning *":                       true,
+		"UPDATE users SET note = 'RETURNING' WHERE id = 1":              false,
+		"UPDATE users SET returning_at = NOW()":                         false,
+		`UPDATE users SET "RETURNING" = 1`:                              false,
+		"DELETE FROM users WHERE id IN (SELECT id FROM x) -- returning": false,
+	}
+
+	for query, expected := range tests {
+		if got := hasReturning(query); got != expected {
+			t.Fatalf("expected %t for %q, got: %t", expected, query, got)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidChunkSize is returned by ExecChunked if the chunk size is not
+// positive.
+var ErrInvalidChunkSize = errors.New("chunk size must be positive")
+
+// ChunkPolicy configures the pace of a chunked execution.
+type ChunkPolicy struct {
+	// Pause is the duration waited between two chunks.
+	Pause time.Duration
+
+	// SlowChunk, if set, is the duration of a chunk considered slow, e.g. due
+	// to lock waits or replication lag. Pause is doubled after every slow
+	// chunk up to MaxPause, if it is set, and restored after a fast one.
+	SlowChunk time.Duration
+	MaxPause  time.Duration
+
+	// Progress, if set, is called after every chunk with the number of the
+	// executed chunks and the total number of the affected rows.
+	Progress func(chunks int, affected int64)
+}
+
+// ExecChunked runs a large DELETE or UPDATE in bounded chunks, so it does not
+// hold locks for long or cause replication lag spikes like a single massive
+// statement. Query must affect at most chunkSize rows, which is passed as its
+// last argument after the given args, e.g.
+//
+//	DELETE FROM events WHERE id IN (
+//		SELECT id FROM events WHERE created_at < $1 LIMIT $2
+//	)
+//
+// Query is executed in separate statements until a chunk affects less than
+// chunkSize rows, waiting for the pause of the policy in between. Returns the
+// total number of the affected rows, even on error. Every chunk is an Exec
+// with the given ctx, Exec retries apply to the chunks.
+func (db *DB) ExecChunked(ctx context.Context, query string, chunkSize int, policy ChunkPolicy, args ...interface{}) (int64, error) {
+	if err := db.check(); err != nil {
+		return 0, err
+	}
+
+	if chunkSize <= 0 {
+		return 0, ErrInvalidChunkSize
+	}
+
+	args = append(args[:len(args):len(args)], chunkSize)
+	pause := policy.Pause
+
+	var total int64
+	for chunks := 1; ; chunks++ {
+		start := time.Now()
+
+		res, err := db.Exec(ctx, query, args...)
+		if err != nil {
+			return total, err
+		}
+
+		affected, err := res.RowsAffected(ctx)
+		if err != nil {
+			return total, err
+		}
+
+		total += affected
+		if policy.Progress != nil {
+			policy.Progress(chunks, total)
+		}
+
+		if affected < int64(chunkSize) {
+			return total, nil
+		}
+
+		pause = policy.nextPause(pause, time.Since(start))
+		if pause <= 0 {
+			if err := ctx.Err(); err != nil {
+				return total, err
+			}
+
+			continue
+		}
+
+		t := time.NewTimer(pause)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return total, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// nextPause returns the pause after a chunk took the given duration
+func (p ChunkPolicy) nextPause(pause, took time.Duration) time.Duration {
+	if p.SlowChunk <= 0 || took < p.SlowChunk {
+		return p.Pause
+	}
+
+	if pause <= 0 {
+		pause = p.SlowChunk
+	}
+
+	pause *= 2
+	if p.MaxPause > 0 && pause > p.MaxPause {
+		pause = p.MaxPause
+	}
+
+	return pause
+}
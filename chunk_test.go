@@ -0,0 +1,67 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExecChunked(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TEMP TABLE chunked AS SELECT i AS id FROM generate_series(1, 25) i"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var progress []int64
+	policy := ChunkPolicy{
+		Pause: time.Millisecond,
+		Progress: func(chunks int, affected int64) {
+			progress = append(progress, affected)
+		},
+	}
+
+	total, err := db.ExecChunked(ctx,
+		"DELETE FROM chunked WHERE id IN (SELECT id FROM chunked WHERE id > $1 LIMIT $2)",
+		10, policy, 5,
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if total != 20 {
+		t.Fatalf("expected 20 deleted rows, got: %d", total)
+	}
+
+	if len(progress) != 3 || progress[0] != 10 || progress[2] != 20 {
+		t.Fatalf("expected the progress of 3 chunks, got: %v", progress)
+	}
+
+	if _, err := db.ExecChunked(ctx, "DELETE FROM chunked", 0, ChunkPolicy{}); err != ErrInvalidChunkSize {
+		t.Fatalf("expected ErrInvalidChunkSize, got: %v", err)
+	}
+}
+
+func TestChunkPolicyNextPause(t *testing.T) {
+	p := ChunkPolicy{
+		Pause:     time.Millisecond,
+		SlowChunk: time.Millisecond * 10,
+		MaxPause:  time.Millisecond * 30,
+	}
+
+	pause := p.nextPause(p.Pause, time.Millisecond*20)
+	if pause != time.Millisecond*2 {
+		t.Fatalf("expected the pause to be doubled, got: %s", pause)
+	}
+
+	pause = p.nextPause(time.Millisecond*20, time.Millisecond*20)
+	if pause != p.MaxPause {
+		t.Fatalf("expected the max pause, got: %s", pause)
+	}
+
+	if pause = p.nextPause(pause, time.Millisecond); pause != p.Pause {
+		t.Fatalf("expected the pause to be restored, got: %s", pause)
+	}
+}
@@ -0,0 +1,233 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidIndexDest is returned by CollectIndex if the dest is not a non-nil
+// pointer to a map of structs, struct pointers or map[string]interface{}.
+var ErrInvalidIndexDest = errors.New("dest must be a pointer to a map of structs or map[string]interface{}")
+
+// CollectMap consumes the rows and returns a map of the values of valCol keyed
+// by the values of keyCol. Byte slices are converted to strings, so they can be
+// used as keys. Later rows overwrite the earlier ones with the same key.
+func (rs *Rows) CollectMap(ctx context.Context, keyCol, valCol string) (map[interface{}]interface{}, error) {
+	columns, err := rs.Columns(ctx)
+	if err != nil {
+		rs.drop(ctx)
+		return nil, err
+	}
+
+	k, err := columnIndex(columns, keyCol)
+	if err != nil {
+		rs.drop(ctx)
+		return nil, err
+	}
+
+	v, err := columnIndex(columns, valCol)
+	if err != nil {
+		rs.drop(ctx)
+		return nil, err
+	}
+
+	m := make(map[interface{}]interface{})
+	err = rs.Reduce(ctx, func(scan ScanFunc) error {
+		values, err := scanValues(scan, len(columns))
+		if err != nil {
+			return err
+		}
+
+		m[values[k]] = values[v]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// CollectIndex consumes the rows into the map pointed at by destMapPtr, keyed
+// by the values of keyCol, e.g. *map[int64]User. Map is created if it is nil.
+// Values of the map can be structs or struct pointers, whose fields are matched
+// with the columns by their `db` tags or case-insensitively by their names,
+// columns without a field are skipped. Values can also be map[string]interface{}
+// holding all the columns. Later rows overwrite the earlier ones with the same
+// key.
+func (rs *Rows) CollectIndex(ctx context.Context, keyCol string, destMapPtr interface{}) error {
+	dest := reflect.ValueOf(destMapPtr)
+	if dest.Kind() != reflect.Ptr || dest.IsNil() || dest.Elem().Kind() != reflect.Map {
+		rs.drop(ctx)
+		return ErrInvalidIndexDest
+	}
+
+	m := dest.Elem()
+	keyType, valType := m.Type().Key(), m.Type().Elem()
+
+	structType := valType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	asMap := valType == reflect.TypeOf(map[string]interface{}(nil))
+	if !asMap && structType.Kind() != reflect.Struct {
+		rs.drop(ctx)
+		return ErrInvalidIndexDest
+	}
+
+	columns, err := rs.Columns(ctx)
+	if err != nil {
+		rs.drop(ctx)
+		return err
+	}
+
+	k, err := columnIndex(columns, keyCol)
+	if err != nil {
+		rs.drop(ctx)
+		return err
+	}
+
+	var fields []int
+	if !asMap {
+		fields = fieldIndexes(structType, columns)
+	}
+
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+
+	return rs.Reduce(ctx, func(scan ScanFunc) error {
+		key := reflect.New(keyType)
+		if asMap {
+			values, err := scanValues(scan, len(columns))
+			if err != nil {
+				return err
+			}
+
+			row := make(map[string]interface{}, len(columns))
+			for i, column := range columns {
+				row[column] = values[i]
+			}
+
+			if err := convertKey(key, values[k]); err != nil {
+				return err
+			}
+
+			m.SetMapIndex(key.Elem(), reflect.ValueOf(row))
+			return nil
+		}
+
+		val := reflect.New(structType)
+		dest := make([]interface{}, len(columns))
+		for i, field := range fields {
+			switch {
+			case i == k:
+				dest[i] = key.Interface()
+			case field >= 0:
+				dest[i] = val.Elem().Field(field).Addr().Interface()
+			default:
+				dest[i] = new(interface{})
+			}
+		}
+
+		if err := scan(dest...); err != nil {
+			return err
+		}
+
+		// key column is scanned into its field too, if it has one
+		if field := fields[k]; field >= 0 {
+			if err := convertKey(val.Elem().Field(field).Addr(), key.Elem().Interface()); err != nil {
+				return err
+			}
+		}
+
+		if valType.Kind() == reflect.Ptr {
+			m.SetMapIndex(key.Elem(), val)
+		} else {
+			m.SetMapIndex(key.Elem(), val.Elem())
+		}
+
+		return nil
+	})
+}
+
+// columnIndex returns the index of the column with the given name
+func columnIndex(columns []string, name string) (int, error) {
+	for i, column := range columns {
+		if column == name {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("column %s is not found", name)
+}
+
+// scanValues scans a row of n columns into interface values, converting byte
+// slices to strings
+func scanValues(scan ScanFunc, n int) ([]interface{}, error) {
+	values := make([]interface{}, n)
+	dest := make([]interface{}, n)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := scan(dest...); err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		if b, ok := value.([]byte); ok {
+			values[i] = string(b)
+		}
+	}
+
+	return values, nil
+}
+
+// fieldIndexes returns the indexes of the exported fields of t matching the
+// columns, -1 for the columns without a field
+func fieldIndexes(t reflect.Type, columns []string) []int {
+	fields := make([]int, len(columns))
+	for i, column := range columns {
+		fields[i] = -1
+
+		for j := 0; j < t.NumField(); j++ {
+			f := t.Field(j)
+			if f.PkgPath != "" {
+				// unexported
+				continue
+			}
+
+			name := f.Tag.Get("db")
+			if name == "-" {
+				continue
+			}
+
+			if name == column || (name == "" && strings.EqualFold(f.Name, column)) {
+				fields[i] = j
+				break
+			}
+		}
+	}
+
+	return fields
+}
+
+// convertKey assigns the scanned value into key, a pointer to the key type or
+// to the key field
+func convertKey(key reflect.Value, value interface{}) error {
+	v := reflect.ValueOf(value)
+	t := key.Elem().Type()
+	if !v.IsValid() || !v.Type().ConvertibleTo(t) ||
+		// numbers convert to strings as runes
+		(t.Kind() == reflect.String && v.Kind() != reflect.String) {
+		return fmt.Errorf("can not use %T as a key of %s", value, t)
+	}
+
+	key.Elem().Set(v.Convert(t))
+	return nil
+}
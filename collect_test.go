@@ -0,0 +1,117 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+const collectSQLStatement = `SELECT i AS id, 'name' || i AS name, i * 10 AS score
+    FROM generate_series(1, 3) i`
+
+func TestRowsCollectMap(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, collectSQLStatement)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	m, err := rows.CollectMap(ctx, "id", "name")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(m) != 3 || m[int64(2)] != "name2" {
+		t.Fatalf("expected 3 names by ids, got: %v", m)
+	}
+
+	rows, err = db.Query(ctx, collectSQLStatement)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := rows.CollectMap(ctx, "id", "unknown"); err == nil {
+		t.Fatal("expected an error for the unknown column, got nil")
+	}
+}
+
+func TestRowsCollectIndex(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+	ctx := context.Background()
+
+	type user struct {
+		ID    int64
+		Name  string `db:"name"`
+		Score int64  `db:"-"`
+	}
+
+	rows, err := db.Query(ctx, collectSQLStatement)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var users map[int64]*user
+	if err := rows.CollectIndex(ctx, "id", &users); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(users) != 3 {
+		t.Fatalf("expected 3 users, got: %d", len(users))
+	}
+
+	if u := users[3]; u.ID != 3 || u.Name != "name3" || u.Score != 0 {
+		t.Fatalf("expected user 3 without score, got: %+v", u)
+	}
+
+	rows, err = db.Query(ctx, collectSQLStatement)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	byName := make(map[string]map[string]interface{})
+	if err := rows.CollectIndex(ctx, "name", &byName); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if row := byName["name1"]; row["score"] != int64(10) {
+		t.Fatalf("expected score 10 for name1, got: %v", row)
+	}
+
+	rows, err = db.Query(ctx, collectSQLStatement)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var invalid map[int64]int64
+	if err := rows.CollectIndex(ctx, "id", &invalid); err != ErrInvalidIndexDest {
+		t.Fatalf("expected ErrInvalidIndexDest, got: %v", err)
+	}
+
+	// key field is converted to its type
+	rows, err = db.Query(ctx, collectSQLStatement)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var byID32 map[int32]user
+	if err := rows.CollectIndex(ctx, "id", &byID32); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if u := byID32[2]; u.ID != 2 {
+		t.Fatalf("expected user 2, got: %+v", u)
+	}
+
+	rows, err = db.Query(ctx, collectSQLStatement)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var byIDString map[string]user
+	if err := rows.CollectIndex(ctx, "id", &byIDString); err == nil {
+		t.Fatal("expected the inconvertible key field to fail")
+	}
+}
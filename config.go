@@ -0,0 +1,128 @@
+package ctxdb
+
+import (
+	"fmt"
+	"time"
+)
+
+// ConfigVersion is the version of the Config format produced by this package.
+// ApplyConfig accepts configs up to this version.
+const ConfigVersion = 1
+
+// Config is the serializable runtime configuration of a DB. It can be taken
+// from one DB with Config, stored or sent over the wire, and applied to
+// another one with ApplyConfig. Funcs, e.g. the journal and capture funcs,
+// are not part of the config.
+type Config struct {
+	// Version is the version of the format, see ConfigVersion.
+	Version int `json:"version"`
+
+	// MaxOpenConns can not be changed by ApplyConfig, since the pool is not
+	// resized; zero keeps the one of the DB.
+	MaxOpenConns int `json:"max_open_conns"`
+
+	MinIdleConns     int      `json:"min_idle_conns,omitempty"`
+	ConnMaxLifetime  Duration `json:"conn_max_lifetime,omitempty"`
+	ConnMaxIdleTime  Duration `json:"conn_max_idle_time,omitempty"`
+	AcquireTimeout   Duration `json:"acquire_timeout,omitempty"`
+	NoWait           bool     `json:"no_wait,omitempty"`
+	IterationTimeout Duration `json:"iteration_timeout,omitempty"`
+	Policy           *Policy  `json:"policy,omitempty"`
+	DefaultTimeout   Duration `json:"default_timeout,omitempty"`
+	Warmup           []string `json:"warmup,omitempty"`
+	CostGuard        float64  `json:"cost_guard,omitempty"`
+}
+
+// Config returns the effective runtime configuration of db.
+func (db *DB) Config() *Config {
+	if db == nil {
+		return nil
+	}
+
+	db.waitMu.Lock()
+	acquireTimeout, noWait := db.acquireTimeout, db.noWait
+	db.waitMu.Unlock()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return &Config{
+		Version:          ConfigVersion,
+		MaxOpenConns:     cap(db.sem),
+		MinIdleConns:     db.minIdleConns,
+		ConnMaxLifetime:  Duration(db.connMaxLifetime),
+		ConnMaxIdleTime:  Duration(db.connMaxIdleTime),
+		AcquireTimeout:   Duration(acquireTimeout),
+		NoWait:           noWait,
+		IterationTimeout: Duration(db.iterationTimeout),
+		Policy:           db.policy.clone(),
+		DefaultTimeout:   Duration(db.defaultTimeout),
+		Warmup:           append([]string(nil), db.warmup...),
+		CostGuard:        db.costGuard,
+	}
+}
+
+// ApplyConfig validates the given config and applies it to db. Configs with
+// an unknown version, or a max open conns other than the one of db are
+// rejected.
+func (db *DB) ApplyConfig(c *Config) error {
+	if err := db.check(); err != nil {
+		return err
+	}
+
+	if c == nil {
+		return fmt.Errorf("config can not be nil")
+	}
+
+	if c.Version < 1 || c.Version > ConfigVersion {
+		return fmt.Errorf("config version %d is not supported", c.Version)
+	}
+
+	// sems are the real size of the pool
+	maxOpen := cap(db.sem)
+	if c.MaxOpenConns != 0 && c.MaxOpenConns != maxOpen {
+		return fmt.Errorf("config: max open conns can not be changed from %d to %d", maxOpen, c.MaxOpenConns)
+	}
+
+	if c.MinIdleConns < 0 {
+		return fmt.Errorf("config: min idle conns can not be negative")
+	}
+
+	if c.ConnMaxLifetime < 0 || c.ConnMaxIdleTime < 0 {
+		return fmt.Errorf("config: conn max lifetime and idle time can not be negative")
+	}
+
+	if c.AcquireTimeout < 0 || c.IterationTimeout < 0 {
+		return fmt.Errorf("config: acquire and iteration timeouts can not be negative")
+	}
+
+	if c.DefaultTimeout < 0 {
+		return fmt.Errorf("config: default timeout can not be negative")
+	}
+
+	if c.Policy != nil {
+		if err := c.Policy.Validate(); err != nil {
+			return err
+		}
+	}
+
+	db.waitMu.Lock()
+	db.acquireTimeout = time.Duration(c.AcquireTimeout)
+	db.noWait = c.NoWait
+	db.waitMu.Unlock()
+
+	db.mu.Lock()
+	db.iterationTimeout = time.Duration(c.IterationTimeout)
+	db.connMaxLifetime = time.Duration(c.ConnMaxLifetime)
+	db.connMaxIdleTime = time.Duration(c.ConnMaxIdleTime)
+	db.startReaperLocked()
+	db.policy = c.Policy.clone()
+	db.defaultTimeout = time.Duration(c.DefaultTimeout)
+	db.warmup = append([]string(nil), c.Warmup...)
+	db.costGuard = c.CostGuard
+	db.mu.Unlock()
+
+	db.SetMinIdleConns(c.MinIdleConns)
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package ctxdb
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	src := getConn(t)
+	defer src.Close(context.Background())
+
+	src.SetWarmup("SELECT 1")
+	src.SetConnMaxLifetime(time.Hour)
+	src.SetPolicy(&Policy{
+		Default: LabelPolicy{Timeout: Duration(time.Second)},
+		Labels: map[string]LabelPolicy{
+			"users/get": {Timeout: Duration(100 * time.Millisecond), Retries: 2},
+		},
+	})
+
+	data, err := json.Marshal(src.Config())
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	c := &Config{}
+	if err := json.Unmarshal(data, c); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	dst := getConn(t)
+	defer dst.Close(context.Background())
+
+	if err := dst.ApplyConfig(c); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if !reflect.DeepEqual(src.Config(), dst.Config()) {
+		t.Fatalf("expected %+v, got: %+v", src.Config(), dst.Config())
+	}
+}
+
+func TestApplyConfigWithInvalidConfig(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	if err := db.ApplyConfig(&Config{Version: ConfigVersion + 1}); err == nil {
+		t.Fatal("expected an error for an unknown version")
+	}
+
+	err := db.ApplyConfig(&Config{
+		Version: ConfigVersion,
+		Policy:  &Policy{Default: LabelPolicy{Retries: -1}},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an invalid policy")
+	}
+
+	if err := db.ApplyConfig(nil); err == nil {
+		t.Fatal("expected an error for a nil config")
+	}
+
+	err = db.ApplyConfig(&Config{Version: ConfigVersion, MaxOpenConns: maxOpenConns + 1})
+	if err == nil {
+		t.Fatal("expected an error for a changed max open conns")
+	}
+}
+
+func TestApplyConfigCopiesPolicy(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	c := &Config{
+		Version: ConfigVersion,
+		Policy: &Policy{
+			Labels: map[string]LabelPolicy{"users/get": {Retries: 1}},
+		},
+	}
+
+	if err := db.ApplyConfig(c); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	c.Policy.Labels["users/get"] = LabelPolicy{Retries: 5}
+
+	if lp := db.Config().Policy.Labels["users/get"]; lp.Retries != 1 {
+		t.Fatalf("expected the applied policy to be copied, got: %+v", lp)
+	}
+}
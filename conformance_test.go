@@ -0,0 +1,18 @@
+package ctxdb_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cihangir/ctxdb/ctxdbtest"
+	_ "github.com/lib/pq"
+)
+
+func TestConformance(t *testing.T) {
+	ctxdbtest.RunConformance(t, ctxdbtest.Config{
+		Driver:      os.Getenv("NISQL_TEST_DIALECT"),
+		DSN:         os.Getenv("NISQL_TEST_DSN"),
+		SleepQuery:  "SELECT pg_sleep(1)",
+		Placeholder: ctxdbtest.Dollar,
+	})
+}
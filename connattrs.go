@@ -0,0 +1,95 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ConnAttributes holds the server side attributes of a connection, for finding
+// its backend in the server logs or terminating it, e.g. with
+// pg_terminate_backend. They are read from postgres once, when the connection
+// is created.
+type ConnAttributes struct {
+	BackendPID      int64
+	ServerVersion   string
+	Database        string
+	User            string
+	ApplicationName string
+}
+
+// WithConnAttributes reads the attributes of every new connection, exposed by
+// the Conn methods of Rows, Row and Tx. Reading is best-effort, attributes are
+// nil if they can not be read.
+func WithConnAttributes() Option {
+	return func(db *DB) {
+		db.connAttrs = true
+	}
+}
+
+// readConnAttributes reads the attributes of the given conn, returns nil on
+// error
+func readConnAttributes(ctx context.Context, conn *sql.DB) *ConnAttributes {
+	a := &ConnAttributes{}
+	err := dbScanRow(ctx, conn, connAttributesSQLStatement, nil,
+		&a.BackendPID,
+		&a.ServerVersion,
+		&a.Database,
+		&a.User,
+		&a.ApplicationName,
+	)
+	if err != nil {
+		return nil
+	}
+
+	return a
+}
+
+// connAttributes returns the attributes of the conn, nil if they are not read
+func (db *DB) connAttributes(conn *sql.DB) *ConnAttributes {
+	if !db.connAttrs {
+		return nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if info := db.infos[conn]; info != nil {
+		return info.attrs
+	}
+
+	return nil
+}
+
+// Conn returns the attributes of the connection the rows are read from, nil if
+// WithConnAttributes is not set.
+func (rs *Rows) Conn() *ConnAttributes {
+	if rs == nil {
+		return nil
+	}
+
+	return rs.attrs
+}
+
+// Conn returns the attributes of the connection the row is read from, nil if
+// WithConnAttributes is not set.
+func (r *Row) Conn() *ConnAttributes {
+	if r == nil {
+		return nil
+	}
+
+	return r.attrs
+}
+
+// Conn returns the attributes of the connection of the transaction, nil if
+// WithConnAttributes is not set.
+func (tx *Tx) Conn() *ConnAttributes {
+	if tx == nil {
+		return nil
+	}
+
+	return tx.attrs
+}
+
+const connAttributesSQLStatement = `SELECT pg_backend_pid(),
+    current_setting('server_version'), current_database(), current_user,
+    current_setting('application_name')`
@@ -0,0 +1,66 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestWithConnAttributes(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithConnAttributes(),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	var pid int64
+	row := db.QueryRow(ctx, "SELECT pg_backend_pid()")
+	attrs := row.Conn()
+	if err := row.Scan(ctx, &pid); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if attrs == nil {
+		t.Fatal("expected the attributes of the connection")
+	}
+
+	if attrs.BackendPID != pid || attrs.ServerVersion == "" || attrs.Database == "" {
+		t.Fatalf("expected the attributes of backend %d, got: %+v", pid, attrs)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if tx.Conn() == nil {
+		t.Fatal("expected the attributes of the transaction connection")
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
+
+func TestConnAttributesDisabled(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer rows.Close(ctx)
+
+	if rows.Conn() != nil {
+		t.Fatalf("expected nil attributes, got: %+v", rows.Conn())
+	}
+}
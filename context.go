@@ -0,0 +1,130 @@
+package ctxdb
+
+import (
+	"context"
+	"time"
+)
+
+// contextKey is the type of the keys ctxdb stores in contexts, it is unexported
+// to prevent collisions with keys defined in other packages.
+type contextKey int
+
+const (
+	retriesKey contextKey = iota
+	primaryKey
+	labelKey
+	noWaitKey
+	scopeKey
+	ddlKey
+	dryRunKey
+	tagsKey
+	includeDeletedKey
+)
+
+// valuesCtx is a ctx with the values of another one
+type valuesCtx struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesCtx) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// detached returns a ctx with the values of ctx, which is not done when ctx
+// is done
+func detached(ctx context.Context) context.Context {
+	return valuesCtx{Context: context.Background(), values: ctx}
+}
+
+// WithDeadlinePercent returns a copy of the parent context with a deadline at
+// the given percent of the parent's remaining time, for reserving the rest of
+// the budget for post-processing. p is clamped to [0, 1], so the deadline is
+// never after the parent's, and a p of 0 or less returns an already expired
+// context. If the parent has no deadline, returned context only carries a
+// cancel func.
+//
+// Canceling this context releases resources associated with it, so code should
+// call cancel as soon as the operations running in this Context complete.
+func WithDeadlinePercent(parent context.Context, p float64) (context.Context, context.CancelFunc) {
+	deadline, ok := parent.Deadline()
+	if !ok {
+		return context.WithCancel(parent)
+	}
+
+	if p > 1 {
+		p = 1
+	}
+
+	if p < 0 {
+		p = 0
+	}
+
+	remaining := deadline.Sub(time.Now())
+	return context.WithTimeout(parent, time.Duration(float64(remaining)*p))
+}
+
+// WithRetries returns a copy of the parent context which allows the operations
+// using it to be retried at most n times.
+func WithRetries(parent context.Context, n int) context.Context {
+	return context.WithValue(parent, retriesKey, n)
+}
+
+// RetriesFromContext returns the number of retries set by WithRetries, ok is
+// false if none is set.
+func RetriesFromContext(ctx context.Context) (n int, ok bool) {
+	n, ok = ctx.Value(retriesKey).(int)
+	return n, ok
+}
+
+// WithPrimary returns a copy of the parent context which marks the operations
+// using it as they must run against the primary database, e.g. read-your-writes
+// reads. BeginReadOnly does not use the replica for such contexts.
+func WithPrimary(parent context.Context) context.Context {
+	return context.WithValue(parent, primaryKey, true)
+}
+
+// IsPrimary reports whether the ctx is marked with WithPrimary.
+func IsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey).(bool)
+	return primary
+}
+
+// WithLabel returns a copy of the parent context which labels the operations
+// using it, labels select the policies of the operations.
+func WithLabel(parent context.Context, label string) context.Context {
+	return context.WithValue(parent, labelKey, label)
+}
+
+// LabelFromContext returns the label set by WithLabel, or empty string if none
+// is set.
+func LabelFromContext(ctx context.Context) string {
+	label, _ := ctx.Value(labelKey).(string)
+	return label
+}
+
+// WithNoWaitContext returns a copy of the parent context which marks the
+// operations using it as they must fail with ErrPoolExhausted instead of
+// waiting for a connection, when all of them are in use.
+func WithNoWaitContext(parent context.Context) context.Context {
+	return context.WithValue(parent, noWaitKey, true)
+}
+
+// IsNoWait reports whether the ctx is marked with WithNoWaitContext.
+func IsNoWait(ctx context.Context) bool {
+	noWait, _ := ctx.Value(noWaitKey).(bool)
+	return noWait
+}
+
+// WithIncludeDeleted returns a copy of the parent context which marks the
+// queries using it as they must return the soft deleted rows too, see
+// SoftDelete.
+func WithIncludeDeleted(parent context.Context) context.Context {
+	return context.WithValue(parent, includeDeletedKey, true)
+}
+
+// IsIncludeDeleted reports whether the ctx is marked with WithIncludeDeleted.
+func IsIncludeDeleted(ctx context.Context) bool {
+	includeDeleted, _ := ctx.Value(includeDeletedKey).(bool)
+	return includeDeleted
+}
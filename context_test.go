@@ -0,0 +1,103 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithDeadlinePercent(t *testing.T) {
+	ctx, cancel := WithDeadlinePercent(context.Background(), 0.5)
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatalf("expected no deadline")
+	}
+
+	parent, cancel1 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel1()
+
+	parentDeadline, _ := parent.Deadline()
+
+	ctx, cancel2 := WithDeadlinePercent(parent, 0.5)
+	defer cancel2()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatalf("expected deadline")
+	}
+
+	if !deadline.Before(parentDeadline.Add(-time.Millisecond * 400)) {
+		t.Fatalf("expected deadline to be at half of the parent, got: %s, parent: %s", deadline, parentDeadline)
+	}
+}
+
+func TestWithDeadlinePercentClamped(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	parentDeadline, _ := parent.Deadline()
+
+	ctx, cancel1 := WithDeadlinePercent(parent, 2)
+	defer cancel1()
+
+	if deadline, _ := ctx.Deadline(); deadline.After(parentDeadline) {
+		t.Fatalf("expected deadline not to be after the parent, got: %s, parent: %s", deadline, parentDeadline)
+	}
+
+	ctx, cancel2 := WithDeadlinePercent(parent, -1)
+	defer cancel2()
+
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got: %v", ctx.Err())
+	}
+}
+
+func TestWithRetries(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := RetriesFromContext(ctx); ok {
+		t.Fatalf("expected no retries")
+	}
+
+	n, ok := RetriesFromContext(WithRetries(ctx, 3))
+	if !ok {
+		t.Fatalf("expected retries")
+	}
+
+	if n != 3 {
+		t.Fatalf("expected 3 retries, got: %d", n)
+	}
+}
+
+func TestWithPrimary(t *testing.T) {
+	ctx := context.Background()
+	if IsPrimary(ctx) {
+		t.Fatalf("expected not primary")
+	}
+
+	if !IsPrimary(WithPrimary(ctx)) {
+		t.Fatalf("expected primary")
+	}
+}
+
+func TestWithLabel(t *testing.T) {
+	ctx := context.Background()
+	if label := LabelFromContext(ctx); label != "" {
+		t.Fatalf("expected no label, got: %s", label)
+	}
+
+	if label := LabelFromContext(WithLabel(ctx, "users")); label != "users" {
+		t.Fatalf("expected users label, got: %s", label)
+	}
+}
+
+func TestWithNoWaitContext(t *testing.T) {
+	ctx := context.Background()
+	if IsNoWait(ctx) {
+		t.Fatalf("expected wait")
+	}
+
+	if !IsNoWait(WithNoWaitContext(ctx)) {
+		t.Fatalf("expected no wait")
+	}
+}
@@ -0,0 +1,97 @@
+package ctxdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrQueryTooExpensive is returned when the estimated cost of a statement
+// exceeds the threshold set with WithCostGuard.
+var ErrQueryTooExpensive = errors.New("estimated cost of the query exceeds the threshold")
+
+// WithCostGuard enables the cost guard, every SELECT, INSERT, UPDATE, DELETE
+// and WITH statement executed by Exec, Query and QueryRow is explained before
+// it's executed, and rejected with ErrQueryTooExpensive if its estimated total
+// cost exceeds threshold. Explaining doubles the round trips of the
+// statements, so it's meant for development and staging environments. The
+// database must support EXPLAIN (FORMAT JSON), e.g. postgres.
+func WithCostGuard(threshold float64) Option {
+	return func(db *DB) {
+		db.costGuard = threshold
+	}
+}
+
+// guardCost explains the given statement and returns ErrQueryTooExpensive if
+// the cost guard is enabled and the estimated cost exceeds its threshold.
+func (db *DB) guardCost(ctx context.Context, query string, args []interface{}) error {
+	db.mu.Lock()
+	threshold := db.costGuard
+	db.mu.Unlock()
+
+	if threshold <= 0 {
+		return nil
+	}
+
+	switch verb, _ := parseStatement(query); verb {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+	default:
+		return nil
+	}
+
+	p, err := db.explain(ctx, query, args)
+	if err != nil {
+		return err
+	}
+
+	if p.TotalCost > threshold {
+		return ErrQueryTooExpensive
+	}
+
+	return nil
+}
+
+// plan is the estimates of the top node of a query plan
+type plan struct {
+	TotalCost float64 `json:"Total Cost"`
+	Rows      float64 `json:"Plan Rows"`
+}
+
+// explain returns the estimates of the plan of the given statement. Plans are
+// read with queryRow, so the explains have no spans and the middlewares do not
+// see them, but like any QueryRow they are passed to the functions set with
+// WithContextObserver and WithOpReport.
+func (db *DB) explain(ctx context.Context, query string, args []interface{}) (*plan, error) {
+	// plans are read, even for the dry runs
+	ctx = context.WithValue(ctx, dryRunKey, false)
+
+	query, args, err := db.bindQueryArgs("EXPLAIN (FORMAT JSON) "+query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = db.queryRow(ctx, query, args...).Scan(ctx, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePlan(data)
+}
+
+// parsePlan parses the given JSON formatted plan
+func parsePlan(data []byte) (*plan, error) {
+	var plans []struct {
+		Plan plan `json:"Plan"`
+	}
+
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, err
+	}
+
+	if len(plans) == 0 {
+		return &plan{}, nil
+	}
+
+	return &plans[0].Plan, nil
+}
@@ -0,0 +1,46 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestParsePlan(t *testing.T) {
+	p, err := parsePlan([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Total Cost": 35.5, "Plan Rows": 2550}}]`))
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if p.TotalCost != 35.5 || p.Rows != 2550 {
+		t.Fatalf("expected 35.5 and 2550, got: %+v", p)
+	}
+}
+
+func TestCostGuard(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithCostGuard(1000),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	var i int
+	if err := db.QueryRow(ctx, "SELECT 1").Scan(ctx, &i); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	crossJoin := "SELECT COUNT(*) FROM generate_series(1, 1000) a, generate_series(1, 1000) b"
+	if _, err := db.Query(ctx, crossJoin); err != ErrQueryTooExpensive {
+		t.Fatalf("expected ErrQueryTooExpensive, got: %v", err)
+	}
+
+	if err := db.QueryRow(ctx, crossJoin).Scan(ctx, &i); err != ErrQueryTooExpensive {
+		t.Fatalf("expected ErrQueryTooExpensive, got: %v", err)
+	}
+}
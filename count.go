@@ -0,0 +1,58 @@
+package ctxdb
+
+import (
+	"context"
+	"math"
+)
+
+// exactCountThreshold is the estimated row count under which CountEstimated
+// falls back to an exact count.
+const exactCountThreshold = 10000
+
+// CountEstimated returns the row count of the given table, filtered with the
+// where clause if it's not empty, e.g. "deleted_at IS NULL AND owner = $1".
+// Count is estimated from the planner statistics; reltuples of the table
+// without a where clause, or the row estimate of the plan with it. Estimates
+// under 10000 rows are replaced by an exact COUNT(*), which is cheap at that
+// size. Table and where are placed into the statement as they are, they must
+// not contain user input. The database must be postgres.
+func (db *DB) CountEstimated(ctx context.Context, table, where string, args ...interface{}) (int64, error) {
+	if err := db.check(); err != nil {
+		return 0, err
+	}
+
+	from := " FROM " + table
+	if where != "" {
+		from += " WHERE " + where
+	}
+
+	var estimate float64
+	if where == "" {
+		err := db.QueryRow(ctx, reltuplesSQLStatement, table).Scan(ctx, &estimate)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		p, err := db.explain(ctx, "SELECT 1"+from, args)
+		if err != nil {
+			return 0, err
+		}
+
+		estimate = p.Rows
+	}
+
+	// reltuples is -1 or 0 for the tables which are not analyzed yet, they
+	// are counted exactly as well
+	if estimate >= exactCountThreshold {
+		return int64(math.Floor(estimate + 0.5)), nil
+	}
+
+	var count int64
+	if err := db.QueryRow(ctx, "SELECT COUNT(*)"+from, args...).Scan(ctx, &count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+const reltuplesSQLStatement = `SELECT reltuples::float8 FROM pg_class WHERE oid = $1::regclass`
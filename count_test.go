@@ -0,0 +1,66 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCountEstimatedWithSmallTable(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	prepareAggregateData(t, db)
+	ctx := context.Background()
+
+	// small tables are counted exactly
+	count, err := db.CountEstimated(ctx, "nullable", "")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 4 {
+		t.Fatalf("expected 4, got: %d", count)
+	}
+
+	count, err = db.CountEstimated(ctx, "nullable", "int64_val > $1", 2)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected 2, got: %d", count)
+	}
+}
+
+func TestCountEstimatedWithLargeTable(t *testing.T) {
+	db := getConn(t)
+	ctx := context.Background()
+
+	for _, query := range []string{
+		"DROP TABLE IF EXISTS count_estimated",
+		"CREATE TABLE count_estimated AS SELECT i FROM generate_series(1, 50000) i",
+		"ANALYZE count_estimated",
+	} {
+		if _, err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+	defer db.Exec(ctx, "DROP TABLE count_estimated")
+
+	count, err := db.CountEstimated(ctx, "count_estimated", "")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count < 45000 || count > 55000 {
+		t.Fatalf("expected an estimate around 50000, got: %d", count)
+	}
+
+	count, err = db.CountEstimated(ctx, "count_estimated", "i > $1", 25000)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count < 20000 || count > 30000 {
+		t.Fatalf("expected an estimate around 25000, got: %d", count)
+	}
+}
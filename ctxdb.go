@@ -1,12 +1,13 @@
 package ctxdb
 
 import (
+	"container/list"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"sync"
-
-	"golang.org/x/net/context"
+	"time"
 )
 
 const maxOpenConns = 2
@@ -14,14 +15,95 @@ const maxOpenConns = 2
 // DB is a database handle representing a pool of zero or more underlying
 // connections. It's safe for concurrent use by multiple goroutines.
 type DB struct {
-	// maxIdleConns int
+	// first for 64-bit alignment of its atomic access
+	degradations uint64 // optional queries served by their fallbacks
+
+	maxIdleConns int
 	maxOpenConns int
 	sem          chan struct{}
 
 	mu    sync.Mutex
 	conns chan *sql.DB
 
+	waitMu       sync.Mutex
+	waiters      list.List     // FIFO queue of the operations waiting for a sem
+	waitCount    int64         // total number of the waits for a sem
+	waitDuration time.Duration // total time waited for a sem
+
 	factory Factory // sql.DB generator
+	gen     int     // generation of the factory, incremented on migrations
+	infos   map[*sql.DB]*connInfo
+
+	checkouts       int64 // total number of the checkouts, guarded by mu
+	factoryFailures int64 // total number of the failed factory calls, guarded by mu
+	cancelCloses    int64 // total number of the closes due to cancellation, guarded by mu
+
+	txJournal      func(j *TxJournal)         // transaction debug mode
+	captures       map[string]func(c *Change) // change capture funcs by table
+	policy         *Policy                    // timeouts and retries by labels
+	defaultTimeout time.Duration              // timeout of the operations without deadline
+	warmup         []string                   // queries run on new connections
+	costGuard      float64                    // max estimated cost of statements
+	plans          map[string]PlanMode        // plan modes by labels
+	pingCall       *pingCall                  // in-flight ping
+	errNotFound    bool                       // ErrNotFound instead of sql.ErrNoRows
+	dialect        Dialect                    // placeholders of the named args
+	rebind         bool                       // rebind the placeholders to the dialect
+	driverErrors   bool                       // map the driver errors to DriverErrors
+	connAttrs      bool                       // read the attributes of new connections
+	hooks          Hooks                      // pool lifecycle event hooks
+	canceler       *Canceler                  // cancels timed out queries on the server
+	deadlineGuard  *deadlineGuard             // guard of the operations without deadline
+	retryPolicy    *RetryPolicy               // retries of the transient errors
+	queries        *Queries                   // registry of the named queries
+	txTemplates    map[string][]TxStep        // transaction templates by names
+	breaker        *breaker                   // circuit breaker of the operations
+	healthCheck    *healthCheck               // background health checker
+	statsHistory   *statsHistory              // recent pool statistics
+	noWait         bool                       // fail fast when the pool is exhausted
+	ddlGate        bool                       // reject the schema changes without WithDDL
+	dryRunDisabled bool                       // reject the dry runs
+	strict         bool                       // report the misuses of the API
+	strictPanics   bool                       // panic on the misuses
+
+	iterationTimeout time.Duration // max wall time of iterating rows
+	acquireTimeout   time.Duration // max wait for a connection
+
+	leakThreshold time.Duration // max time a connection is held before it is reported
+	leakReport    func(l *Leak) // reports the leaked connections
+
+	connMaxLifetime time.Duration // max lifetime of the connections
+	connMaxIdleTime time.Duration // max idle time of the connections
+	reaperStop      chan struct{} // stops the reaper of the expired connections
+	txIdleTimeout   time.Duration // idle-in-transaction timeout of the transactions
+
+	minIdleConns int           // min number of idle connections
+	keeperStop   chan struct{} // stops the keeper of the min idle connections
+
+	standbyConns int           // number of the standby connections
+	standby      []*sql.DB     // pre-authenticated connections, guarded by mu
+	standbyWake  chan struct{} // wakes the filler of the standby connections
+	standbyStop  chan struct{} // stops the filler of the standby connections
+
+	observer func(ctx context.Context, op string) // observer of the operation contexts
+	tracer   Tracer                               // starts the spans of the operations
+	opReport func(r *OpReport)                    // reports of the operation results
+
+	logger      Logger   // receives the records of the operations
+	logRedactor Redactor // redacts the args of the logged queries
+	slowLog     *slowLog // slow query log
+
+	serverTiming *serverTiming // server time samples of the statements
+	deps         *depGraph     // live dependency graph of the tables
+
+	timeoutAdvisor *timeoutAdvisor // latencies of the labels for the timeout suggestions
+
+	middlewares []Middleware // wrap the operations
+
+	replica *DB // pool of the read-only transactions
+
+	txReport          func(r *TxReport) // transaction reports
+	txReportThreshold time.Duration     // min duration of reported transactions
 }
 
 // Factory holds db generator
@@ -42,57 +124,190 @@ type Factory func() (*sql.DB, error)
 // The returned DB is safe for concurrent use by multiple goroutines and
 // maintains its own pool of idle connections. Thus, the Open function should be
 // called just once. It is rarely necessary to close a DB.
-func Open(driver, dsn string) (*DB, error) {
+//
+// The pool holds at most 2 connections by default, see WithMaxOpenConns and
+// WithMaxIdleConns for sizing it.
+func Open(driver, dsn string, opts ...Option) (*DB, error) {
+	// given options override the detected dialect
+	opts = append([]Option{WithDialect(DialectOf(driver))}, opts...)
+
+	return NewWithDB(func() (*sql.DB, error) {
+		return sql.Open(driver, dsn)
+	}, opts...)
+}
+
+// NewWithDB creates a DB using the *sql.DB handles returned by the given
+// factory, e.g. the ones created through a connector configuring TLS and
+// authentication. Factory is called for every new connection of the pool, and
+// must return a new handle every time. Every handle is used as a single
+// connection, it's limited to one open and one idle connection.
+func NewWithDB(factory Factory, opts ...Option) (*DB, error) {
+	if factory == nil {
+		return nil, ErrNilFactory
+	}
+
+	return OpenWithFactory(singleConn(factory), opts...)
+}
+
+// singleConn limits the handles of the given factory to one open and one idle
+// connection
+func singleConn(factory Factory) Factory {
+	return func() (*sql.DB, error) {
+		d, err := factory()
+		if err != nil {
+			return nil, err
+		}
+
+		d.SetMaxIdleConns(1)
+		d.SetMaxOpenConns(1)
+		return d, nil
+	}
+}
+
+// OpenWithFactory creates a DB using the *sql.DB handles returned by the given
+// factory as they are, so the factory has the full control over the handles;
+// their limits, the setup of new connections and their credentials. Factory is
+// called for every new connection of the pool, and must return a new handle
+// every time. A handle is closed when an operation on it times out, so it
+// should be limited to one open connection, see NewWithDB.
+func OpenWithFactory(factory Factory, opts ...Option) (*DB, error) {
+	if factory == nil {
+		return nil, ErrNilFactory
+	}
+
 	// We wrap *sql.DB into our DB
 	db := &DB{
 		maxOpenConns: maxOpenConns,
-		sem:          make(chan struct{}, maxOpenConns),
+		maxIdleConns: -1, // defaults to maxOpenConns
+		factory:      factory,
+	}
 
-		conns: make(chan *sql.DB, maxOpenConns),
-		factory: func() (*sql.DB, error) {
-			d, err := sql.Open(driver, dsn)
-			if err != nil {
-				return nil, err
-			}
+	for _, opt := range opts {
+		opt(db)
+	}
 
-			d.SetMaxIdleConns(1)
-			d.SetMaxOpenConns(1)
-			return d, nil
-		},
+	if db.maxOpenConns < 1 {
+		return nil, ErrInvalidPoolSize
 	}
 
-	for i := 0; i < maxOpenConns; i++ {
+	if db.maxIdleConns < 0 || db.maxIdleConns > db.maxOpenConns {
+		db.maxIdleConns = db.maxOpenConns
+	}
+
+	db.sem = make(chan struct{}, db.maxOpenConns)
+	db.conns = make(chan *sql.DB, db.maxIdleConns)
+
+	for i := 0; i < db.maxOpenConns; i++ {
 		db.sem <- struct{}{}
 	}
 
+	if db.healthCheck != nil {
+		go db.runHealthCheck()
+	}
+
+	if db.statsHistory != nil {
+		go db.recordStats()
+	}
+
+	if db.timeoutAdvisor != nil && db.timeoutAdvisor.config.ApplyEvery > 0 {
+		go db.applySuggestions()
+	}
+
 	return db, nil
 }
 
 // Begin starts a transaction. The isolation level is dependent on the driver.
+//
+// Transaction is bound to the given ctx; if the ctx is done before the
+// transaction is committed or rolled back, it is rolled back and its
+// connection is recycled, and its later operations fail with the ctx error.
 func (db *DB) Begin(ctx context.Context) (*Tx, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	ctx, end := db.startOp(ctx, nil, "Tx", "", nil)
+	tx, err := db.begin(ctx)
+	if err != nil {
+		end(-1, err)
+		return nil, err
+	}
+
+	tx.spanCtx, tx.spanEnd = ctx, end
+	trackTx(ctx, tx)
+	return tx, nil
+}
+
+// begin is Begin without the span of the transaction
+func (db *DB) begin(ctx context.Context) (*Tx, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	db.observe(ctx, "Begin")
+
 	done := make(chan struct{}, 1)
 
+	db.mu.Lock()
+	reported := db.txReport != nil
+	db.mu.Unlock()
+
 	var err error
 	var tx *sql.Tx
+	var stats *txStats
 	f := func(sqldb *sql.DB) {
-		tx, err = sqldb.Begin()
+		if reported {
+			stats = &txStats{started: time.Now()}
+			// pid is read before the transaction, so SET TRANSACTION can be
+			// the first statement of it. Lock waits are probed on postgres
+			// only, errors are ignored
+			sqldb.QueryRow(backendPIDSQLStatement).Scan(&stats.pid)
+		}
+
+		tx, err = dbBegin(ctx, sqldb)
 		close(done)
 	}
 
-	sqldb, opErr := db.handleWithSQL(ctx, f, done)
+	sqldb, opErr := db.handleWithSQLContext(ctx, f, done)
 	if opErr != nil {
 		return nil, opErr
 	}
 
-	return &Tx{
+	if err != nil {
+		db.restoreOrClose(err, sqldb)
+		return nil, err
+	}
+
+	db.trackLeak(sqldb, "Begin")
+
+	t := &Tx{
+		id:    nextOperationID(),
 		tx:    tx,
 		sqldb: sqldb,
 		db:    db,
-	}, nil
+		stats: stats,
+		attrs: db.connAttributes(sqldb),
+	}
+
+	if db.getTxJournal() != nil {
+		t.journal = &TxJournal{}
+	}
+
+	t.startIdle()
+	t.watchdog(ctx)
+	return t, nil
 }
 
-// Close closes the all connections
-func (db *DB) Close() error {
+// Close closes the DB gracefully. It stops handing out connections, new
+// operations fail with ErrClosed, then waits for the in-flight operations to
+// put their connections back, and closes all the connections. If ctx is done
+// before the in-flight operations finish, returns the ctx error, connections of
+// the in-flight operations are closed as they are put back.
+func (db *DB) Close(ctx context.Context) error {
+	if err := db.check(); err != nil {
+		return err
+	}
+
 	db.mu.Lock()
 	conns := db.conns
 	db.conns = nil
@@ -104,6 +319,15 @@ func (db *DB) Close() error {
 		return ErrClosed
 	}
 
+	db.stopHealthCheck()
+	db.stopStatsHistory()
+	db.stopTimeoutAdvisor()
+	db.stopReaper()
+	db.stopKeeper()
+	db.stopStandby()
+
+	drainErr := db.drain(ctx)
+
 	close(conns)
 
 	for conn := range conns {
@@ -111,16 +335,41 @@ func (db *DB) Close() error {
 			continue
 		}
 
-		if err := conn.Close(); err != nil {
+		if err := db.closeConn(conn); err != nil {
 			return err
 		}
 	}
 
+	return drainErr
+}
+
+// drain waits for all sems to be given back, so no operation is in-flight
+func (db *DB) drain(ctx context.Context) error {
+	var acquired int
+	defer func() {
+		for i := 0; i < acquired; i++ {
+			db.release()
+		}
+	}()
+
+	for acquired < cap(db.sem) {
+		select {
+		case <-db.sem:
+			acquired++
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
 	return nil
 }
 
 // Driver returns the database's underlying driver.
 func (db *DB) Driver(ctx context.Context) driver.Driver {
+	if err := db.check(); err != nil {
+		return nil
+	}
+
 	done := make(chan struct{}, 1)
 
 	var res driver.Driver
@@ -139,41 +388,166 @@ func (db *DB) Driver(ctx context.Context) driver.Driver {
 
 // Exec executes a query without returning any rows. The args are for any
 // placeholder parameters in the query.
-func (db *DB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	done := make(chan struct{}, 1)
+func (db *DB) Exec(ctx context.Context, query string, args ...interface{}) (*Result, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
 
-	var res sql.Result
-	var err error
+	op := &Op{Name: "Exec", Query: query, Args: args}
+	err := db.intercept(ctx, op, func(ctx context.Context, op *Op) error {
+		ctx, end := db.startOp(ctx, nil, op.Name, op.Query, op.Args)
 
-	f := func(sqldb *sql.DB) {
-		res, err = sqldb.Exec(query, args...)
-		close(done)
+		var err error
+		op.Result, err = db.exec(ctx, op.Query, op.Args...)
+		end(op.Result.rowsAffected(), err)
+		return err
+	})
+
+	return op.Result, db.mapDriverError(err)
+}
+
+// exec is Exec without the span
+func (db *DB) exec(ctx context.Context, query string, args ...interface{}) (*Result, error) {
+	if err := db.check(); err != nil {
+		return nil, err
 	}
 
-	if err := db.process(ctx, f, done); err != nil {
+	db.observe(ctx, "Exec")
+	start := time.Now()
+
+	if err := prepareArgs(args); err != nil {
+		return nil, err
+	}
+
+	if err := db.guardDeadline(ctx, query); err != nil {
+		return nil, err
+	}
+
+	if err := db.guardDDL(ctx, query); err != nil {
+		return nil, err
+	}
+
+	if err := db.guardBreaker(); err != nil {
 		return nil, err
 	}
 
-	return res, err
+	ctx, cancel := db.applyPolicy(ctx)
+	defer cancel()
+
+	// policy timeouts are deadlines too
+	if err := db.misuseNoDeadline(ctx, "Exec"); err != nil {
+		return nil, err
+	}
+
+	if err := db.guardCost(ctx, query, args); err != nil {
+		return nil, err
+	}
+
+	if IsDryRun(ctx) {
+		return db.execDryRun(ctx, query, args)
+	}
+
+	if f, verb, table := db.getCapture(query); f != nil {
+		return db.execCapture(ctx, f, verb, table, query, args...)
+	}
+
+	var res sql.Result
+	err := db.retry(ctx, func() error {
+		done := make(chan struct{}, 1)
+
+		var err error
+
+		f := func(sqldb *sql.DB) {
+			if stmt := db.pinned(ctx, sqldb, query); stmt != nil {
+				res, err = stmtExec(ctx, stmt, args)
+			} else {
+				res, err = dbExec(ctx, sqldb, tagQuery(ctx, query), args)
+			}
+			close(done)
+		}
+
+		if err := db.processContext(ctx, f, done); err != nil {
+			return err
+		}
+
+		return err
+	})
+	db.recordBreaker(err)
+	db.reportOp("Exec", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{id: nextOperationID(), res: res, query: query}, nil
 }
 
 // Ping verifies a connection to the database is still alive, establishing a
 // connection if necessary.
+//
+// Concurrent calls are coalesced into a single ping, run with the ctx of the
+// first call, so health checks from many goroutines do not consume the sems of
+// the pool. Every call still returns when its own ctx is done.
 func (db *DB) Ping(ctx context.Context) error {
+	if err := db.check(); err != nil {
+		return err
+	}
+
+	db.observe(ctx, "Ping")
+	start := time.Now()
+
+	if err := db.guardBreaker(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	if c := db.pingCall; c != nil {
+		db.mu.Unlock()
+
+		select {
+		case <-c.done:
+			return c.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	c := &pingCall{done: make(chan struct{})}
+	db.pingCall = c
+	db.mu.Unlock()
+
+	c.err = db.ping(ctx)
+	db.recordBreaker(c.err)
+	db.reportOp("Ping", start, c.err)
+
+	db.mu.Lock()
+	db.pingCall = nil
+	db.mu.Unlock()
+
+	close(c.done)
+	return c.err
+}
+
+// pingCall is an in-flight ping shared by the concurrent Ping calls
+type pingCall struct {
+	done chan struct{}
+	err  error
+}
+
+func (db *DB) ping(ctx context.Context) error {
 	done := make(chan struct{}, 1)
 
 	var err error
 
 	f := func(sqldb *sql.DB) {
-		err = sqldb.Ping()
+		err = dbPing(ctx, sqldb)
 		close(done)
 	}
 
-	if err := db.process(ctx, f, done); err != nil {
+	if err := db.processContext(ctx, f, done); err != nil {
 		return err
 	}
 
-	return nil
+	return err
 }
 
 // Prepare creates a prepared statement for later queries or executions.
@@ -181,15 +555,23 @@ func (db *DB) Ping(ctx context.Context) error {
 // statement. The caller must call the statement's Close method when the
 // statement is no longer needed.
 func (db *DB) Prepare(ctx context.Context, query string) (*Stmt, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	db.observe(ctx, "Prepare")
+
+	query, names := db.bindStmt(query)
+
 	done := make(chan struct{}, 0)
 	var res *sql.Stmt
 	var queryErr error
 	f := func(sqldb *sql.DB) {
-		res, queryErr = sqldb.Prepare(query)
+		res, queryErr = dbPrepare(ctx, sqldb, query)
 		close(done)
 	}
 
-	sqldb, err := db.handleWithSQL(ctx, f, done)
+	sqldb, err := db.handleWithSQLContext(ctx, f, done)
 	if err != nil {
 		return nil, err
 	}
@@ -198,39 +580,130 @@ func (db *DB) Prepare(ctx context.Context, query string) (*Stmt, error) {
 		return nil, queryErr
 	}
 
-	return &Stmt{
+	stmt := &Stmt{
+		id:    nextOperationID(),
 		stmt:  res,
 		query: query,
+		names: names,
 		sqldb: sqldb,
 		db:    db,
-	}, nil
+	}
+
+	trackStmt(ctx, stmt)
+	return stmt, nil
 }
 
 // Query executes a query that returns rows, typically a SELECT. The args are
 // for any placeholder parameters in the query.
 func (db *DB) Query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
-	done := make(chan struct{}, 0)
-	var res *sql.Rows
-	var queryErr error
-	f := func(sqldb *sql.DB) {
-		res, queryErr = sqldb.Query(query, args...)
-		close(done)
+	if err := db.check(); err != nil {
+		return nil, err
 	}
 
-	sqldb, err := db.handleWithSQL(ctx, f, done)
+	op := &Op{Name: "Query", Query: query, Args: args}
+	err := db.intercept(ctx, op, func(ctx context.Context, op *Op) error {
+		ctx, end := db.startOp(ctx, nil, op.Name, op.Query, op.Args)
+
+		var err error
+		op.Rows, err = db.query(ctx, op.Query, op.Args...)
+		end(-1, err)
+		trackRows(ctx, op.Rows)
+		return err
+	})
+
+	return op.Rows, db.mapDriverError(err)
+}
+
+// query is Query without the span
+func (db *DB) query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	db.observe(ctx, "Query")
+	start := time.Now()
+
+	if err := prepareArgs(args); err != nil {
+		return nil, err
+	}
+
+	if err := db.guardDryRun(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := db.guardDeadline(ctx, query); err != nil {
+		return nil, err
+	}
+
+	if err := db.guardBreaker(); err != nil {
+		return nil, err
+	}
+
+	origin := ctx
+	ctx, cancel := db.applyPolicy(ctx)
+	defer cancel()
+
+	if err := db.guardCost(ctx, query, args); err != nil {
+		return nil, err
+	}
+
+	var res *sql.Rows
+	var sqldb *sql.DB
+	err := db.retry(ctx, func() error {
+		done := make(chan struct{}, 0)
+		var queryErr error
+		f := func(sqldb *sql.DB) {
+			if stmt := db.pinned(ctx, sqldb, query); stmt != nil {
+				res, queryErr = stmtQuery(ctx, stmt, args)
+			} else {
+				res, queryErr = dbQuery(ctx, sqldb, tagQuery(ctx, query), args)
+			}
+			close(done)
+		}
+
+		var err error
+		sqldb, err = db.handleWithSQLContext(ctx, f, done)
+		if err != nil {
+			return err
+		}
+
+		if queryErr != nil {
+			// give the conn back, closing it if it is broken
+			var brokenErr error
+			if isBrokenConn(queryErr) {
+				brokenErr = queryErr
+			}
+			db.restoreOrClose(brokenErr, sqldb)
+		}
+
+		return queryErr
+	})
+	db.recordBreaker(err)
+	db.reportOp("Query", start, err)
 	if err != nil {
 		return nil, err
 	}
 
-	if queryErr != nil {
-		return nil, queryErr
+	db.trackLeak(sqldb, "Query")
+
+	rs := &Rows{
+		id:     nextOperationID(),
+		rows:   res,
+		sqldb:  sqldb,
+		db:     db,
+		origin: origin,
+		attrs:  db.connAttributes(sqldb),
 	}
 
-	return &Rows{
-		rows:  res,
-		sqldb: sqldb,
-		db:    db,
-	}, nil
+	db.mu.Lock()
+	iterationTimeout := db.iterationTimeout
+	db.mu.Unlock()
+
+	if iterationTimeout > 0 {
+		rs.SetIterationTimeout(iterationTimeout)
+	}
+
+	return rs, nil
 
 }
 
@@ -238,40 +711,183 @@ func (db *DB) Query(ctx context.Context, query string, args ...interface{}) (*Ro
 // QueryRow always return a non-nil value. Errors are deferred until Row's Scan
 // method is called.
 func (db *DB) QueryRow(ctx context.Context, query string, args ...interface{}) *Row {
-	done := make(chan struct{}, 0)
+	if err := db.check(); err != nil {
+		return &Row{err: err}
+	}
 
-	var res *sql.Row
+	op := &Op{Name: "QueryRow", Query: query, Args: args}
+	err := db.intercept(ctx, op, func(ctx context.Context, op *Op) error {
+		ctx, end := db.startOp(ctx, nil, op.Name, op.Query, op.Args)
 
-	f := func(sqldb *sql.DB) {
-		res = sqldb.QueryRow(query, args...)
-		close(done)
+		op.Row = db.queryRow(ctx, op.Query, op.Args...)
+		op.Row.traceWith(end)
+		return op.Row.err
+	})
+
+	if op.Row == nil {
+		// stopped by a middleware
+		return &Row{id: nextOperationID(), err: err}
 	}
 
-	sqldb, err := db.handleWithSQL(ctx, f, done)
-	if err != nil {
+	return op.Row
+}
+
+// queryRow is QueryRow without the span
+func (db *DB) queryRow(ctx context.Context, query string, args ...interface{}) *Row {
+	if err := db.check(); err != nil {
 		return &Row{err: err}
 	}
 
+	db.observe(ctx, "QueryRow")
+	start := time.Now()
+
+	if err := prepareArgs(args); err != nil {
+		return &Row{id: nextOperationID(), err: err}
+	}
+
+	if err := db.guardDryRun(ctx); err != nil {
+		return &Row{id: nextOperationID(), err: err}
+	}
+
+	if err := db.guardDeadline(ctx, query); err != nil {
+		return &Row{id: nextOperationID(), err: err}
+	}
+
+	if err := db.guardBreaker(); err != nil {
+		return &Row{id: nextOperationID(), err: err}
+	}
+
+	ctx, cancel := db.applyPolicy(ctx)
+	defer cancel()
+
+	if err := db.guardCost(ctx, query, args); err != nil {
+		return &Row{id: nextOperationID(), err: err}
+	}
+
+	var res *sql.Row
+	var sqldb *sql.DB
+	err := db.retry(ctx, func() error {
+		done := make(chan struct{}, 0)
+		var queryErr error
+		f := func(sqldb *sql.DB) {
+			if stmt := db.pinned(ctx, sqldb, query); stmt != nil {
+				res, queryErr = stmtQueryRow(ctx, stmt, args)
+			} else {
+				res, queryErr = dbQueryRow(ctx, sqldb, tagQuery(ctx, query), args)
+			}
+			close(done)
+		}
+
+		var err error
+		sqldb, err = db.handleWithSQLContext(ctx, f, done)
+		if err != nil {
+			return err
+		}
+
+		if queryErr != nil {
+			// query is stopped by the driver, conn can be reused
+			db.restoreOrClose(nil, sqldb)
+		}
+
+		return queryErr
+	})
+	if err != nil {
+		db.recordBreaker(err)
+		db.reportOp("QueryRow", start, err)
+		return &Row{id: nextOperationID(), err: err}
+	}
+
+	db.trackLeak(sqldb, "QueryRow")
+
 	return &Row{
-		row:   res,
-		sqldb: sqldb,
-		db:    db,
+		id:      nextOperationID(),
+		row:     res,
+		sqldb:   sqldb,
+		db:      db,
+		attrs:   db.connAttributes(sqldb),
+		started: start,
+	}
+}
+
+// Raw checks out an underlying *sql.DB from the pool, calls f with it, and puts
+// it back to the pool, for integrating the libraries which require a *sql.DB.
+// Underlying *sql.DB holds a single connection, so operations of f run on the
+// same connection. f must not retain the *sql.DB after it returns.
+//
+// Uses the given ctx and its deadline to signal timeouts. On timeout or cancel
+// case, closes the underlying connection.
+func (db *DB) Raw(ctx context.Context, f func(sqldb *sql.DB) error) error {
+	if err := db.check(); err != nil {
+		return err
+	}
+
+	db.observe(ctx, "Raw")
+
+	done := make(chan struct{}, 1)
+
+	var err error
+
+	g := func(sqldb *sql.DB) {
+		err = f(sqldb)
+		close(done)
+	}
+
+	if opErr := db.process(ctx, g, done); opErr != nil {
+		return opErr
 	}
+
+	return err
 }
 
 // SetMaxIdleConns sets the maximum number of connections in the idle connection
-// pool.
+// pool, closing the idle connections above it. It can not exceed the max idle
+// conns the DB is opened with, since the pool is not resized. Zero or less
+// means no idle connections are kept.
 func (db *DB) SetMaxIdleConns(i int) {
-	panic("not fully implemented")
-}
+	if db == nil {
+		return
+	}
 
-// SetMaxOpenConns sets the maximum number of open connections to the database.
-func (db *DB) SetMaxOpenConns(i int) {
 	db.mu.Lock()
-	db.maxOpenConns = i
-	db.mu.Unlock()
+	defer db.mu.Unlock()
+
+	if i < 0 {
+		i = 0
+	}
+
+	if db.conns == nil {
+		db.maxIdleConns = i
+		return
+	}
 
-	// panic("not fully implemented")
+	if i > cap(db.conns) {
+		i = cap(db.conns)
+	}
+
+	db.maxIdleConns = i
+
+	for len(db.conns) > i {
+		select {
+		case conn := <-db.conns:
+			db.closeConnLocked(conn)
+		default:
+			return
+		}
+	}
+}
+
+// SetMaxOpenConns is kept for the compatibility with database/sql, it does
+// nothing. The pool is sized when the DB is opened, see WithMaxOpenConns, and
+// it is not resized afterwards.
+func (db *DB) SetMaxOpenConns(i int) {}
+
+// check returns ErrNotInitialized if db is nil or not created with Open
+func (db *DB) check() error {
+	if db == nil || db.sem == nil {
+		return ErrNotInitialized
+	}
+
+	return nil
 }
 
 // process accepts context for deadlines, f for operation, and done channel for
@@ -292,38 +908,66 @@ func (db *DB) process(ctx context.Context, f func(sqldb *sql.DB), done chan stru
 // recycling a new db. If operation is successfull, returns the underlying db
 // connection, receiver must handle the sem communication and db lifecycle
 func (db *DB) handleWithSQL(ctx context.Context, f func(sqldb *sql.DB), done chan struct{}) (*sql.DB, error) {
-	select {
-	case <-db.sem:
-		var err error
+	return db.handleWith(ctx, f, done, db.handleWithGivenSQL)
+}
 
-		defer func() {
-			// db is not inuse anymore
-			if err != nil {
-				select {
-				case db.sem <- struct{}{}:
-				default:
-					panic("sem overflow 5")
-				}
-			}
-		}()
+// processContext is process for the operations using the context aware
+// methods, see handleContext.
+func (db *DB) processContext(ctx context.Context, f func(sqldb *sql.DB), done chan struct{}) error {
+	sqldb, err := db.handleWithSQLContext(ctx, f, done)
+	if err != nil {
+		return err
+	}
 
-		// we aquired one connection sem, continue with that
-		sqldb, err := db.getFromPool()
-		if err != nil {
-			return nil, err
+	return db.restoreOrClose(nil, sqldb)
+}
+
+// handleWithSQLContext is handleWithSQL for the operations using the context
+// aware methods, see handleContext.
+func (db *DB) handleWithSQLContext(ctx context.Context, f func(sqldb *sql.DB), done chan struct{}) (*sql.DB, error) {
+	return db.handleWith(ctx, f, done, db.handleContext)
+}
+
+// handleWith acquires a sem and a connection, and runs f with the connection
+// using the given handle func. Sem is given back if the handle fails.
+func (db *DB) handleWith(ctx context.Context, f func(sqldb *sql.DB), done chan struct{}, handle func(ctx context.Context, f func(), done chan struct{}, sqldb *sql.DB) error) (*sql.DB, error) {
+	// do not wait for a sem while the db is closing
+	if db.getConns() == nil {
+		return nil, ErrClosed
+	}
+
+	if err := db.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	var err error
+
+	defer func() {
+		// db is not inuse anymore
+		if err != nil && !db.release() {
+			panic("sem overflow 5")
 		}
+	}()
 
-		fn := func() { f(sqldb) }
+	// we aquired one connection sem, continue with that
+	sqldb, err := db.getFromPool(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		err = db.handleWithGivenSQL(ctx, fn, done, sqldb)
-		if err != nil {
-			return nil, err
+	fn := func() { f(sqldb) }
+
+	err = handle(ctx, fn, done, sqldb)
+	if err != nil {
+		// query is stopped on the server, conn can be reused
+		if db.takeCancelled(sqldb) {
+			db.put(sqldb)
 		}
 
-		return sqldb, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
+		return nil, err
 	}
+
+	return sqldb, nil
 }
 
 func (db *DB) processWithGivenSQL(ctx context.Context, f func(), done chan struct{}, sqldb *sql.DB) error {
@@ -334,40 +978,70 @@ func (db *DB) processWithGivenSQL(ctx context.Context, f func(), done chan struc
 // handleWithGivenSQL closes the given db connection if given context return an
 // error while executing the give f func
 func (db *DB) handleWithGivenSQL(ctx context.Context, f func(), done chan struct{}, sqldb *sql.DB) error {
-	var err error
+	return db.handleWithOrigin(ctx, nil, f, done, sqldb)
+}
+
+// handleWithOrigin is handleWithGivenSQL additionally observing the origin
+// context, which is the context of the operation created the resource using the
+// given db connection. Nil origin is never done.
+func (db *DB) handleWithOrigin(ctx, origin context.Context, f func(), done chan struct{}, sqldb *sql.DB) error {
+	var originDone <-chan struct{}
+	if origin != nil {
+		originDone = origin.Done()
+	}
 
 	go f()
 
 	select {
 	case <-ctx.Done():
-		err = sqldb.Close()
-		if err != nil {
+		if db.cancelQuery(sqldb, done) {
+			return ctx.Err()
+		}
+
+		if err := db.closeCancelled(sqldb); err != nil {
 			return err
 		}
 
-		err = ctx.Err()
-		return err
+		return ctx.Err()
+	case <-originDone:
+		if db.cancelQuery(sqldb, done) {
+			return origin.Err()
+		}
+
+		if err := db.closeCancelled(sqldb); err != nil {
+			return err
+		}
+
+		return origin.Err()
 	case <-done:
 		return nil
 	}
-
 }
 
 func (db *DB) restoreOrClose(err error, sqldb *sql.DB) error {
-	select {
-	case db.sem <- struct{}{}:
-		if err == nil {
-			return db.put(sqldb)
-		}
+	db.untrackLeak(sqldb)
 
-		// Close is idempotent
-		if err := sqldb.Close(); err != nil {
-			return err
+	if !db.release() {
+		return errors.New("sem overflow in restoreOrClose")
+	}
+
+	if err == nil {
+		return db.put(sqldb)
+	}
+
+	// query is stopped on the server, conn can be reused
+	if db.takeCancelled(sqldb) {
+		if putErr := db.put(sqldb); putErr != nil {
+			return putErr
 		}
 
 		return err
+	}
 
-	default:
-		return errors.New("sem overflow in restoreOrClose")
+	// Close is idempotent
+	if err := db.closeConn(sqldb); err != nil {
+		return err
 	}
+
+	return err
 }
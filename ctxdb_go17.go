@@ -0,0 +1,74 @@
+// +build !go1.8
+
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Before Go 1.8, database/sql has no context aware methods, operations are
+// raced against ctx, and the connection is closed on timeout or cancel case.
+
+// handleContext runs f racing it against ctx, see handleWithGivenSQL.
+func (db *DB) handleContext(ctx context.Context, f func(), done chan struct{}, sqldb *sql.DB) error {
+	return db.handleWithGivenSQL(ctx, f, done, sqldb)
+}
+
+// wait waits for done or ctx, returns the ctx error if ctx is done first.
+func wait(ctx context.Context, done chan struct{}) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
+
+func dbBegin(ctx context.Context, sqldb *sql.DB) (*sql.Tx, error) {
+	return sqldb.Begin()
+}
+
+func dbQuery(ctx context.Context, sqldb *sql.DB, query string, args []interface{}) (*sql.Rows, error) {
+	return sqldb.Query(query, args...)
+}
+
+func dbQueryRow(ctx context.Context, sqldb *sql.DB, query string, args []interface{}) (*sql.Row, error) {
+	return sqldb.QueryRow(query, args...), nil
+}
+
+func stmtQuery(ctx context.Context, stmt *sql.Stmt, args []interface{}) (*sql.Rows, error) {
+	return stmt.Query(args...)
+}
+
+func stmtQueryRow(ctx context.Context, stmt *sql.Stmt, args []interface{}) (*sql.Row, error) {
+	return stmt.QueryRow(args...), nil
+}
+
+func dbExec(ctx context.Context, sqldb *sql.DB, query string, args []interface{}) (sql.Result, error) {
+	return sqldb.Exec(query, args...)
+}
+
+func dbScanRow(ctx context.Context, sqldb *sql.DB, query string, args []interface{}, dest ...interface{}) error {
+	return sqldb.QueryRow(query, args...).Scan(dest...)
+}
+
+func dbPing(ctx context.Context, sqldb *sql.DB) error {
+	return sqldb.Ping()
+}
+
+func dbPrepare(ctx context.Context, sqldb *sql.DB, query string) (*sql.Stmt, error) {
+	return sqldb.Prepare(query)
+}
+
+func txExec(ctx context.Context, tx *sql.Tx, query string, args []interface{}) (sql.Result, error) {
+	return tx.Exec(query, args...)
+}
+
+func txPrepare(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	return tx.Prepare(query)
+}
+
+func stmtExec(ctx context.Context, stmt *sql.Stmt, args []interface{}) (sql.Result, error) {
+	return stmt.Exec(args...)
+}
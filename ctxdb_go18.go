@@ -0,0 +1,165 @@
+// +build go1.8
+
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// On Go 1.8 and later, Begin, Exec, Query, QueryRow, Ping and Prepare of DB,
+// Exec of Stmt, and Exec and Prepare of Tx delegate to the context aware
+// methods of database/sql. The driver stops the operation when ctx is done, so
+// the operation is waited instead of racing it against ctx, and the connection
+// is kept. Query and QueryRow of DB are stopped only if ctx is done before they
+// return, since the rows outlive the ctx of the call. Other operations
+// returning rows keep racing.
+
+// handleContext runs f, which uses the context aware methods, and waits for it.
+func (db *DB) handleContext(ctx context.Context, f func(), done chan struct{}, sqldb *sql.DB) error {
+	go f()
+	<-done
+	return nil
+}
+
+// wait waits for done, the operation signalling it uses the context aware
+// methods.
+func wait(ctx context.Context, done chan struct{}) error {
+	<-done
+	return nil
+}
+
+// contextErr returns the error of ctx instead of the given err, if the
+// operation is stopped because ctx is done, since drivers report it with
+// their own errors.
+func contextErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// untilReturn returns a ctx with the values of ctx, which is canceled if ctx is
+// done before the returned stop func is called, and never after it, for the
+// operations returning rows which outlive the ctx of the call. Stop reports
+// whether the returned ctx is canceled.
+func untilReturn(ctx context.Context) (context.Context, func() bool) {
+	rctx, cancel := context.WithCancel(detached(ctx))
+
+	var mu sync.Mutex
+	var stopped, canceled bool
+	returned := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			if !stopped {
+				canceled = true
+				cancel()
+			}
+			mu.Unlock()
+		case <-returned:
+		}
+	}()
+
+	return rctx, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !stopped {
+			stopped = true
+			close(returned)
+		}
+
+		return canceled
+	}
+}
+
+func dbBegin(ctx context.Context, sqldb *sql.DB) (*sql.Tx, error) {
+	tx, err := sqldb.BeginTx(ctx, nil)
+	return tx, contextErr(ctx, err)
+}
+
+func dbQuery(ctx context.Context, sqldb *sql.DB, query string, args []interface{}) (*sql.Rows, error) {
+	rctx, stop := untilReturn(ctx)
+	rows, err := sqldb.QueryContext(rctx, query, args...)
+	if stop() {
+		if err == nil {
+			rows.Close()
+		}
+
+		return nil, ctx.Err()
+	}
+
+	return rows, err
+}
+
+func dbQueryRow(ctx context.Context, sqldb *sql.DB, query string, args []interface{}) (*sql.Row, error) {
+	rctx, stop := untilReturn(ctx)
+	row := sqldb.QueryRowContext(rctx, query, args...)
+	if stop() {
+		return nil, ctx.Err()
+	}
+
+	return row, nil
+}
+
+func stmtQuery(ctx context.Context, stmt *sql.Stmt, args []interface{}) (*sql.Rows, error) {
+	rctx, stop := untilReturn(ctx)
+	rows, err := stmt.QueryContext(rctx, args...)
+	if stop() {
+		if err == nil {
+			rows.Close()
+		}
+
+		return nil, ctx.Err()
+	}
+
+	return rows, err
+}
+
+func stmtQueryRow(ctx context.Context, stmt *sql.Stmt, args []interface{}) (*sql.Row, error) {
+	rctx, stop := untilReturn(ctx)
+	row := stmt.QueryRowContext(rctx, args...)
+	if stop() {
+		return nil, ctx.Err()
+	}
+
+	return row, nil
+}
+
+func dbExec(ctx context.Context, sqldb *sql.DB, query string, args []interface{}) (sql.Result, error) {
+	res, err := sqldb.ExecContext(ctx, query, args...)
+	return res, contextErr(ctx, err)
+}
+
+func dbScanRow(ctx context.Context, sqldb *sql.DB, query string, args []interface{}, dest ...interface{}) error {
+	return contextErr(ctx, sqldb.QueryRowContext(ctx, query, args...).Scan(dest...))
+}
+
+func dbPing(ctx context.Context, sqldb *sql.DB) error {
+	return contextErr(ctx, sqldb.PingContext(ctx))
+}
+
+func dbPrepare(ctx context.Context, sqldb *sql.DB, query string) (*sql.Stmt, error) {
+	stmt, err := sqldb.PrepareContext(ctx, query)
+	return stmt, contextErr(ctx, err)
+}
+
+func txExec(ctx context.Context, tx *sql.Tx, query string, args []interface{}) (sql.Result, error) {
+	res, err := tx.ExecContext(ctx, query, args...)
+	return res, contextErr(ctx, err)
+}
+
+func txPrepare(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	stmt, err := tx.PrepareContext(ctx, query)
+	return stmt, contextErr(ctx, err)
+}
+
+func stmtExec(ctx context.Context, stmt *sql.Stmt, args []interface{}) (sql.Result, error) {
+	res, err := stmt.ExecContext(ctx, args...)
+	return res, contextErr(ctx, err)
+}
@@ -0,0 +1,81 @@
+// +build go1.8
+
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExecKeepsConnOnTimeout(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	conn := <-db.conns
+	db.conns <- conn
+
+	timedoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+
+	if _, err := db.Exec(timedoutCtx, "SELECT pg_sleep(1)"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	// statement is cancelled by the driver, connection is put back
+	if len(db.conns) != 1 || <-db.conns != conn {
+		t.Fatal("expected the connection to be put back to the pool")
+	}
+}
+
+func TestQueryKeepsConnOnTimeout(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	conn := <-db.conns
+	db.conns <- conn
+
+	timedoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+
+	if _, err := db.Query(timedoutCtx, "SELECT pg_sleep(1)"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	// query is cancelled by the driver, connection is put back
+	if len(db.conns) != 1 || <-db.conns != conn {
+		t.Fatal("expected the connection to be put back to the pool")
+	}
+}
+
+func TestQueryRowsOutlivePolicyCtx(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	// ctx of the default timeout is canceled when Query returns
+	db.SetDefaultTimeout(time.Minute)
+
+	ctx := context.Background()
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if !rows.Next(ctx) {
+		t.Fatalf("expected a row, got: %v", rows.Err())
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
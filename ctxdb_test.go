@@ -1,13 +1,14 @@
 package ctxdb
 
 import (
+	"context"
 	"database/sql"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/cihangir/nisql"
 	_ "github.com/lib/pq"
-	"golang.org/x/net/context"
 )
 
 func TestPing(t *testing.T) {
@@ -18,7 +19,7 @@ func TestPing(t *testing.T) {
 		t.Errorf("Err while pinging: %# v", err)
 	}
 
-	if err := p.Close(); err != nil {
+	if err := p.Close(ctx); err != nil {
 		t.Errorf("Err should be nil:  got %# v", err)
 	}
 }
@@ -341,6 +342,291 @@ func TestQueryRowWithTimeout(t *testing.T) {
 	}
 }
 
+func TestRaw(t *testing.T) {
+	db := getConn(t)
+	ctx := context.Background()
+
+	err := db.Raw(ctx, func(sqldb *sql.DB) error {
+		return sqldb.Ping()
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	timeout := time.Millisecond * 10
+	timedoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = db.Raw(timedoutCtx, func(sqldb *sql.DB) error {
+		time.Sleep(timeout * 2)
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %s", err)
+	}
+
+	// sem is given back in both cases
+	if len(db.sem) != cap(db.sem) {
+		t.Fatalf("expected %d sems, got: %d", cap(db.sem), len(db.sem))
+	}
+}
+
+func TestNewWithDB(t *testing.T) {
+	var calls int
+	db, err := NewWithDB(func() (*sql.DB, error) {
+		calls++
+		return sql.Open(os.Getenv("NISQL_TEST_DIALECT"), os.Getenv("NISQL_TEST_DSN"))
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected 1 factory call, got: %d", calls)
+	}
+
+	sqldb := <-db.conns
+	if stats := sqldb.Stats(); stats.MaxOpenConnections != 1 {
+		t.Fatalf("expected 1 max open conn, got: %d", stats.MaxOpenConnections)
+	}
+	db.conns <- sqldb
+}
+
+func TestOpenWithFactory(t *testing.T) {
+	db, err := OpenWithFactory(func() (*sql.DB, error) {
+		d, err := sql.Open(os.Getenv("NISQL_TEST_DIALECT"), os.Getenv("NISQL_TEST_DSN"))
+		if err != nil {
+			return nil, err
+		}
+
+		d.SetMaxOpenConns(1)
+		d.SetMaxIdleConns(0)
+		return d, nil
+	}, WithMaxOpenConns(1))
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// limits of the factory are kept
+	sqldb := <-db.conns
+	if stats := sqldb.Stats(); stats.Idle != 0 {
+		t.Fatalf("expected no idle conns, got: %d", stats.Idle)
+	}
+	db.conns <- sqldb
+
+	if _, err := OpenWithFactory(nil); err != ErrNilFactory {
+		t.Fatalf("expected ErrNilFactory, got: %v", err)
+	}
+}
+
+func TestCloseDrainsInFlightOperations(t *testing.T) {
+	db := getConn(t)
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		rows.Close(ctx)
+	}()
+
+	if err := db.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// rows are closed before the db
+	if len(db.sem) != cap(db.sem) {
+		t.Fatalf("expected %d sems, got: %d", cap(db.sem), len(db.sem))
+	}
+
+	if err := db.Ping(ctx); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got: %v", err)
+	}
+}
+
+func TestCloseWithTimeout(t *testing.T) {
+	db := getConn(t)
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	timedoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+
+	if err := db.Close(timedoutCtx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	// connection of the in-flight rows is closed as it's put back
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(db.sem) != cap(db.sem) {
+		t.Fatalf("expected %d sems, got: %d", cap(db.sem), len(db.sem))
+	}
+}
+
+func TestPingCoalesced(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	// hold the only connection, so pings wait
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- db.Ping(ctx) }()
+	}
+
+	// let one of the goroutines start the shared ping
+	time.Sleep(time.Millisecond * 10)
+
+	timedoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel()
+
+	// waiting calls return with their own ctx
+	if err := db.Ping(timedoutCtx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	db.mu.Lock()
+	inflight := db.pingCall != nil
+	db.mu.Unlock()
+
+	if !inflight {
+		t.Fatal("expected an in-flight ping")
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+}
+
+func TestNotInitialized(t *testing.T) {
+	ctx := context.Background()
+
+	for _, db := range []*DB{nil, &DB{}} {
+		if err := db.Ping(ctx); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		if _, err := db.Exec(ctx, "SELECT 1"); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		if _, err := db.Query(ctx, "SELECT 1"); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		var i int
+		if err := db.QueryRow(ctx, "SELECT 1").Scan(ctx, &i); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		if _, err := db.Begin(ctx); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		if _, err := db.Prepare(ctx, "SELECT 1"); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		if _, err := db.Named(ctx, "users/get"); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		if _, err := db.NamedExec(ctx, "users/get"); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		if err := db.NamedRow(ctx, "users/get").Scan(ctx, &i); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		if err := db.Close(ctx); err != ErrNotInitialized {
+			t.Fatalf("expected ErrNotInitialized, got: %v", err)
+		}
+
+		// setters are no-op
+		db.SetMaxOpenConns(1)
+		db.SetPolicy(nil)
+	}
+
+	var tx *Tx
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got: %v", err)
+	}
+
+	if err := (&Tx{}).Commit(ctx); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got: %v", err)
+	}
+
+	var s *Stmt
+	if _, err := s.Exec(ctx); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got: %v", err)
+	}
+
+	if err := (&Stmt{}).Close(ctx); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got: %v", err)
+	}
+
+	var rs *Rows
+	if rs.Next(ctx) {
+		t.Fatal("expected Next to be false")
+	}
+
+	if err := rs.Close(ctx); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got: %v", err)
+	}
+
+	var r *Row
+	if err := r.Scan(ctx); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got: %v", err)
+	}
+
+	var res *Result
+	if _, err := res.RowsAffected(ctx); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got: %v", err)
+	}
+}
+
 var (
 	insertSQLStatement = `INSERT INTO nullable
 VALUES ( NULL, 'NULLABLE', NULL, $1, $2, $3, NULL, true, NULL, NOW() )`
@@ -0,0 +1,184 @@
+// Package ctxdbhttp wires ctxdb into HTTP servers; a middleware creating the
+// request scoped contexts of the operations, and the health and stats
+// handlers of a ctxdb.DB. Usage:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/users", users)
+//	...
+//	http.ListenAndServe(addr, ctxdbhttp.Wrap(db, mux, ctxdbhttp.Options{
+//	    Timeout: 5 * time.Second,
+//	}))
+package ctxdbhttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/cihangir/ctxdb"
+)
+
+const (
+	// DefaultRequestIDHeader is the header of the request ids, if none is set
+	// in the Options.
+	DefaultRequestIDHeader = "X-Request-Id"
+
+	// DefaultHealthPath and DefaultStatsPath are the paths of the handlers
+	// served by Wrap, if none is set in the Options.
+	DefaultHealthPath = "/healthz"
+	DefaultStatsPath  = "/debug/ctxdb/stats"
+)
+
+// requestIDKey is the key of the request ids in contexts
+type requestIDKey struct{}
+
+// Options configures the Middleware and Wrap.
+type Options struct {
+	// Timeout is the deadline of the request ctx, zero keeps the deadline of
+	// the request as it is.
+	Timeout time.Duration
+
+	// RequestIDHeader is the header of the request id, defaults to
+	// DefaultRequestIDHeader. Requests without one are given a random id.
+	RequestIDHeader string
+
+	// Label returns the label of the operations of the request, which selects
+	// their policies, see ctxdb.WithLabel. Operations are not labeled if it is
+	// nil or returns an empty string.
+	Label func(r *http.Request) string
+
+	// HealthPath and StatsPath are the paths of the handlers served by Wrap,
+	// default to DefaultHealthPath and DefaultStatsPath.
+	HealthPath string
+	StatsPath  string
+}
+
+// Middleware returns a middleware which serves the requests with a request
+// scoped ctx; it has the deadline of the Timeout, the request id and the path
+// as ctxdb tags, the label of the request, and a ctxdb.Scope which closes the
+// rows, statements and transactions left open by the handler when it
+// returns. Request id is sent back in the response header.
+func Middleware(opts Options) func(next http.Handler) http.Handler {
+	header := opts.RequestIDHeader
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			if opts.Timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+				defer cancel()
+			}
+
+			id := r.Header.Get(header)
+			if id == "" {
+				id = newRequestID()
+			}
+			w.Header().Set(header, id)
+
+			ctx = context.WithValue(ctx, requestIDKey{}, id)
+			ctx = ctxdb.WithTag(ctx, "request_id", id)
+			ctx = ctxdb.WithTag(ctx, "route", r.URL.Path)
+
+			if opts.Label != nil {
+				if label := opts.Label(r); label != "" {
+					ctx = ctxdb.WithLabel(ctx, label)
+				}
+			}
+
+			ctx, scope := ctxdb.NewScope(ctx)
+			defer scope.End()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestID returns the request id set by the Middleware, or empty string if
+// none is set.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// HealthHandler returns a handler responding 200 if the db is healthy, 503
+// otherwise, see ctxdb.DB.Healthy.
+func HealthHandler(db *ctxdb.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !db.Healthy(r.Context()) {
+			http.Error(w, "unhealthy", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("ok\n"))
+	})
+}
+
+// Stats is the response of the StatsHandler.
+type Stats struct {
+	Pool          ctxdb.PoolStats        `json:"pool"`
+	History       []ctxdb.StatsSample    `json:"history,omitempty"`
+	LatencySplits []ctxdb.LatencySplit   `json:"latency_splits,omitempty"`
+	Dependencies  *ctxdb.DependencyGraph `json:"dependencies,omitempty"`
+}
+
+// StatsHandler returns a handler responding the statistics of the db as JSON;
+// the pool statistics, and the stats history, the latency splits and the
+// dependency graph if they are enabled.
+func StatsHandler(db *ctxdb.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pool, err := db.Stats(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		s := &Stats{
+			Pool:          pool,
+			History:       db.StatsHistory(),
+			LatencySplits: db.LatencySplits(),
+			Dependencies:  db.DependencyGraph(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+	})
+}
+
+// Wrap returns a handler serving the health and stats handlers of the db at
+// the paths of the opts, and the other requests with next through the
+// Middleware.
+func Wrap(db *ctxdb.DB, next http.Handler, opts Options) http.Handler {
+	healthPath := opts.HealthPath
+	if healthPath == "" {
+		healthPath = DefaultHealthPath
+	}
+
+	statsPath := opts.StatsPath
+	if statsPath == "" {
+		statsPath = DefaultStatsPath
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(healthPath, HealthHandler(db))
+	mux.Handle(statsPath, StatsHandler(db))
+	mux.Handle("/", Middleware(opts)(next))
+	return mux
+}
+
+// newRequestID returns a random request id
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,95 @@
+package ctxdbhttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cihangir/ctxdb"
+	_ "github.com/lib/pq"
+)
+
+func TestMiddleware(t *testing.T) {
+	var ctx context.Context
+	h := Middleware(Options{
+		Timeout: time.Second,
+		Label:   func(r *http.Request) string { return "api" },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	}))
+
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.Header.Set(DefaultRequestIDHeader, "42")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if _, ok := ctx.Deadline(); !ok {
+		t.Fatalf("expected the request ctx to have a deadline")
+	}
+
+	if id := RequestID(ctx); id != "42" {
+		t.Fatalf("expected request id 42, got: %q", id)
+	}
+
+	if tags := ctxdb.TagsFromContext(ctx); tags["request_id"] != "42" || tags["route"] != "/users" {
+		t.Fatalf("expected request tags, got: %v", tags)
+	}
+
+	if label := ctxdb.LabelFromContext(ctx); label != "api" {
+		t.Fatalf("expected api label, got: %q", label)
+	}
+
+	if ctxdb.ScopeFromContext(ctx) == nil {
+		t.Fatalf("expected the request ctx to have a scope")
+	}
+
+	// request id is generated if the request has none
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/users", nil))
+
+	if id := w.Header().Get(DefaultRequestIDHeader); id == "" || id != RequestID(ctx) {
+		t.Fatalf("expected the generated request id in the response, got: %q", id)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	db, err := ctxdb.Open(os.Getenv("NISQL_TEST_DIALECT"), os.Getenv("NISQL_TEST_DSN"))
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := db.Exec(r.Context(), "SELECT 1"); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	h := Wrap(db, next, Options{Timeout: time.Second})
+
+	for _, path := range []string{DefaultHealthPath, DefaultStatsPath, "/users"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got: %d %s", path, w.Code, w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", DefaultStatsPath, nil))
+
+	var s Stats
+	if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if s.Pool.MaxOpenConns == 0 || s.Pool.Checkouts == 0 {
+		t.Fatalf("expected pool stats, got: %+v", s.Pool)
+	}
+}
@@ -0,0 +1,280 @@
+// Package ctxdbtest provides a conformance test suite for ctxdb, it exercises
+// timeouts, cancellation, pool exhaustion, transactions and scans against a
+// real database, so drivers and dialects can be verified with ctxdb.
+package ctxdbtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cihangir/ctxdb"
+)
+
+// Config parameterizes the conformance suite for a driver.
+type Config struct {
+	// Driver and DSN are passed to ctxdb.Open.
+	Driver string
+	DSN    string
+
+	// SleepQuery is a query sleeping at least one second, e.g.
+	// "SELECT pg_sleep(1)" for postgres, "SELECT SLEEP(1)" for mysql. Tests
+	// requiring a long running query are skipped if it is empty.
+	SleepQuery string
+
+	// Placeholder returns the nth placeholder of the driver, starting from 1,
+	// e.g. "$1" for postgres. Defaults to "?".
+	Placeholder func(n int) string
+}
+
+// Dollar is the Placeholder of the drivers using $1..$n placeholders.
+func Dollar(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (c Config) placeholder(n int) string {
+	if c.Placeholder == nil {
+		return "?"
+	}
+
+	return c.Placeholder(n)
+}
+
+// RunConformance runs the conformance suite against the database of the given
+// config. It creates and cleans the table ctxdbtest_conformance.
+func RunConformance(t *testing.T, c Config) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, c Config, db *ctxdb.DB)
+	}{
+		{"Ping", testPing},
+		{"Scan", testScan},
+		{"Timeout", testTimeout},
+		{"Cancel", testCancel},
+		{"PoolExhaustion", testPoolExhaustion},
+		{"TxCommit", testTxCommit},
+		{"TxRollback", testTxRollback},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			db, err := ctxdb.Open(c.Driver, c.DSN)
+			if err != nil {
+				t.Fatalf("open error: %s", err)
+			}
+			defer db.Close(context.Background())
+
+			ensureTable(t, db)
+			test.f(t, c, db)
+		})
+	}
+}
+
+func ensureTable(t *testing.T, db *ctxdb.DB) {
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE IF NOT EXISTS ctxdbtest_conformance (id INTEGER)"); err != nil {
+		t.Fatalf("err while creating the table: %s", err)
+	}
+
+	if _, err := db.Exec(ctx, "DELETE FROM ctxdbtest_conformance"); err != nil {
+		t.Fatalf("err while cleaning the table: %s", err)
+	}
+}
+
+func insert(t *testing.T, c Config, db *ctxdb.DB, ids ...int) {
+	ctx := context.Background()
+	query := "INSERT INTO ctxdbtest_conformance (id) VALUES (" + c.placeholder(1) + ")"
+
+	for _, id := range ids {
+		if _, err := db.Exec(ctx, query, id); err != nil {
+			t.Fatalf("err while inserting: %s", err)
+		}
+	}
+}
+
+func count(t *testing.T, db *ctxdb.DB) int64 {
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT id FROM ctxdbtest_conformance")
+	if err != nil {
+		t.Fatalf("err while querying: %s", err)
+	}
+
+	n, err := rows.Count(ctx)
+	if err != nil {
+		t.Fatalf("err while counting: %s", err)
+	}
+
+	return n
+}
+
+func testPing(t *testing.T, c Config, db *ctxdb.DB) {
+	if err := db.Ping(context.Background()); err != nil {
+		t.Fatalf("err while pinging: %s", err)
+	}
+}
+
+func testScan(t *testing.T, c Config, db *ctxdb.DB) {
+	insert(t, c, db, 1, 2, 3)
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT id FROM ctxdbtest_conformance ORDER BY id")
+	if err != nil {
+		t.Fatalf("err while querying: %s", err)
+	}
+
+	var expected int64 = 1
+	for rows.Next(ctx) {
+		var id int64
+		if err := rows.Scan(ctx, &id); err != nil {
+			t.Fatalf("err while scanning: %s", err)
+		}
+
+		if id != expected {
+			t.Fatalf("expected %d, got: %d", expected, id)
+		}
+
+		expected++
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if expected != 4 {
+		t.Fatalf("expected 3 rows, got: %d", expected-1)
+	}
+
+	var id int64
+	query := "SELECT id FROM ctxdbtest_conformance WHERE id = " + c.placeholder(1)
+	if err := db.QueryRow(ctx, query, 2).Scan(ctx, &id); err != nil {
+		t.Fatalf("err while scanning: %s", err)
+	}
+
+	if id != 2 {
+		t.Fatalf("expected 2, got: %d", id)
+	}
+}
+
+func testTimeout(t *testing.T, c Config, db *ctxdb.DB) {
+	if c.SleepQuery == "" {
+		t.Skip("no sleep query")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	if _, err := db.Exec(ctx, c.SleepQuery); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %s", err)
+	}
+
+	// pool recovers from the closed connection
+	testPing(t, c, db)
+}
+
+func testCancel(t *testing.T, c Config, db *ctxdb.DB) {
+	if c.SleepQuery == "" {
+		t.Skip("no sleep query")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*50, cancel)
+
+	if _, err := db.Exec(ctx, c.SleepQuery); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got: %s", err)
+	}
+
+	testPing(t, c, db)
+}
+
+func testPoolExhaustion(t *testing.T, c Config, db *ctxdb.DB) {
+	insert(t, c, db, 1)
+	ctx := context.Background()
+
+	// hold connections with open rows until the pool is exhausted
+	var held []*ctxdb.Rows
+	defer func() {
+		for _, rows := range held {
+			rows.Close(ctx)
+		}
+	}()
+
+	for i := 0; ; i++ {
+		if i == 64 {
+			t.Fatalf("pool is not exhausted after %d connections", i)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+		rows, err := db.Query(timeoutCtx, "SELECT id FROM ctxdbtest_conformance")
+		cancel()
+
+		if err == context.DeadlineExceeded {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("err while querying: %s", err)
+		}
+
+		held = append(held, rows)
+	}
+
+	// a released connection can be acquired again
+	if err := held[0].Close(ctx); err != nil {
+		t.Fatalf("err while closing rows: %s", err)
+	}
+	held = held[1:]
+
+	testPing(t, c, db)
+}
+
+func testTxCommit(t *testing.T, c Config, db *ctxdb.DB) {
+	ctx := context.Background()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("err while beginning the transaction: %s", err)
+	}
+
+	query := "INSERT INTO ctxdbtest_conformance (id) VALUES (" + c.placeholder(1) + ")"
+	if _, err := tx.Exec(ctx, query, 1); err != nil {
+		t.Fatalf("err while inserting: %s", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("err while committing: %s", err)
+	}
+
+	if n := count(t, db); n != 1 {
+		t.Fatalf("expected 1 row, got: %d", n)
+	}
+}
+
+func testTxRollback(t *testing.T, c Config, db *ctxdb.DB) {
+	ctx := context.Background()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("err while beginning the transaction: %s", err)
+	}
+
+	query := "INSERT INTO ctxdbtest_conformance (id) VALUES (" + c.placeholder(1) + ")"
+	if _, err := tx.Exec(ctx, query, 1); err != nil {
+		t.Fatalf("err while inserting: %s", err)
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("err while rolling back: %s", err)
+	}
+
+	if n := count(t, db); n != 0 {
+		t.Fatalf("expected 0 rows, got: %d", n)
+	}
+}
@@ -0,0 +1,77 @@
+package ctxdbtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/cihangir/ctxdb"
+)
+
+// sentinelKey is the key of the sentinel value of a Propagation in contexts
+type sentinelKey struct{}
+
+// Propagation verifies that every ctxdb operation of a code path receives a
+// ctx derived from the request ctx, catching the accidental usages of
+// context.Background() or context.TODO(). Usage:
+//
+//     p := ctxdbtest.NewPropagation()
+//     db, err := ctxdb.Open(driver, dsn, p.Option())
+//     ...
+//     handler(p.Context(ctx), db)
+//     p.Verify(t)
+type Propagation struct {
+	mu      sync.Mutex
+	ops     int
+	missing []string
+}
+
+// NewPropagation creates a propagation check.
+func NewPropagation() *Propagation {
+	return &Propagation{}
+}
+
+// Option returns the option which observes the operations of a DB for the
+// check.
+func (p *Propagation) Option() ctxdb.Option {
+	return ctxdb.WithContextObserver(p.observe)
+}
+
+// Context returns a copy of the parent carrying the sentinel of the check, it
+// should be the request ctx of the verified code path.
+func (p *Propagation) Context(parent context.Context) context.Context {
+	return context.WithValue(parent, sentinelKey{}, p)
+}
+
+func (p *Propagation) observe(ctx context.Context, op string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ops++
+	if ctx.Value(sentinelKey{}) != p {
+		p.missing = append(p.missing, op)
+	}
+}
+
+// Verify fails the test if any operation received a ctx without the sentinel,
+// or if no operation is observed at all.
+func (p *Propagation) Verify(t testing.TB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ops == 0 {
+		t.Errorf("no ctxdb operation is observed")
+	}
+
+	for _, op := range p.missing {
+		t.Errorf("%s is called with a ctx not derived from the request ctx", op)
+	}
+}
+
+// Reset forgets the observed operations, for reusing the check.
+func (p *Propagation) Reset() {
+	p.mu.Lock()
+	p.ops = 0
+	p.missing = nil
+	p.mu.Unlock()
+}
@@ -0,0 +1,49 @@
+package ctxdbtest
+
+import (
+	"context"
+	"testing"
+)
+
+type recorder struct {
+	testing.TB
+	errors int
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors++
+}
+
+func TestPropagation(t *testing.T) {
+	p := NewPropagation()
+	ctx := p.Context(context.Background())
+
+	// derived contexts carry the sentinel
+	derived, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p.observe(derived, "Exec")
+
+	r := &recorder{TB: t}
+	p.Verify(r)
+	if r.errors != 0 {
+		t.Fatalf("expected no errors, got: %d", r.errors)
+	}
+
+	p.observe(context.Background(), "Query")
+	p.observe(context.TODO(), "Tx.Commit")
+
+	r = &recorder{TB: t}
+	p.Verify(r)
+	if r.errors != 2 {
+		t.Fatalf("expected 2 errors, got: %d", r.errors)
+	}
+
+	p.Reset()
+
+	r = &recorder{TB: t}
+	p.Verify(r)
+	if r.errors != 1 {
+		t.Fatalf("expected 1 error for no operations, got: %d", r.errors)
+	}
+}
@@ -0,0 +1,47 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDDLNotAllowed is returned by Exec for the schema changes, if the DDL gate
+// is enabled and the ctx is not marked with WithDDL.
+var ErrDDLNotAllowed = errors.New("schema changes are not allowed")
+
+// WithDDLGate enables the DDL gate, CREATE, ALTER, DROP, TRUNCATE, COMMENT and
+// RENAME statements executed by Exec of the DB and its transactions are
+// rejected with ErrDDLNotAllowed, unless their ctx is marked with WithDDL, so
+// schema changes only run from the code paths meant for them, e.g. migrations.
+func WithDDLGate() Option {
+	return func(db *DB) {
+		db.ddlGate = true
+	}
+}
+
+// WithDDL returns a copy of the parent context which allows the schema changes
+// of the operations using it, when the DDL gate is enabled.
+func WithDDL(parent context.Context) context.Context {
+	return context.WithValue(parent, ddlKey, true)
+}
+
+// IsDDLAllowed reports whether the ctx is marked with WithDDL.
+func IsDDLAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(ddlKey).(bool)
+	return allowed
+}
+
+// guardDDL rejects the schema changes if the gate is enabled and ctx does not
+// allow them
+func (db *DB) guardDDL(ctx context.Context, query string) error {
+	if !db.ddlGate || IsDDLAllowed(ctx) {
+		return nil
+	}
+
+	switch verb, _ := parseStatement(query); verb {
+	case "CREATE", "ALTER", "DROP", "TRUNCATE", "COMMENT", "RENAME":
+		return ErrDDLNotAllowed
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestWithDDLGate(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithDDLGate(),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE IF NOT EXISTS ddl_gated (id int)"); err != ErrDDLNotAllowed {
+		t.Fatalf("expected ErrDDLNotAllowed, got: %v", err)
+	}
+
+	if _, err := db.Exec(WithDDL(ctx), "CREATE TABLE IF NOT EXISTS ddl_gated (id int)"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "-- cleanup\nDROP TABLE ddl_gated"); err != ErrDDLNotAllowed {
+		t.Fatalf("expected ErrDDLNotAllowed in the transaction, got: %v", err)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
@@ -0,0 +1,58 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoDeadline is returned by the operations invoked with a ctx without a
+// deadline, if the deadline guard is enabled with rejection.
+var ErrNoDeadline = errors.New("ctx has no deadline")
+
+// WithDeadlineGuard enables the deadline guard for Exec, Query and QueryRow,
+// operations invoked with a ctx without a deadline, e.g. context.Background(),
+// are rejected with ErrNoDeadline, or if warn is set they are passed to warn
+// and run as usual. Operations having one of the allowed labels, set with
+// WithLabel, are not guarded. The deadline of the given ctx is checked, before
+// the policy and the default timeout are applied.
+func WithDeadlineGuard(warn func(query string), allowed ...string) Option {
+	return func(db *DB) {
+		db.deadlineGuard = &deadlineGuard{
+			warn:    warn,
+			allowed: make(map[string]struct{}, len(allowed)),
+		}
+
+		for _, label := range allowed {
+			db.deadlineGuard.allowed[label] = struct{}{}
+		}
+	}
+}
+
+// deadlineGuard holds the config of the deadline guard
+type deadlineGuard struct {
+	warn    func(query string)
+	allowed map[string]struct{}
+}
+
+// guardDeadline checks the deadline of ctx if the guard is enabled
+func (db *DB) guardDeadline(ctx context.Context, query string) error {
+	g := db.deadlineGuard
+	if g == nil {
+		return nil
+	}
+
+	if _, ok := ctx.Deadline(); ok {
+		return nil
+	}
+
+	if _, ok := g.allowed[LabelFromContext(ctx)]; ok {
+		return nil
+	}
+
+	if g.warn != nil {
+		g.warn(query)
+		return nil
+	}
+
+	return ErrNoDeadline
+}
@@ -0,0 +1,63 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithDeadlineGuard(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithDeadlineGuard(nil, "migrations"),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "SELECT 1"); err != ErrNoDeadline {
+		t.Fatalf("expected ErrNoDeadline, got: %v", err)
+	}
+
+	var i int
+	if err := db.QueryRow(ctx, "SELECT 1").Scan(ctx, &i); err != ErrNoDeadline {
+		t.Fatalf("expected ErrNoDeadline, got: %v", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	if _, err := db.Exec(timeoutCtx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := db.Exec(WithLabel(ctx, "migrations"), "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
+
+func TestWithDeadlineGuardWarning(t *testing.T) {
+	var warned []string
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithDeadlineGuard(func(query string) { warned = append(warned, query) }),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(warned) != 1 || warned[0] != "SELECT 1" {
+		t.Fatalf("expected a warning for the query, got: %v", warned)
+	}
+}
@@ -0,0 +1,50 @@
+package ctxdb
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// QueryOptional runs query for data which is optional for the caller, e.g. a
+// cached or partial result can be served instead. When the pool is saturated,
+// no connection is free at the moment, or the circuit breaker is open, query is
+// skipped and the result of fallback is returned instead, so the caller does
+// not wait for a connection, and the degradation is counted. query runs with
+// the given ctx otherwise, its result is returned as is.
+func (db *DB) QueryOptional(
+	ctx context.Context,
+	query func(ctx context.Context) (interface{}, error),
+	fallback func() (interface{}, error),
+) (interface{}, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	if db.isSaturated() || db.guardBreaker() != nil {
+		return db.degrade(fallback)
+	}
+
+	res, err := query(ctx)
+	if err == ErrCircuitOpen {
+		// breaker is opened in the meantime
+		return db.degrade(fallback)
+	}
+
+	return res, err
+}
+
+// Degradations returns the number of the optional queries served by their
+// fallbacks since the DB is opened.
+func (db *DB) Degradations() uint64 {
+	if db == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&db.degradations)
+}
+
+// degrade counts a degradation and calls the fallback
+func (db *DB) degrade(fallback func() (interface{}, error)) (interface{}, error) {
+	atomic.AddUint64(&db.degradations, 1)
+	return fallback()
+}
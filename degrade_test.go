@@ -0,0 +1,79 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestQueryOptional(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	query := func(ctx context.Context) (interface{}, error) {
+		var i int64
+		err := db.QueryRow(ctx, "SELECT 1").Scan(ctx, &i)
+		return i, err
+	}
+
+	fallback := func() (interface{}, error) {
+		return int64(0), nil
+	}
+
+	res, err := db.QueryOptional(ctx, query, fallback)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if res != int64(1) || db.Degradations() != 0 {
+		t.Fatalf("expected 1 without degradations, got: %v, %d", res, db.Degradations())
+	}
+
+	// saturate the pool
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer rows.Close(ctx)
+
+	res, err = db.QueryOptional(ctx, query, fallback)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if res != int64(0) || db.Degradations() != 1 {
+		t.Fatalf("expected the fallback with 1 degradation, got: %v, %d", res, db.Degradations())
+	}
+}
+
+func TestQueryOptionalCircuitOpen(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+	WithCircuitBreaker(1, time.Minute)(db)
+
+	db.recordBreaker(context.DeadlineExceeded)
+
+	res, err := db.QueryOptional(context.Background(), func(ctx context.Context) (interface{}, error) {
+		t.Fatal("expected query to be skipped")
+		return nil, nil
+	}, func() (interface{}, error) {
+		return "stale", nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if res != "stale" {
+		t.Fatalf("expected stale, got: %v", res)
+	}
+}
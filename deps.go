@@ -0,0 +1,182 @@
+package ctxdb
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// TableWrite is the event of a write on a table, passed to the func set with
+// WithDependencyTracking.
+type TableWrite struct {
+	Table        string
+	Verb         string // INSERT, UPDATE or DELETE
+	Label        string // label of the statement, see WithLabel
+	RowsAffected int64  // -1 if it is unknown
+
+	// Readers are the named queries declared to read the table, whose
+	// cached results should be invalidated, see Queries.Reads.
+	Readers []string
+}
+
+// DependencyGraph holds the labels of the statements reading and writing the
+// tables, by the tables.
+type DependencyGraph struct {
+	Reads  map[string][]string
+	Writes map[string][]string
+}
+
+// Reads declares the tables read by the named query, so the writes on the
+// tables are reported with the query as a reader.
+func (q *Queries) Reads(name string, tables ...string) {
+	q.mu.Lock()
+	if q.reads == nil {
+		q.reads = make(map[string][]string)
+	}
+
+	for _, table := range tables {
+		q.reads[table] = appendUnique(q.reads[table], name)
+	}
+	q.mu.Unlock()
+}
+
+// Readers returns the sorted names of the queries declared to read the table.
+func (q *Queries) Readers(table string) []string {
+	q.mu.RLock()
+	readers := append([]string(nil), q.reads[table]...)
+	q.mu.RUnlock()
+
+	sort.Strings(readers)
+	return readers
+}
+
+// WithDependencyTracking detects the tables of the statements run by Exec,
+// Query and QueryRow of the DB and its transactions, building a live graph of
+// the labels reading and writing every table, see DependencyGraph. f, if set,
+// is called after every successful INSERT, UPDATE or DELETE with the readers
+// of its table declared with Queries.Reads, e.g. for invalidating their
+// caches. Detection is naive, only the target table of a write and the first
+// table of a SELECT are detected. Statements without labels are not added to
+// the graph.
+func WithDependencyTracking(f func(w *TableWrite)) Option {
+	return func(db *DB) {
+		db.deps = &depGraph{
+			report: f,
+			reads:  make(map[string][]string),
+			writes: make(map[string][]string),
+		}
+	}
+}
+
+// depGraph is the live dependency graph of the tables
+type depGraph struct {
+	report func(w *TableWrite)
+
+	mu     sync.Mutex
+	reads  map[string][]string
+	writes map[string][]string
+}
+
+// DependencyGraph returns a copy of the live dependency graph, merged with the
+// reads declared in the query registry. It is nil if the tracking is not
+// enabled.
+func (db *DB) DependencyGraph() *DependencyGraph {
+	if db == nil || db.deps == nil {
+		return nil
+	}
+
+	g := &DependencyGraph{
+		Reads:  make(map[string][]string),
+		Writes: make(map[string][]string),
+	}
+
+	d := db.deps
+	d.mu.Lock()
+	for table, labels := range d.reads {
+		g.Reads[table] = append([]string(nil), labels...)
+	}
+
+	for table, labels := range d.writes {
+		g.Writes[table] = append([]string(nil), labels...)
+	}
+	d.mu.Unlock()
+
+	if q := db.queries; q != nil {
+		q.mu.RLock()
+		for table, names := range q.reads {
+			for _, name := range names {
+				g.Reads[table] = appendUnique(g.Reads[table], name)
+			}
+		}
+		q.mu.RUnlock()
+	}
+
+	for _, labels := range g.Reads {
+		sort.Strings(labels)
+	}
+
+	for _, labels := range g.Writes {
+		sort.Strings(labels)
+	}
+
+	return g
+}
+
+// trackDeps records the table of a successful statement in the dependency graph
+// and reports the writes, if the tracking is enabled
+func (db *DB) trackDeps(ctx context.Context, query string, rowsAffected int64, err error) {
+	d := db.deps
+	if d == nil || err != nil {
+		return
+	}
+
+	verb, table := parseStatement(query)
+	if table == "" {
+		return
+	}
+
+	label := LabelFromContext(ctx)
+
+	switch verb {
+	case "SELECT":
+		if label != "" {
+			d.mu.Lock()
+			d.reads[table] = appendUnique(d.reads[table], label)
+			d.mu.Unlock()
+		}
+	case "INSERT", "UPDATE", "DELETE":
+		if label != "" {
+			d.mu.Lock()
+			d.writes[table] = appendUnique(d.writes[table], label)
+			d.mu.Unlock()
+		}
+
+		if d.report == nil {
+			return
+		}
+
+		var readers []string
+		if db.queries != nil {
+			readers = db.queries.Readers(table)
+		}
+
+		d.report(&TableWrite{
+			Table:        table,
+			Verb:         verb,
+			Label:        label,
+			RowsAffected: rowsAffected,
+			Readers:      readers,
+		})
+	}
+}
+
+// appendUnique appends s to the list unless it is already in it
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+
+	return append(list, s)
+}
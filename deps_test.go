@@ -0,0 +1,66 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestWithDependencyTracking(t *testing.T) {
+	queries := NewQueries()
+	queries.Add("users/get", "SELECT name FROM deps_users WHERE id = $1")
+	queries.Reads("users/get", "deps_users")
+	queries.Reads("users/list", "deps_users")
+
+	var writes []*TableWrite
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithQueries(queries),
+		WithDependencyTracking(func(w *TableWrite) {
+			writes = append(writes, w)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TABLE IF NOT EXISTS deps_users (id int, name text)"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	labeled := WithLabel(ctx, "users/create")
+	if _, err := db.Exec(labeled, "INSERT INTO deps_users (id, name) VALUES ($1, $2)", 1, "name"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var name string
+	if err := db.NamedRow(ctx, "users/get", 1).Scan(ctx, &name); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(writes) != 1 {
+		t.Fatalf("expected 1 write, got: %d", len(writes))
+	}
+
+	w := writes[0]
+	if w.Table != "deps_users" || w.Verb != "INSERT" || w.Label != "users/create" || w.RowsAffected != 1 {
+		t.Fatalf("expected the insert of users/create, got: %+v", w)
+	}
+
+	if !reflect.DeepEqual(w.Readers, []string{"users/get", "users/list"}) {
+		t.Fatalf("expected the declared readers, got: %v", w.Readers)
+	}
+
+	g := db.DependencyGraph()
+	if !reflect.DeepEqual(g.Reads["deps_users"], []string{"users/get", "users/list"}) {
+		t.Fatalf("expected the readers of deps_users, got: %v", g.Reads)
+	}
+
+	if !reflect.DeepEqual(g.Writes["deps_users"], []string{"users/create"}) {
+		t.Fatalf("expected the writers of deps_users, got: %v", g.Writes)
+	}
+}
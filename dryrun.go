@@ -0,0 +1,83 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrDryRunDisabled is returned by the operations using a ctx marked with
+	// WithDryRun, if the dry runs are disabled, e.g. by ProfileProd.
+	ErrDryRunDisabled = errors.New("dry runs are disabled")
+
+	// ErrDryRunUnsupported is returned by the operations which can not be
+	// simulated, for the ctx marked with WithDryRun.
+	ErrDryRunUnsupported = errors.New("dry runs are supported by DB.Exec only")
+)
+
+// WithDryRun returns a copy of the parent context which marks the Exec calls
+// using it as dry runs; the statement is executed in a transaction which is
+// rolled back, so its result, e.g. the number of the affected rows, can be
+// checked without applying it. Statements which are not transactional, e.g.
+// sequence increments, still take effect. Only Exec of DB can be simulated,
+// the other operations using the ctx, e.g. Query, which may run a DELETE ...
+// RETURNING, and the operations of the transactions, fail with
+// ErrDryRunUnsupported, so a dry run never writes by accident.
+func WithDryRun(parent context.Context) context.Context {
+	return context.WithValue(parent, dryRunKey, true)
+}
+
+// IsDryRun reports whether the ctx is marked with WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey).(bool)
+	return dryRun
+}
+
+// WithoutDryRun disables the dry runs, operations using a ctx marked with
+// WithDryRun fail with ErrDryRunDisabled.
+func WithoutDryRun() Option {
+	return func(db *DB) {
+		db.dryRunDisabled = true
+	}
+}
+
+// execDryRun executes the statement in a transaction which is rolled back
+func (db *DB) execDryRun(ctx context.Context, query string, args []interface{}) (*Result, error) {
+	if db.dryRunDisabled {
+		return nil, ErrDryRunDisabled
+	}
+
+	// statement of the dry run is executed for real, then rolled back
+	ctx = context.WithValue(ctx, dryRunKey, false)
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// guardDryRun rejects the ctx marked with WithDryRun, for the operations which
+// can not simulate it
+func (db *DB) guardDryRun(ctx context.Context) error {
+	if !IsDryRun(ctx) {
+		return nil
+	}
+
+	if db.dryRunDisabled {
+		return ErrDryRunDisabled
+	}
+
+	return ErrDryRunUnsupported
+}
@@ -0,0 +1,85 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestWithDryRun(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS dry_runs (id int)",
+		"TRUNCATE dry_runs",
+		"INSERT INTO dry_runs SELECT generate_series(1, 3)",
+	} {
+		if _, err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	res, err := db.Exec(WithDryRun(ctx), "DELETE FROM dry_runs")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if n, err := res.RowsAffected(ctx); err != nil || n != 3 {
+		t.Fatalf("expected 3 affected rows, got: %d, %v", n, err)
+	}
+
+	var count int
+	if err := db.QueryRow(ctx, "SELECT count(*) FROM dry_runs").Scan(ctx, &count); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 3 {
+		t.Fatalf("expected the dry run not to be applied, got: %d rows", count)
+	}
+
+	// operations which can not be simulated are rejected
+	dryRun := WithDryRun(ctx)
+	if _, err := db.Query(dryRun, "DELETE FROM dry_runs RETURNING id"); err != ErrDryRunUnsupported {
+		t.Fatalf("expected ErrDryRunUnsupported, got: %v", err)
+	}
+
+	if err := db.QueryRow(dryRun, "DELETE FROM dry_runs RETURNING id").Scan(ctx, &count); err != ErrDryRunUnsupported {
+		t.Fatalf("expected ErrDryRunUnsupported, got: %v", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(dryRun, "DELETE FROM dry_runs"); err != ErrDryRunUnsupported {
+		t.Fatalf("expected ErrDryRunUnsupported, got: %v", err)
+	}
+
+	if _, err := tx.ExecBatch(dryRun, []Statement{{Query: "DELETE FROM dry_runs"}}); err != ErrDryRunUnsupported {
+		t.Fatalf("expected ErrDryRunUnsupported, got: %v", err)
+	}
+}
+
+func TestWithoutDryRun(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithoutDryRun(),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	if _, err := db.Exec(WithDryRun(context.Background()), "SELECT 1"); err != ErrDryRunDisabled {
+		t.Fatalf("expected ErrDryRunDisabled, got: %v", err)
+	}
+
+	if _, err := db.Query(WithDryRun(context.Background()), "SELECT 1"); err != ErrDryRunDisabled {
+		t.Fatalf("expected ErrDryRunDisabled, got: %v", err)
+	}
+}
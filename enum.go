@@ -0,0 +1,158 @@
+package ctxdb
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// EnumError is returned when a value of a registered enum type is not one of
+// its allowed values, either while passing it as an arg or scanning into it.
+type EnumError struct {
+	Type    string
+	Value   string
+	Allowed []string
+}
+
+func (e *EnumError) Error() string {
+	return fmt.Sprintf(
+		"invalid %s value %q, allowed values are: %s",
+		e.Type, e.Value, strings.Join(e.Allowed, ", "),
+	)
+}
+
+// enum holds the allowed values of a registered type
+type enum struct {
+	allowed []string
+	values  map[string]struct{}
+}
+
+var enums = struct {
+	sync.RWMutex
+	m map[reflect.Type]*enum
+}{m: make(map[reflect.Type]*enum)}
+
+// RegisterEnum registers the typed string type of v as an enum having the given
+// allowed database values. Args of the type are validated before they are sent
+// to the database, and scanned values are validated before they are assigned
+// into the dest of the type.
+//
+//     type Status string
+//
+//     const (
+//         Active   Status = "active"
+//         Inactive Status = "inactive"
+//     )
+//
+//     ctxdb.RegisterEnum(Active, string(Active), string(Inactive))
+func RegisterEnum(v interface{}, values ...string) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.String {
+		panic("ctxdb: RegisterEnum requires a string kinded type")
+	}
+
+	e := &enum{
+		allowed: values,
+		values:  make(map[string]struct{}, len(values)),
+	}
+
+	for _, value := range values {
+		e.values[value] = struct{}{}
+	}
+
+	enums.Lock()
+	enums.m[t] = e
+	enums.Unlock()
+}
+
+func lookupEnum(t reflect.Type) (*enum, bool) {
+	enums.RLock()
+	defer enums.RUnlock()
+
+	if len(enums.m) == 0 {
+		return nil, false
+	}
+
+	e, ok := enums.m[t]
+	return e, ok
+}
+
+func (e *enum) validate(t reflect.Type, value string) error {
+	if _, ok := e.values[value]; ok {
+		return nil
+	}
+
+	return &EnumError{Type: t.String(), Value: value, Allowed: e.allowed}
+}
+
+// prepareArgs validates the args of the registered enum types
+func prepareArgs(args []interface{}) error {
+	for _, arg := range args {
+		t := reflect.TypeOf(arg)
+		if t == nil {
+			continue
+		}
+
+		e, ok := lookupEnum(t)
+		if !ok {
+			continue
+		}
+
+		if err := e.validate(t, reflect.ValueOf(arg).String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepareDest wraps the dest of the registered enum types with validating
+// scanners
+func prepareDest(dest []interface{}) []interface{} {
+	var prepared []interface{}
+	for i, d := range dest {
+		t := reflect.TypeOf(d)
+		if t == nil || t.Kind() != reflect.Ptr {
+			continue
+		}
+
+		e, ok := lookupEnum(t.Elem())
+		if !ok {
+			continue
+		}
+
+		if prepared == nil {
+			prepared = append([]interface{}(nil), dest...)
+		}
+
+		prepared[i] = &enumScanner{enum: e, dest: reflect.ValueOf(d).Elem()}
+	}
+
+	if prepared == nil {
+		return dest
+	}
+
+	return prepared
+}
+
+// enumScanner validates the scanned value before assigning it into dest
+type enumScanner struct {
+	enum *enum
+	dest reflect.Value
+}
+
+func (s *enumScanner) Scan(src interface{}) error {
+	var value sql.NullString
+	if err := value.Scan(src); err != nil {
+		return err
+	}
+
+	if err := s.enum.validate(s.dest.Type(), value.String); err != nil {
+		return err
+	}
+
+	s.dest.SetString(value.String)
+	return nil
+}
@@ -0,0 +1,80 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+type testEnum string
+
+const (
+	testEnumNullable testEnum = "NULLABLE"
+	testEnumOther    testEnum = "OTHER"
+)
+
+func init() {
+	RegisterEnum(testEnumNullable, string(testEnumNullable), string(testEnumOther))
+}
+
+func TestEnumArgs(t *testing.T) {
+	if err := prepareArgs([]interface{}{testEnumNullable, 42, nil}); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	err := prepareArgs([]interface{}{testEnum("invalid")})
+	if _, ok := err.(*EnumError); !ok {
+		t.Fatalf("expected EnumError, got: %# v", err)
+	}
+}
+
+func TestEnumScan(t *testing.T) {
+	var e testEnum
+	dest := prepareDest([]interface{}{&e})
+
+	s, ok := dest[0].(*enumScanner)
+	if !ok {
+		t.Fatalf("expected enumScanner, got: %# v", dest[0])
+	}
+
+	if err := s.Scan([]byte("OTHER")); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if e != testEnumOther {
+		t.Fatalf("expected %s, got: %s", testEnumOther, e)
+	}
+
+	if _, ok := s.Scan("invalid").(*EnumError); !ok {
+		t.Fatalf("expected EnumError")
+	}
+}
+
+func TestEnumQueryRow(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, insertSQLStatement, 42, nil, 12); err != nil {
+		t.Fatalf("err while adding null item: %s", err.Error())
+	}
+
+	var e testEnum
+	err := db.QueryRow(ctx, "SELECT string_val FROM nullable WHERE string_val = $1", testEnumNullable).
+		Scan(ctx, &e)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if e != testEnumNullable {
+		t.Fatalf("expected %s, got: %s", testEnumNullable, e)
+	}
+
+	row := db.QueryRow(ctx, "SELECT string_val FROM nullable WHERE string_val = $1", testEnum("invalid"))
+	if _, ok := row.err.(*EnumError); !ok {
+		t.Fatalf("expected EnumError, got: %# v", row.err)
+	}
+
+	if _, err := db.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+}
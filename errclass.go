@@ -0,0 +1,136 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"strings"
+)
+
+// transientMessages are the fragments of the driver errors caused by broken or
+// unreachable connections
+var transientMessages = []string{
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"bad connection",
+}
+
+// mysql error numbers of the constraint violations, besides the mapped ones
+var mysqlConstraints = map[uint64]struct{}{
+	1048: {}, // ER_BAD_NULL_ERROR
+	3819: {}, // ER_CHECK_CONSTRAINT_VIOLATED
+}
+
+// IsTransient reports whether err is caused by a broken or unreachable
+// connection, e.g. driver.ErrBadConn, a connection reset or refused error, or
+// by a serialization failure, so the operation can be retried. It is the
+// default of RetryPolicy.Retryable. Wrapped errors are unwrapped.
+func IsTransient(err error) bool {
+	return matchError(err, func(err error) bool {
+		return isBrokenConn(err) || kindOf(err) == ErrSerializationFailure
+	})
+}
+
+// IsConstraint reports whether err is caused by a constraint violation, e.g.
+// ErrUniqueViolation, ErrForeignKeyViolation, or a not null or check
+// constraint violation of the driver. Wrapped errors are unwrapped.
+func IsConstraint(err error) bool {
+	return matchError(err, func(err error) bool {
+		switch kindOf(err) {
+		case ErrUniqueViolation, ErrForeignKeyViolation:
+			return true
+		}
+
+		if state := sqlState(err); state != "" {
+			// integrity constraint violation class
+			return strings.HasPrefix(state, "23")
+		}
+
+		number, ok := mysqlNumber(err)
+		if !ok {
+			return false
+		}
+
+		_, ok = mysqlConstraints[number]
+		return ok
+	})
+}
+
+// IsTimeout reports whether err is caused by a timeout; the deadline of the
+// ctx, ErrPoolExhausted of a busy pool, ErrLockTimeout, a statement cancelled
+// on the server, or a network timeout. Wrapped errors are unwrapped.
+func IsTimeout(err error) bool {
+	return matchError(err, func(err error) bool {
+		if err == context.DeadlineExceeded || err == ErrPoolExhausted || kindOf(err) == ErrLockTimeout {
+			return true
+		}
+
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return true
+		}
+
+		if sqlState(err) == "57014" { // query_canceled
+			return true
+		}
+
+		number, ok := mysqlNumber(err)
+		return ok && number == 3024 // ER_QUERY_TIMEOUT
+	})
+}
+
+// isBrokenConn reports whether err is caused by a broken or unreachable
+// connection
+func isBrokenConn(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if err == driver.ErrBadConn {
+		return true
+	}
+
+	msg := err.Error()
+	for _, m := range transientMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// kindOf returns the ctxdb error of err, err itself if it is one of them, or
+// the mapped error of a driver error
+func kindOf(err error) error {
+	switch err {
+	case ErrUniqueViolation, ErrForeignKeyViolation, ErrSerializationFailure, ErrLockTimeout:
+		return err
+	}
+
+	if de, ok := err.(*DriverError); ok {
+		return de.Kind
+	}
+
+	return driverErrorKind(err)
+}
+
+// matchError reports whether err or any error it wraps matches f
+func matchError(err error, f func(err error) bool) bool {
+	for err != nil {
+		if f(err) {
+			return true
+		}
+
+		u, ok := err.(interface {
+			Unwrap() error
+		})
+		if !ok {
+			return false
+		}
+
+		err = u.Unwrap()
+	}
+
+	return false
+}
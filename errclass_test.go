@@ -0,0 +1,54 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		err        error
+		transient  bool
+		constraint bool
+		timeout    bool
+	}{
+		{err: nil},
+		{err: errors.New("other")},
+		{err: ErrSerializationFailure, transient: true},
+		{err: &codeError{"40P01"}, transient: true},
+		{err: &numberError{1213}, transient: true},
+		{err: &TxStepError{Err: &DriverError{Err: &codeError{"40001"}, Kind: ErrSerializationFailure}}, transient: true},
+		{err: &codeError{"23505"}, constraint: true},
+		{err: &codeError{"23502"}, constraint: true},
+		{err: MapDriverError(&numberError{1452}), constraint: true},
+		{err: &numberError{3819}, constraint: true},
+		{err: context.DeadlineExceeded, timeout: true},
+		{err: ErrPoolExhausted, timeout: true},
+		{err: &codeError{"55P03"}, timeout: true},
+		{err: &codeError{"57014"}, timeout: true},
+		{err: &numberError{3024}, timeout: true},
+		{err: timeoutError{}, timeout: true},
+		{err: context.Canceled},
+	}
+
+	for _, test := range tests {
+		if got := IsTransient(test.err); got != test.transient {
+			t.Fatalf("expected IsTransient %t for %v", test.transient, test.err)
+		}
+
+		if got := IsConstraint(test.err); got != test.constraint {
+			t.Fatalf("expected IsConstraint %t for %v", test.constraint, test.err)
+		}
+
+		if got := IsTimeout(test.err); got != test.timeout {
+			t.Fatalf("expected IsTimeout %t for %v", test.timeout, test.err)
+		}
+	}
+}
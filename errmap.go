@@ -0,0 +1,167 @@
+package ctxdb
+
+import (
+	"errors"
+	"reflect"
+)
+
+var (
+	// ErrUniqueViolation is matched by the errors of the statements violating
+	// a unique constraint, see WithDriverErrors.
+	ErrUniqueViolation = errors.New("unique violation")
+
+	// ErrForeignKeyViolation is matched by the errors of the statements
+	// violating a foreign key constraint.
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+
+	// ErrSerializationFailure is matched by the errors of the transactions
+	// aborted due to a concurrent update or a deadlock, they can be retried.
+	ErrSerializationFailure = errors.New("serialization failure")
+
+	// ErrLockTimeout is matched by the errors of the statements which could
+	// not get a lock in time.
+	ErrLockTimeout = errors.New("lock timeout")
+)
+
+// DriverError is a driver error mapped to one of the ctxdb errors, e.g.
+// ErrUniqueViolation. errors.Is reports it as the mapped error, and errors.As
+// finds the driver error, e.g. *pq.Error.
+type DriverError struct {
+	Err  error // error of the driver
+	Kind error // mapped ctxdb error
+}
+
+func (e *DriverError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the error of the driver.
+func (e *DriverError) Unwrap() error { return e.Err }
+
+// Is reports the DriverError as its Kind for errors.Is.
+func (e *DriverError) Is(target error) bool { return target == e.Kind }
+
+// postgres SQLSTATE codes of the mapped errors
+var sqlStateKinds = map[string]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"40001": ErrSerializationFailure,
+	"40P01": ErrSerializationFailure, // deadlock_detected
+	"55P03": ErrLockTimeout,          // lock_not_available
+}
+
+// mysql error numbers of the mapped errors
+var mysqlKinds = map[uint64]error{
+	1062: ErrUniqueViolation, // ER_DUP_ENTRY
+	1586: ErrUniqueViolation, // ER_DUP_ENTRY_WITH_KEY_NAME
+	1216: ErrForeignKeyViolation,
+	1217: ErrForeignKeyViolation,
+	1451: ErrForeignKeyViolation,  // ER_ROW_IS_REFERENCED_2
+	1452: ErrForeignKeyViolation,  // ER_NO_REFERENCED_ROW_2
+	1213: ErrSerializationFailure, // ER_LOCK_DEADLOCK
+	1205: ErrLockTimeout,          // ER_LOCK_WAIT_TIMEOUT
+}
+
+// WithDriverErrors makes Exec, Query, Row.Scan and Tx.Commit return the
+// errors of lib/pq and go-sql-driver/mysql as DriverErrors, so they can be
+// matched with errors.Is against ErrUniqueViolation, ErrForeignKeyViolation,
+// ErrSerializationFailure and ErrLockTimeout. Other errors are returned as
+// they are.
+func WithDriverErrors() Option {
+	return func(db *DB) {
+		db.driverErrors = true
+	}
+}
+
+// MapDriverError maps the given driver error to a DriverError, or returns it
+// as it is if it is not one of the mapped errors. Errors having a SQLState
+// method or a Code field, e.g. *pq.Error, are mapped by their SQLSTATE codes,
+// and the errors having a Number field, e.g. *mysql.MySQLError, by their
+// error numbers, so the drivers are not imported.
+func MapDriverError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(*DriverError); ok {
+		return err
+	}
+
+	if kind := driverErrorKind(err); kind != nil {
+		return &DriverError{Err: err, Kind: kind}
+	}
+
+	return err
+}
+
+// mapDriverError maps err if the db is opened with WithDriverErrors
+func (db *DB) mapDriverError(err error) error {
+	if err == nil || !db.driverErrors {
+		return err
+	}
+
+	return MapDriverError(err)
+}
+
+// driverErrorKind returns the ctxdb error of the driver error, nil if it is
+// not mapped
+func driverErrorKind(err error) error {
+	if state := sqlState(err); state != "" {
+		return sqlStateKinds[state]
+	}
+
+	if number, ok := mysqlNumber(err); ok {
+		return mysqlKinds[number]
+	}
+
+	return nil
+}
+
+// sqlState returns the SQLSTATE code of the driver error, read from its
+// SQLState method or its Code field, empty if it has none
+func sqlState(err error) string {
+	if s, ok := err.(interface {
+		SQLState() string
+	}); ok {
+		return s.SQLState()
+	}
+
+	if code := errorField(err, "Code"); code.IsValid() && code.Kind() == reflect.String {
+		return code.String()
+	}
+
+	return ""
+}
+
+// mysqlNumber returns the mysql error number of the driver error, read from
+// its Number field
+func mysqlNumber(err error) (uint64, bool) {
+	number := errorField(err, "Number")
+	if !number.IsValid() {
+		return 0, false
+	}
+
+	switch number.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return number.Uint(), true
+	default:
+		return 0, false
+	}
+}
+
+// errorField returns the field of the error struct with the given name, the
+// zero Value if err is not a struct or has no such field
+func errorField(err error, name string) reflect.Value {
+	v := reflect.ValueOf(err)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	return v.FieldByName(name)
+}
@@ -0,0 +1,94 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+type sqlStateError struct{ code string }
+
+func (e sqlStateError) Error() string    { return "sqlstate " + e.code }
+func (e sqlStateError) SQLState() string { return e.code }
+
+type codeError struct{ Code string }
+
+func (e *codeError) Error() string { return "code " + e.Code }
+
+type numberError struct{ Number uint16 }
+
+func (e *numberError) Error() string { return "number" }
+
+func TestMapDriverError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected error
+	}{
+		{err: sqlStateError{"23505"}, expected: ErrUniqueViolation},
+		{err: &codeError{"23503"}, expected: ErrForeignKeyViolation},
+		{err: &codeError{"40001"}, expected: ErrSerializationFailure},
+		{err: &codeError{"55P03"}, expected: ErrLockTimeout},
+		{err: &numberError{1062}, expected: ErrUniqueViolation},
+		{err: &numberError{1452}, expected: ErrForeignKeyViolation},
+		{err: &numberError{1213}, expected: ErrSerializationFailure},
+		{err: &numberError{1205}, expected: ErrLockTimeout},
+	}
+
+	for _, test := range tests {
+		err := MapDriverError(test.err)
+
+		de, ok := err.(*DriverError)
+		if !ok {
+			t.Fatalf("expected a DriverError for %v, got: %T", test.err, err)
+		}
+
+		if de.Kind != test.expected || !de.Is(test.expected) || de.Unwrap() != test.err {
+			t.Fatalf("expected %v for %v, got: %v", test.expected, test.err, de.Kind)
+		}
+
+		if err.Error() != test.err.Error() {
+			t.Fatalf("expected the message of the driver error, got: %s", err)
+		}
+	}
+
+	for _, err := range []error{nil, errors.New("other"), &codeError{"42P01"}, &numberError{1146}, (*codeError)(nil)} {
+		if got := MapDriverError(err); got != err {
+			t.Fatalf("expected %v to be returned as it is, got: %v", err, got)
+		}
+	}
+}
+
+func TestWithDriverErrors(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithDriverErrors(),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS driver_errors (id int PRIMARY KEY)",
+		"TRUNCATE driver_errors",
+		"INSERT INTO driver_errors VALUES (1)",
+	} {
+		if _, err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	_, err = db.Exec(ctx, "INSERT INTO driver_errors VALUES (1)")
+	if de, ok := err.(*DriverError); !ok || de.Kind != ErrUniqueViolation {
+		t.Fatalf("expected ErrUniqueViolation, got: %v", err)
+	}
+
+	var id int
+	err = db.QueryRow(ctx, "INSERT INTO driver_errors VALUES (1) RETURNING id").Scan(ctx, &id)
+	if de, ok := err.(*DriverError); !ok || de.Kind != ErrUniqueViolation {
+		t.Fatalf("expected ErrUniqueViolation from Scan, got: %v", err)
+	}
+}
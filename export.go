@@ -0,0 +1,149 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+// defaultExportBatchSize is the number of rows fetched at once by Export, if
+// the policy does not set one.
+const defaultExportBatchSize = 1000
+
+// ExportSink receives the rows of an export one by one, with the column names
+// of the query. Returning an error stops the export.
+type ExportSink func(columns []string, values []interface{}) error
+
+// ExportToken is the position of an export, it can be serialized and passed to
+// a later export of the same query for resuming it.
+type ExportToken struct {
+	// Offset is the number of rows passed to the sink.
+	Offset int64 `json:"offset"`
+}
+
+// ExportPolicy configures an export.
+type ExportPolicy struct {
+	// BatchSize is the number of rows fetched at once, defaults to 1000.
+	BatchSize int
+
+	// Resume is the token of a previous export of the query, rows before it
+	// are skipped.
+	Resume ExportToken
+
+	// Progress, if set, is called with the position of the export after
+	// every fetched batch.
+	Progress func(token ExportToken)
+}
+
+// Export runs the given query in a read-only, repeatable read transaction, and
+// passes its rows to sink, fetching them through a server-side cursor in
+// batches. So all the rows of an export are read from the same snapshot, no
+// matter how long it lasts. Returns the position of the export, even on error,
+// which can be passed to a later export for resuming it. A resumed export reads
+// a new snapshot, so the query must have a stable order, e.g. ORDER BY a
+// unique column. The database must support cursors, e.g. postgres.
+//
+// Export runs on a single connection checked out for its lifetime. Uses the
+// given ctx and its deadline to signal timeouts. On timeout or cancel case,
+// closes the underlying connection.
+func (db *DB) Export(ctx context.Context, query string, sink ExportSink, policy ExportPolicy) (ExportToken, error) {
+	if err := db.check(); err != nil {
+		return policy.Resume, err
+	}
+
+	batchSize := policy.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultExportBatchSize
+	}
+
+	// offset is updated by the export running on the connection, which may
+	// outlive Export on timeout
+	offset := policy.Resume.Offset
+
+	err := db.Raw(ctx, func(sqldb *sql.DB) error {
+		tx, err := sqldb.Begin()
+		if err != nil {
+			return err
+		}
+
+		// export is read-only, there is nothing to commit
+		defer tx.Rollback()
+
+		statements := []string{
+			exportTxSQLStatement,
+			"DECLARE ctxdb_export NO SCROLL CURSOR FOR " + query,
+		}
+
+		if offset > 0 {
+			statements = append(statements, fmt.Sprintf("MOVE FORWARD %d IN ctxdb_export", offset))
+		}
+
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM ctxdb_export", batchSize)
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			n, err := exportBatch(tx, fetch, sink, &offset)
+			if err != nil {
+				return err
+			}
+
+			if n == 0 {
+				return nil
+			}
+
+			if policy.Progress != nil {
+				policy.Progress(ExportToken{Offset: atomic.LoadInt64(&offset)})
+			}
+		}
+	})
+
+	return ExportToken{Offset: atomic.LoadInt64(&offset)}, err
+}
+
+// exportBatch fetches a batch of rows with the given fetch statement and passes
+// them to sink, returns the number of the fetched rows
+func exportBatch(tx *sql.Tx, fetch string, sink ExportSink, offset *int64) (int, error) {
+	rows, err := tx.Query(fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(prepareDest(dest)...); err != nil {
+			return n, err
+		}
+
+		if err := sink(columns, values); err != nil {
+			return n, err
+		}
+
+		n++
+		atomic.AddInt64(offset, 1)
+	}
+
+	return n, rows.Err()
+}
+
+const exportTxSQLStatement = `SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY`
@@ -0,0 +1,106 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	query := "SELECT i FROM generate_series(1, 2500) i ORDER BY i"
+
+	var count int
+	var progress []int64
+	token, err := db.Export(ctx, query, func(columns []string, values []interface{}) error {
+		count++
+		return nil
+	}, ExportPolicy{
+		Progress: func(token ExportToken) {
+			progress = append(progress, token.Offset)
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 2500 || token.Offset != 2500 {
+		t.Fatalf("expected 2500 rows, got: %d, token: %d", count, token.Offset)
+	}
+
+	if len(progress) != 3 || progress[2] != 2500 {
+		t.Fatalf("expected progress for 3 batches, got: %v", progress)
+	}
+}
+
+func TestExportResume(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	query := "SELECT i FROM generate_series(1, 100) i ORDER BY i"
+	errStop := errors.New("stop")
+
+	token, err := db.Export(ctx, query, func(columns []string, values []interface{}) error {
+		if values[0].(int64) == 41 {
+			return errStop
+		}
+
+		return nil
+	}, ExportPolicy{BatchSize: 10})
+	if err != errStop {
+		t.Fatalf("expected errStop, got: %v", err)
+	}
+
+	if token.Offset != 40 {
+		t.Fatalf("expected offset 40, got: %d", token.Offset)
+	}
+
+	var first int64
+	_, err = db.Export(ctx, query, func(columns []string, values []interface{}) error {
+		if first == 0 {
+			first = values[0].(int64)
+		}
+
+		return nil
+	}, ExportPolicy{BatchSize: 10, Resume: token})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if first != 41 {
+		t.Fatalf("expected export to resume from 41, got: %d", first)
+	}
+}
+
+func TestExportSnapshot(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	prepareAggregateData(t, db)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	var count int
+	_, err := db.Export(ctx, "SELECT * FROM nullable", func(columns []string, values []interface{}) error {
+		if count == 0 {
+			// rows added during the export are not exported
+			if _, err := db.Exec(ctx, insertSQLStatement, 5, nil, 1); err != nil {
+				return err
+			}
+		}
+
+		count++
+		return nil
+	}, ExportPolicy{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 4 {
+		t.Fatalf("expected 4 rows, got: %d", count)
+	}
+}
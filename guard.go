@@ -0,0 +1,41 @@
+package ctxdb
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrConcurrentMisuse is returned when the calls of two goroutines interleave on
+// a Rows or a Tx, which are not meant for concurrent use.
+var ErrConcurrentMisuse = errors.New("concurrent use of rows or transaction")
+
+// guard detects the interleaved calls on its owner
+type guard struct {
+	busy    int32
+	misused int32
+}
+
+// enter marks the owner as in use, returns ErrConcurrentMisuse and records the
+// misuse if it's already in use
+func (g *guard) enter() error {
+	if !atomic.CompareAndSwapInt32(&g.busy, 0, 1) {
+		atomic.StoreInt32(&g.misused, 1)
+		return ErrConcurrentMisuse
+	}
+
+	return nil
+}
+
+// leave marks the owner as not in use
+func (g *guard) leave() {
+	atomic.StoreInt32(&g.busy, 0)
+}
+
+// err returns ErrConcurrentMisuse if a misuse is recorded
+func (g *guard) err() error {
+	if atomic.LoadInt32(&g.misused) == 1 {
+		return ErrConcurrentMisuse
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	var g guard
+	if err := g.enter(); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := g.enter(); err != ErrConcurrentMisuse {
+		t.Fatalf("expected ErrConcurrentMisuse, got: %v", err)
+	}
+
+	g.leave()
+
+	if err := g.enter(); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// misuse is recorded
+	if err := g.err(); err != ErrConcurrentMisuse {
+		t.Fatalf("expected ErrConcurrentMisuse, got: %v", err)
+	}
+}
+
+func TestRowsConcurrentMisuse(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// simulate an interleaved call
+	rows.guard.enter()
+	if rows.Next(ctx) {
+		t.Fatal("expected Next to fail")
+	}
+	rows.guard.leave()
+
+	if err := rows.Err(); err != ErrConcurrentMisuse {
+		t.Fatalf("expected ErrConcurrentMisuse, got: %v", err)
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
+
+func TestTxConcurrentMisuse(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// simulate an interleaved call
+	tx.guard.enter()
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != ErrConcurrentMisuse {
+		t.Fatalf("expected ErrConcurrentMisuse, got: %v", err)
+	}
+	tx.guard.leave()
+
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
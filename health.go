@@ -0,0 +1,125 @@
+package ctxdb
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// TableHealth holds the vacuum and bloat statistics of a table, read from the
+// postgres statistics collector.
+type TableHealth struct {
+	Table           string
+	LiveTuples      int64
+	DeadTuples      int64
+	DeadTupleRatio  float64
+	LastVacuum      *time.Time
+	LastAutovacuum  *time.Time
+	LastAnalyze     *time.Time
+	LastAutoanalyze *time.Time
+	Indexes         []IndexHealth
+}
+
+// IndexHealth holds the size and estimated bloat of an index.
+type IndexHealth struct {
+	Name  string
+	Size  int64 // in bytes
+	Scans int64
+
+	// EstimatedBloat is the estimated ratio of the wasted pages of the index,
+	// it is an estimate from the column statistics, not an exact value.
+	EstimatedBloat float64
+}
+
+// TableHealth returns the health statistics of the given table, given without
+// its schema name. Returns sql.ErrNoRows if the table does not exist.
+func (db *DB) TableHealth(ctx context.Context, table string) (*TableHealth, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	h := &TableHealth{Table: table}
+
+	err := db.QueryRow(ctx, tableHealthSQLStatement, table).Scan(ctx,
+		&h.LiveTuples,
+		&h.DeadTuples,
+		&h.LastVacuum,
+		&h.LastAutovacuum,
+		&h.LastAnalyze,
+		&h.LastAutoanalyze,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if total := h.LiveTuples + h.DeadTuples; total > 0 {
+		h.DeadTupleRatio = float64(h.DeadTuples) / float64(total)
+	}
+
+	rows, err := db.Query(ctx, indexHealthSQLStatement, table)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rows.Reduce(ctx, func(scan ScanFunc) error {
+		var i IndexHealth
+		var pages, width, blockSize int64
+		var tuples float64
+		if err := scan(&i.Name, &i.Size, &i.Scans, &pages, &tuples, &width, &blockSize); err != nil {
+			return err
+		}
+
+		i.EstimatedBloat = estimateIndexBloat(pages, tuples, width, blockSize)
+		h.Indexes = append(h.Indexes, i)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// estimateIndexBloat estimates the bloat ratio of a btree index having the
+// given number of pages and tuples, with the average width of its columns.
+func estimateIndexBloat(pages int64, tuples float64, width, blockSize int64) float64 {
+	if pages <= 1 {
+		return 0
+	}
+
+	const (
+		itemPointer = 4  // line pointer of tuples
+		tupleHeader = 8  // index tuple header
+		pageHeader  = 24 // page header
+		special     = 16 // btree special space
+		fillFactor  = 0.9
+	)
+
+	// index tuples are aligned to 8 bytes
+	tupleSize := itemPointer + tupleHeader + (width+7)/8*8
+	usable := float64(blockSize-pageHeader-special) * fillFactor
+
+	// one page is the btree meta page
+	expected := math.Ceil(tuples*float64(tupleSize)/usable) + 1
+	if expected >= float64(pages) {
+		return 0
+	}
+
+	return 1 - expected/float64(pages)
+}
+
+const (
+	tableHealthSQLStatement = `SELECT n_live_tup, n_dead_tup,
+    last_vacuum, last_autovacuum, last_analyze, last_autoanalyze
+    FROM pg_stat_user_tables WHERE relname = $1`
+
+	indexHealthSQLStatement = `SELECT i.indexrelname,
+    pg_relation_size(i.indexrelid), i.idx_scan, c.relpages, c.reltuples,
+    COALESCE((SELECT SUM(s.avg_width) FROM pg_attribute a
+        JOIN pg_stats s ON s.schemaname = i.schemaname
+            AND s.tablename = i.relname AND s.attname = a.attname
+        WHERE a.attrelid = i.indexrelid), 0),
+    current_setting('block_size')::bigint
+    FROM pg_stat_user_indexes i JOIN pg_class c ON c.oid = i.indexrelid
+    WHERE i.relname = $1`
+)
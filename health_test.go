@@ -0,0 +1,41 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestTableHealth(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	h, err := db.TableHealth(ctx, "nullable")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if h.DeadTupleRatio < 0 || h.DeadTupleRatio > 1 {
+		t.Fatalf("expected ratio between 0 and 1, got: %f", h.DeadTupleRatio)
+	}
+
+	if _, err := db.TableHealth(ctx, "non_existing"); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got: %s", err)
+	}
+}
+
+func TestEstimateIndexBloat(t *testing.T) {
+	// 1000 tuples of 8 byte width fit in 4 pages with the meta page
+	if bloat := estimateIndexBloat(4, 1000, 8, 8192); bloat != 0 {
+		t.Fatalf("expected no bloat, got: %f", bloat)
+	}
+
+	if bloat := estimateIndexBloat(40, 1000, 8, 8192); bloat != 0.9 {
+		t.Fatalf("expected 0.9 bloat, got: %f", bloat)
+	}
+
+	if bloat := estimateIndexBloat(1, 0, 0, 8192); bloat != 0 {
+		t.Fatalf("expected no bloat for empty index, got: %f", bloat)
+	}
+}
@@ -0,0 +1,175 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// WithHealthCheck enables the background health checker, which pings the idle
+// connections of the pool on every interval, closing the dead ones. Database
+// is healthy if any of the connections is alive. Health is reported by Healthy
+// and its transitions are sent to HealthChanges. Checker stops when the DB is
+// closed. Non-positive intervals are ignored.
+func WithHealthCheck(interval time.Duration) Option {
+	return func(db *DB) {
+		if interval <= 0 {
+			return
+		}
+
+		db.healthCheck = &healthCheck{
+			interval: interval,
+			changes:  make(chan bool, 1),
+			stop:     make(chan struct{}),
+		}
+	}
+}
+
+// healthCheck holds the state of the health checker
+type healthCheck struct {
+	interval time.Duration
+	changes  chan bool     // health transitions
+	stop     chan struct{} // closed by Close
+
+	mu      sync.Mutex
+	checked bool // at least one check is completed
+	healthy bool
+}
+
+// Healthy reports whether the database is healthy, as of the last check of the
+// health checker. Pings the database with the given ctx if the checker is not
+// enabled, or has not completed a check yet.
+func (db *DB) Healthy(ctx context.Context) bool {
+	if db.check() != nil {
+		return false
+	}
+
+	if hc := db.healthCheck; hc != nil {
+		hc.mu.Lock()
+		checked, healthy := hc.checked, hc.healthy
+		hc.mu.Unlock()
+
+		if checked {
+			return healthy
+		}
+	}
+
+	return db.Ping(ctx) == nil
+}
+
+// HealthChanges returns the channel receiving the health transitions found by
+// the health checker, true when the database becomes healthy, false when it
+// becomes unhealthy. First check always sends its result. Channel holds only
+// the latest transition if it is not received in time. Channel is closed when
+// the checker stops, it is nil if the checker is not enabled.
+func (db *DB) HealthChanges() <-chan bool {
+	if db == nil || db.healthCheck == nil {
+		return nil
+	}
+
+	return db.healthCheck.changes
+}
+
+// runHealthCheck runs the health checks on every interval until the checker is
+// stopped
+func (db *DB) runHealthCheck() {
+	hc := db.healthCheck
+	defer close(hc.changes)
+
+	ticker := time.NewTicker(hc.interval)
+	defer ticker.Stop()
+
+	for {
+		db.checkHealth(hc)
+
+		select {
+		case <-hc.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopHealthCheck stops the health checker if it is enabled
+func (db *DB) stopHealthCheck() {
+	if db.healthCheck != nil {
+		close(db.healthCheck.stop)
+	}
+}
+
+// checkHealth pings the idle connections, and the database if none of them is
+// alive, then records the health
+func (db *DB) checkHealth(hc *healthCheck) {
+	ctx, cancel := context.WithTimeout(context.Background(), hc.interval)
+	defer cancel()
+
+	healthy := db.checkIdle(ctx) > 0 || db.ping(ctx) == nil
+
+	hc.mu.Lock()
+	changed := !hc.checked || hc.healthy != healthy
+	hc.checked = true
+	hc.healthy = healthy
+	hc.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	// replace the transition which is not received yet
+	select {
+	case <-hc.changes:
+	default:
+	}
+
+	hc.changes <- healthy
+}
+
+// checkIdle pings the idle connections once each, closing the dead ones, and
+// returns the number of the alive ones. Connections in use are not checked.
+func (db *DB) checkIdle(ctx context.Context) int {
+	conns := db.getConns()
+	if conns == nil {
+		return 0
+	}
+
+	var alive int
+	for n := len(conns); n > 0; n-- {
+		// a sem is held while the conn is checked, like any operation
+		if !db.tryAcquire() {
+			return alive
+		}
+
+		var conn *sql.DB
+		select {
+		case conn = <-conns:
+		default:
+		}
+
+		if conn == nil {
+			db.release()
+			return alive
+		}
+
+		done := make(chan struct{}, 1)
+		var pingErr error
+		f := func() {
+			pingErr = dbPing(ctx, conn)
+			close(done)
+		}
+
+		err := db.handleWithGivenSQL(ctx, f, done, conn)
+		if err == nil {
+			err = pingErr
+		}
+
+		if err == nil {
+			alive++
+		}
+
+		// dead conns are closed
+		db.restoreOrClose(err, conn)
+	}
+
+	return alive
+}
@@ -0,0 +1,97 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHealthCheck(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithHealthCheck(time.Millisecond*20),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	ctx := context.Background()
+
+	select {
+	case healthy := <-db.HealthChanges():
+		if !healthy {
+			t.Fatal("expected healthy, got unhealthy")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a health transition")
+	}
+
+	if !db.Healthy(ctx) {
+		t.Fatal("expected healthy, got unhealthy")
+	}
+
+	// dead idle connections are evicted
+	conn, err := db.getFromPool(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	conn.Close()
+	if err := db.put(conn); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	time.Sleep(time.Millisecond * 50)
+
+	db.mu.Lock()
+	_, ok := db.infos[conn]
+	db.mu.Unlock()
+
+	if ok {
+		t.Fatal("expected the dead connection to be evicted")
+	}
+
+	if err := db.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// changes are closed with the checker
+	for range db.HealthChanges() {
+	}
+}
+
+func TestHealthyWithoutHealthCheck(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	if db.HealthChanges() != nil {
+		t.Fatal("expected nil changes")
+	}
+
+	if !db.Healthy(context.Background()) {
+		t.Fatal("expected healthy, got unhealthy")
+	}
+}
+
+func TestHealthCheckInvalidInterval(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithHealthCheck(0),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	if db.HealthChanges() != nil {
+		t.Fatal("expected the checker not to be enabled")
+	}
+
+	var nilDB *DB
+	if nilDB.Healthy(context.Background()) {
+		t.Fatal("expected nil db to be unhealthy")
+	}
+}
@@ -0,0 +1,99 @@
+package ctxdb
+
+import (
+	"sync"
+	"time"
+)
+
+// StatsSample is a snapshot of the pool statistics taken at a time.
+type StatsSample struct {
+	At time.Time
+	PoolStats
+}
+
+// WithStatsHistory keeps the last size snapshots of the pool statistics taken
+// at every interval in memory, so the recent saturation of the pool can be
+// inspected with StatsHistory after an incident, without external metrics.
+// Sampling stops when the DB is closed. Non-positive intervals and sizes are
+// ignored.
+func WithStatsHistory(interval time.Duration, size int) Option {
+	return func(db *DB) {
+		if interval <= 0 || size <= 0 {
+			return
+		}
+
+		db.statsHistory = &statsHistory{
+			interval: interval,
+			samples:  make([]StatsSample, size),
+			stop:     make(chan struct{}),
+		}
+	}
+}
+
+// statsHistory is a ring buffer of the pool statistics
+type statsHistory struct {
+	interval time.Duration
+	stop     chan struct{} // closed by Close
+
+	mu      sync.Mutex
+	samples []StatsSample
+	next    int  // index of the next sample
+	full    bool // samples wrapped around
+}
+
+// StatsHistory returns the recorded snapshots of the pool statistics, oldest
+// first. It is nil if the history is not enabled.
+func (db *DB) StatsHistory() []StatsSample {
+	if db == nil || db.statsHistory == nil {
+		return nil
+	}
+
+	h := db.statsHistory
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		return append([]StatsSample(nil), h.samples[:h.next]...)
+	}
+
+	samples := make([]StatsSample, 0, len(h.samples))
+	samples = append(samples, h.samples[h.next:]...)
+	return append(samples, h.samples[:h.next]...)
+}
+
+// recordStats samples the pool statistics on every interval until the history
+// is stopped
+func (db *DB) recordStats() {
+	h := db.statsHistory
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+
+		h.add(StatsSample{At: time.Now(), PoolStats: db.PoolStats()})
+	}
+}
+
+// stopStatsHistory stops the sampling if the history is enabled
+func (db *DB) stopStatsHistory() {
+	if db.statsHistory != nil {
+		close(db.statsHistory.stop)
+	}
+}
+
+func (h *statsHistory) add(s StatsSample) {
+	h.mu.Lock()
+	h.samples[h.next] = s
+	h.next++
+	if h.next == len(h.samples) {
+		h.next = 0
+		h.full = true
+	}
+	h.mu.Unlock()
+}
@@ -0,0 +1,73 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithStatsHistory(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithStatsHistory(time.Millisecond*5, 3),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	time.Sleep(time.Millisecond * 50)
+
+	samples := db.StatsHistory()
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples, got: %d", len(samples))
+	}
+
+	for i := 1; i < len(samples); i++ {
+		if !samples[i].At.After(samples[i-1].At) {
+			t.Fatalf("expected the samples oldest first, got: %+v", samples)
+		}
+	}
+
+	if samples[0].MaxOpenConns == 0 {
+		t.Fatalf("expected the pool statistics, got: %+v", samples[0])
+	}
+}
+
+func TestStatsHistoryRing(t *testing.T) {
+	db := &DB{statsHistory: &statsHistory{samples: make([]StatsSample, 2)}}
+	if n := len(db.StatsHistory()); n != 0 {
+		t.Fatalf("expected no samples, got: %d", n)
+	}
+
+	for i := 1; i <= 3; i++ {
+		db.statsHistory.add(StatsSample{PoolStats: PoolStats{Checkouts: int64(i)}})
+	}
+
+	samples := db.StatsHistory()
+	if len(samples) != 2 || samples[0].Checkouts != 2 || samples[1].Checkouts != 3 {
+		t.Fatalf("expected the last 2 samples, got: %+v", samples)
+	}
+
+	if (&DB{}).StatsHistory() != nil {
+		t.Fatal("expected nil history when it is not enabled")
+	}
+}
+
+func TestStatsHistoryInvalidInterval(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithStatsHistory(0, 10),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	if db.StatsHistory() != nil {
+		t.Fatal("expected the history not to be enabled")
+	}
+}
@@ -0,0 +1,48 @@
+package ctxdb
+
+import "time"
+
+// ConnEvent describes a connection in a pool lifecycle event.
+type ConnEvent struct {
+	Age        time.Duration   // time since the connection is created
+	Uses       int64           // number of checkouts of the connection
+	Attributes *ConnAttributes // set if WithConnAttributes is set
+}
+
+// Hooks are called on the lifecycle events of the pooled connections, for
+// metrics and debugging. Hooks are called synchronously while the pool is
+// locked, so they must be fast and must not use the DB. Nil hooks are skipped.
+type Hooks struct {
+	// OnConnectionNew is called when a connection is created.
+	OnConnectionNew func(e ConnEvent)
+
+	// OnCheckout is called when a connection is taken for an operation.
+	OnCheckout func(e ConnEvent)
+
+	// OnCheckin is called when a used connection is put back to the pool.
+	OnCheckin func(e ConnEvent)
+
+	// OnClose is called when a connection is closed.
+	OnClose func(e ConnEvent)
+}
+
+// WithHooks sets the hooks of the pool lifecycle events.
+func WithHooks(h Hooks) Option {
+	return func(db *DB) {
+		db.hooks = h
+	}
+}
+
+// fireLocked calls the hook with the event of the given conn info, caller must
+// hold db.mu
+func fireLocked(hook func(e ConnEvent), info *connInfo) {
+	if hook == nil || info == nil {
+		return
+	}
+
+	hook(ConnEvent{
+		Age:        time.Since(info.createdAt),
+		Uses:       info.uses,
+		Attributes: info.attrs,
+	})
+}
@@ -0,0 +1,67 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestWithHooks(t *testing.T) {
+	var mu sync.Mutex
+	var news, checkouts, checkins, closes int
+	var lastUses int64
+
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithHooks(Hooks{
+			OnConnectionNew: func(e ConnEvent) {
+				mu.Lock()
+				news++
+				mu.Unlock()
+			},
+			OnCheckout: func(e ConnEvent) {
+				mu.Lock()
+				checkouts++
+				lastUses = e.Uses
+				mu.Unlock()
+			},
+			OnCheckin: func(e ConnEvent) {
+				mu.Lock()
+				checkins++
+				mu.Unlock()
+			},
+			OnClose: func(e ConnEvent) {
+				mu.Lock()
+				closes++
+				mu.Unlock()
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	if err := db.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if news != 1 || checkouts != 2 || checkins != 2 || closes != 1 {
+		t.Fatalf("expected 1 new, 2 checkouts, 2 checkins and 1 close, got: %d, %d, %d, %d", news, checkouts, checkins, closes)
+	}
+
+	if lastUses != 2 {
+		t.Fatalf("expected the connection to be used twice, got: %d", lastUses)
+	}
+}
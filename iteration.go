@@ -0,0 +1,73 @@
+package ctxdb
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrIterationTimeout is returned by the Rows iterated longer than their
+// iteration timeout.
+var ErrIterationTimeout = errors.New("rows iteration timed out")
+
+// WithIterationTimeout sets the max wall time of iterating the Rows returned by
+// Query, see Rows.SetIterationTimeout. Zero disables it.
+func WithIterationTimeout(d time.Duration) Option {
+	return func(db *DB) {
+		db.iterationTimeout = d
+	}
+}
+
+// SetIterationTimeout limits the wall time of the iteration of the rows to d
+// from now, independent of the ctx of the calls. When it is exceeded, the rows
+// are closed and their connection is given back, even if the consumer stalls
+// between two calls, then Next returns false and Err returns
+// ErrIterationTimeout. Replaces the previous timeout, zero disables it.
+func (rs *Rows) SetIterationTimeout(d time.Duration) {
+	if rs.check() != nil {
+		return
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.expiry != nil {
+		rs.expiry.Stop()
+		rs.expiry = nil
+	}
+
+	if d > 0 && !rs.released {
+		rs.expiry = time.AfterFunc(d, rs.expire)
+	}
+}
+
+// expire closes the rows on iteration timeout and gives their connection back
+func (rs *Rows) expire() {
+	atomic.StoreInt32(&rs.expired, 1)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.released {
+		return
+	}
+
+	// safe for concurrent use with an in-flight Next or Scan
+	rs.rows.Close()
+
+	rs.released = true
+	rs.db.restoreOrClose(ErrIterationTimeout, rs.sqldb)
+}
+
+// isExpired reports whether the iteration timeout is exceeded
+func (rs *Rows) isExpired() bool {
+	return atomic.LoadInt32(&rs.expired) == 1
+}
+
+// checkExpired fails the rows with ErrIterationTimeout if the iteration timeout
+// is exceeded, caller must enter the guard of the rows
+func (rs *Rows) checkExpired() {
+	if rs.err == nil && rs.isExpired() {
+		rs.err = ErrIterationTimeout
+	}
+}
@@ -0,0 +1,81 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRowsSetIterationTimeout(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT generate_series(1, 10)")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	rows.SetIterationTimeout(time.Millisecond * 20)
+
+	if !rows.Next(ctx) {
+		t.Fatalf("expected a row, got: %v", rows.Err())
+	}
+
+	// stalled consumer does not hold the connection
+	time.Sleep(time.Millisecond * 40)
+
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if rows.Next(ctx) {
+		t.Fatal("expected no rows after the iteration timeout")
+	}
+
+	if err := rows.Err(); err != ErrIterationTimeout {
+		t.Fatalf("expected ErrIterationTimeout, got: %v", err)
+	}
+
+	if err := rows.Close(ctx); err != ErrIterationTimeout {
+		t.Fatalf("expected ErrIterationTimeout, got: %v", err)
+	}
+}
+
+func TestWithIterationTimeout(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithIterationTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, "SELECT generate_series(1, 10)")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// rows finished in time are not affected
+	count, err := rows.Count(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 10 {
+		t.Fatalf("expected 10 rows, got: %d", count)
+	}
+}
@@ -0,0 +1,113 @@
+package ctxdb
+
+import "context"
+
+// TxJournal is the bundle of statements executed within a failed transaction,
+// in their execution order. It can be serialized, and replayed against another
+// database with ReplayTx for reproducing the failure.
+type TxJournal struct {
+	// Statements are the statements executed within the transaction.
+	Statements []TxStatement `json:"statements"`
+
+	// Committed reports whether the transaction was ended with Commit.
+	Committed bool `json:"committed"`
+
+	// Err is the error ended the transaction, if any.
+	Err string `json:"err,omitempty"`
+}
+
+// TxStatement is a statement executed within a transaction.
+type TxStatement struct {
+	Query string        `json:"query"`
+	Args  []interface{} `json:"args,omitempty"`
+	Err   string        `json:"err,omitempty"`
+}
+
+// SetTxJournal enables the transaction debug mode. When enabled, all statements
+// of a transaction are journaled with their args, and f is called with the
+// journal if the transaction fails; it is rolled back, or its commit returns an
+// error. Passing nil disables the debug mode.
+func (db *DB) SetTxJournal(f func(j *TxJournal)) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.txJournal = f
+	db.mu.Unlock()
+}
+
+func (db *DB) getTxJournal() func(j *TxJournal) {
+	db.mu.Lock()
+	f := db.txJournal
+	db.mu.Unlock()
+	return f
+}
+
+// ReplayTx replays the statements of the given journal within a new
+// transaction on db, ending it the same way the journaled one ended. Returns
+// the first error encountered, which is the reproduced failure if any.
+func ReplayTx(ctx context.Context, db *DB, j *TxJournal) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range j.Statements {
+		if _, err := tx.Exec(ctx, s.Query, s.Args...); err != nil {
+			tx.Rollback(ctx)
+			return err
+		}
+	}
+
+	if j.Committed {
+		return tx.Commit(ctx)
+	}
+
+	return tx.Rollback(ctx)
+}
+
+// record journals the given statement if the debug mode is enabled.
+func (tx *Tx) record(query string, args []interface{}, err error) {
+	if tx.journal == nil {
+		return
+	}
+
+	s := TxStatement{
+		Query: query,
+		Args:  append([]interface{}(nil), args...),
+	}
+
+	if err != nil {
+		s.Err = err.Error()
+	}
+
+	tx.journal.Statements = append(tx.journal.Statements, s)
+}
+
+// finishJournal passes the journal to the journal func of db if the
+// transaction is failed. Journal is passed at most once.
+func (tx *Tx) finishJournal(committed bool, err error) {
+	j := tx.journal
+	if j == nil {
+		return
+	}
+
+	tx.journal = nil
+
+	if committed && err == nil {
+		return
+	}
+
+	f := tx.db.getTxJournal()
+	if f == nil {
+		return
+	}
+
+	j.Committed = committed
+	if err != nil {
+		j.Err = err.Error()
+	}
+
+	f(j)
+}
@@ -0,0 +1,82 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTxJournal(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	var journal *TxJournal
+	db.SetTxJournal(func(j *TxJournal) {
+		journal = j
+	})
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("err while beginning the transaction: %s", err)
+	}
+
+	if _, err := tx.Exec(ctx, insertSQLStatement, 42, nil, 12); err != nil {
+		t.Fatalf("err while adding null item: %s", err.Error())
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT * FROM non_existing"); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("err while rolling back the tx: err : %s", err)
+	}
+
+	if journal == nil {
+		t.Fatalf("journal should not be nil")
+	}
+
+	if len(journal.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got: %d", len(journal.Statements))
+	}
+
+	if journal.Statements[1].Err == "" {
+		t.Fatalf("expected error of the second statement to be journaled")
+	}
+
+	if journal.Committed {
+		t.Fatalf("expected journal not to be committed")
+	}
+
+	if err := ReplayTx(ctx, db, journal); err == nil {
+		t.Fatalf("expected error while replaying, got nil")
+	}
+}
+
+func TestTxJournalCommit(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	var journal *TxJournal
+	db.SetTxJournal(func(j *TxJournal) {
+		journal = j
+	})
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("err while beginning the transaction: %s", err)
+	}
+
+	if _, err := tx.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("err while committing the tx: err : %s", err)
+	}
+
+	if journal != nil {
+		t.Fatalf("journal should be nil for committed transactions")
+	}
+}
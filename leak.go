@@ -0,0 +1,66 @@
+package ctxdb
+
+import (
+	"database/sql"
+	"runtime/debug"
+	"time"
+)
+
+// Leak describes a connection which is not given back to the pool within the
+// threshold of the leak detector, e.g. because of a missing Rows.Close or
+// Tx.Commit.
+type Leak struct {
+	Op    string        // operation checked out the connection, e.g. "Query"
+	Stack []byte        // stack trace of the caller of the operation
+	Held  time.Duration // time the connection is held, the threshold
+}
+
+// WithLeakDetector enables the leak detector, which records the stack traces
+// of the Query, QueryRow and Begin calls, and calls f with a Leak if their
+// connections are not given back within the threshold. f is called from its
+// own goroutine, once for every leaked checkout. Recording the stack traces
+// has a cost, so it is meant for finding leaks rather than running always.
+func WithLeakDetector(threshold time.Duration, f func(l *Leak)) Option {
+	return func(db *DB) {
+		db.leakThreshold = threshold
+		db.leakReport = f
+	}
+}
+
+// trackLeak starts the leak timer of the conn checked out by op, if the leak
+// detector is enabled
+func (db *DB) trackLeak(conn *sql.DB, op string) {
+	if db.leakReport == nil {
+		return
+	}
+
+	l := &Leak{Op: op, Stack: debug.Stack(), Held: db.leakThreshold}
+	timer := time.AfterFunc(db.leakThreshold, func() {
+		db.leakReport(l)
+	})
+
+	db.mu.Lock()
+	info := db.infos[conn]
+	if info != nil {
+		info.leak = timer
+	}
+	db.mu.Unlock()
+
+	if info == nil {
+		timer.Stop()
+	}
+}
+
+// untrackLeak stops the leak timer of the conn given back to the pool
+func (db *DB) untrackLeak(conn *sql.DB) {
+	if db.leakReport == nil {
+		return
+	}
+
+	db.mu.Lock()
+	if info := db.infos[conn]; info != nil && info.leak != nil {
+		info.leak.Stop()
+		info.leak = nil
+	}
+	db.mu.Unlock()
+}
@@ -0,0 +1,61 @@
+package ctxdb
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithLeakDetector(t *testing.T) {
+	leaks := make(chan *Leak, 2)
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithLeakDetector(time.Millisecond*20, func(l *Leak) {
+			leaks <- l
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	// closed rows are not reported
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	leaked, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer leaked.Close(ctx)
+
+	select {
+	case l := <-leaks:
+		if l.Op != "Query" {
+			t.Fatalf("expected a leak of Query, got: %s", l.Op)
+		}
+
+		if !bytes.Contains(l.Stack, []byte("TestWithLeakDetector")) {
+			t.Fatalf("expected the stack of the caller, got: %s", l.Stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a leak to be reported")
+	}
+
+	select {
+	case l := <-leaks:
+		t.Fatalf("expected a single leak, got another of %s", l.Op)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
@@ -0,0 +1,158 @@
+package ctxdb
+
+import (
+	"database/sql"
+	"time"
+)
+
+// minReapInterval is the min interval between two runs of the reaper
+const minReapInterval = time.Millisecond * 10
+
+// SetConnMaxLifetime sets the maximum amount of time a connection may be
+// reused. Expired connections are closed by a background reaper while idle,
+// and when they are checked out or put back to the pool. Zero means
+// connections are reused forever.
+func (db *DB) SetConnMaxLifetime(d time.Duration) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.connMaxLifetime = d
+	db.startReaperLocked()
+	db.mu.Unlock()
+}
+
+// SetConnMaxIdleTime sets the maximum amount of time a connection may be idle
+// in the pool, before it is closed by a background reaper. Zero means
+// connections are not closed due to their idle time.
+func (db *DB) SetConnMaxIdleTime(d time.Duration) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.connMaxIdleTime = d
+	db.startReaperLocked()
+	db.mu.Unlock()
+}
+
+// startReaperLocked starts the reaper, unless it is already running or the db
+// is closed. Reaper is stopped if none of the limits is set.
+func (db *DB) startReaperLocked() {
+	if db.connMaxLifetime <= 0 && db.connMaxIdleTime <= 0 {
+		db.stopReaperLocked()
+		return
+	}
+
+	if db.reaperStop != nil || db.conns == nil {
+		return
+	}
+
+	db.reaperStop = make(chan struct{})
+	go db.reap(db.reaperStop)
+}
+
+// stopReaper stops the reaper if it is running
+func (db *DB) stopReaper() {
+	db.mu.Lock()
+	db.stopReaperLocked()
+	db.mu.Unlock()
+}
+
+func (db *DB) stopReaperLocked() {
+	if db.reaperStop != nil {
+		close(db.reaperStop)
+		db.reaperStop = nil
+	}
+}
+
+// reapInterval returns the interval of the reaper, the half of the shortest
+// limit
+func (db *DB) reapInterval() time.Duration {
+	db.mu.Lock()
+	d := db.connMaxLifetime
+	if d == 0 || (db.connMaxIdleTime > 0 && db.connMaxIdleTime < d) {
+		d = db.connMaxIdleTime
+	}
+	db.mu.Unlock()
+
+	d /= 2
+	if d < minReapInterval {
+		d = minReapInterval
+	}
+
+	return d
+}
+
+// reap closes the expired idle connections on every interval until stop is
+// closed
+func (db *DB) reap(stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(db.reapInterval()):
+		}
+
+		db.reapIdle()
+	}
+}
+
+// reapIdle checks the idle connections once each, closing the expired ones
+func (db *DB) reapIdle() {
+	conns := db.getConns()
+	if conns == nil {
+		return
+	}
+
+	for n := len(conns); n > 0; n-- {
+		// a sem is held while the conn is checked, like any operation
+		if !db.tryAcquire() {
+			return
+		}
+
+		var conn *sql.DB
+		select {
+		case conn = <-conns:
+		default:
+		}
+
+		if conn != nil {
+			if db.isExpired(conn, time.Now()) {
+				db.closeConn(conn)
+			} else {
+				db.put(conn)
+			}
+		}
+
+		db.release()
+
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// isExpired reports whether the conn exceeded the max lifetime, or the max
+// idle time if it is idle
+func (db *DB) isExpired(conn *sql.DB, now time.Time) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.isExpiredLocked(conn, now)
+}
+
+func (db *DB) isExpiredLocked(conn *sql.DB, now time.Time) bool {
+	info, ok := db.infos[conn]
+	if !ok {
+		return false
+	}
+
+	if db.connMaxLifetime > 0 && now.Sub(info.createdAt) >= db.connMaxLifetime {
+		return true
+	}
+
+	return db.connMaxIdleTime > 0 && !info.idleSince.IsZero() &&
+		now.Sub(info.idleSince) >= db.connMaxIdleTime
+}
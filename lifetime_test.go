@@ -0,0 +1,90 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func isOpenConn(db *DB, conn *sql.DB) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	_, ok := db.infos[conn]
+	return ok
+}
+
+func TestSetConnMaxIdleTime(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	if err := db.Ping(context.Background()); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	conn := <-db.conns
+	if err := db.put(conn); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	db.SetConnMaxIdleTime(time.Millisecond * 20)
+	time.Sleep(time.Millisecond * 60)
+
+	if isOpenConn(db, conn) {
+		t.Fatal("expected the idle connection to be closed")
+	}
+
+	if len(db.getConns()) != 0 {
+		t.Fatalf("expected no idle connections, got: %d", len(db.getConns()))
+	}
+}
+
+func TestSetConnMaxLifetime(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	db.SetConnMaxLifetime(time.Millisecond * 20)
+
+	conn, err := db.getFromPool(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	time.Sleep(time.Millisecond * 30)
+
+	// expired conns are closed on put
+	if err := db.put(conn); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if isOpenConn(db, conn) {
+		t.Fatal("expected the expired connection to be closed")
+	}
+}
+
+func TestReaperStopsWithoutLimits(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	running := func() bool {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		return db.reaperStop != nil
+	}
+
+	db.SetConnMaxIdleTime(0)
+	if running() {
+		t.Fatal("expected the reaper not to start without limits")
+	}
+
+	db.SetConnMaxLifetime(time.Hour)
+	if !running() {
+		t.Fatal("expected the reaper to start")
+	}
+
+	db.SetConnMaxLifetime(0)
+	if running() {
+		t.Fatal("expected the reaper to stop without limits")
+	}
+}
@@ -0,0 +1,111 @@
+package ctxdb
+
+import (
+	"context"
+	"time"
+)
+
+// LogEntry is the record of an operation passed to the Logger.
+type LogEntry struct {
+	Op       string // name of the operation, e.g. "Exec" or "Tx" for a transaction
+	Query    string
+	Duration time.Duration
+
+	// ArgCount is the number of the args of the query, Args are the args
+	// returned by the redactor, nil if no redactor is set.
+	ArgCount int
+	Args     []interface{}
+
+	// RowsAffected is the number of the affected rows, -1 if it is unknown.
+	RowsAffected int64
+	Err          error
+}
+
+// Logger receives the records of the operations, set with SetLogger.
+type Logger interface {
+	Log(ctx context.Context, e *LogEntry)
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as Loggers.
+type LoggerFunc func(ctx context.Context, e *LogEntry)
+
+// Log calls f(ctx, e).
+func (f LoggerFunc) Log(ctx context.Context, e *LogEntry) {
+	f(ctx, e)
+}
+
+// Redactor returns the args of a query as they should be logged, e.g. masking
+// the personal data. It must not modify the given args.
+type Redactor func(query string, args []interface{}) []interface{}
+
+// SetLogger sets the logger receiving a record for every Exec, Query and
+// QueryRow of the DB and its transactions, and for every transaction, after
+// they end. Args of the queries are not logged unless a redactor is set with
+// SetLogRedactor, only their count is. Nil disables the logging.
+func (db *DB) SetLogger(l Logger) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.logger = l
+	db.mu.Unlock()
+}
+
+// SetLogRedactor sets the redactor of the args of the logged queries, so the
+// args are logged as they are returned from r.
+func (db *DB) SetLogRedactor(r Redactor) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.logRedactor = r
+	db.mu.Unlock()
+}
+
+// startOp starts the span of an operation, and returns its end which also logs
+// the operation if a logger is set, or it is slow, samples its server time and
+// tracks its table
+func (db *DB) startOp(ctx, parent context.Context, op, query string, args []interface{}) (context.Context, func(int64, error)) {
+	ctx, end := db.startSpan(ctx, parent, op, query)
+
+	db.mu.Lock()
+	logger, redactor := db.logger, db.logRedactor
+	db.mu.Unlock()
+
+	if logger == nil && db.slowLog == nil && db.serverTiming == nil && db.deps == nil &&
+		db.timeoutAdvisor == nil {
+		return ctx, end
+	}
+
+	start := time.Now()
+	return ctx, func(rowsAffected int64, err error) {
+		end(rowsAffected, err)
+
+		d := time.Since(start)
+		db.logSlow(op, query, args, d, err)
+		db.sampleServerTime(ctx, op, query, args, d, err)
+		db.trackDeps(ctx, query, rowsAffected, err)
+		db.recordLatency(ctx, op, d, err)
+
+		if logger == nil {
+			return
+		}
+
+		e := &LogEntry{
+			Op:           op,
+			Query:        query,
+			Duration:     d,
+			ArgCount:     len(args),
+			RowsAffected: rowsAffected,
+			Err:          err,
+		}
+
+		if redactor != nil {
+			e.Args = redactor(query, args)
+		}
+
+		logger.Log(ctx, e)
+	}
+}
@@ -0,0 +1,58 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetLogger(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	var entries []*LogEntry
+	db.SetLogger(LoggerFunc(func(ctx context.Context, e *LogEntry) {
+		entries = append(entries, e)
+	}))
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "SELECT $1::text", "secret"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	db.SetLogRedactor(func(query string, args []interface{}) []interface{} {
+		redacted := make([]interface{}, len(args))
+		for i := range args {
+			redacted[i] = "***"
+		}
+
+		return redacted
+	})
+
+	var s string
+	if err := db.QueryRow(ctx, "SELECT $1::text", "secret").Scan(ctx, &s); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got: %d", len(entries))
+	}
+
+	exec := entries[0]
+	if exec.Op != "Exec" || exec.Query != "SELECT $1::text" || exec.ArgCount != 1 || exec.Args != nil || exec.RowsAffected != 1 {
+		t.Fatalf("expected the entry of Exec without the args, got: %+v", exec)
+	}
+
+	row := entries[1]
+	if row.Op != "QueryRow" || len(row.Args) != 1 || row.Args[0] != "***" || row.Err != nil {
+		t.Fatalf("expected the entry of QueryRow with the redacted args, got: %+v", row)
+	}
+
+	db.SetLogger(nil)
+	if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected the logging to be disabled, got: %d entries", len(entries))
+	}
+}
@@ -0,0 +1,122 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInvalidRefreshInterval is returned by Register if the refresh interval is
+// not positive.
+var ErrInvalidRefreshInterval = errors.New("refresh interval must be positive")
+
+// ViewRefresher refreshes the registered materialized views on their
+// intervals, or on demand. Refreshes are protected with an advisory lock, so
+// concurrent refreshes of a view across the replicas of a service are skipped.
+type ViewRefresher struct {
+	db *DB
+
+	mu    sync.Mutex
+	views map[string]time.Duration
+}
+
+// NewViewRefresher creates a refresher running the refreshes on given db.
+func NewViewRefresher(db *DB) *ViewRefresher {
+	return &ViewRefresher{
+		db:    db,
+		views: make(map[string]time.Duration),
+	}
+}
+
+// Register registers the view to be refreshed at every interval. Views must
+// be registered before calling Run.
+func (r *ViewRefresher) Register(view string, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidRefreshInterval
+	}
+
+	r.mu.Lock()
+	r.views[view] = interval
+	r.mu.Unlock()
+	return nil
+}
+
+// Refresh refreshes the given view concurrently, within a transaction holding
+// an advisory lock for the view. Returns false if the lock is held by another
+// refresh.
+func (r *ViewRefresher) Refresh(ctx context.Context, view string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.Exec(ctx, "SELECT 1 WHERE pg_try_advisory_xact_lock(hashtext($1))", view)
+	if err != nil {
+		tx.Rollback(ctx)
+		return false, err
+	}
+
+	locked, err := res.RowsAffected(ctx)
+	if err != nil {
+		tx.Rollback(ctx)
+		return false, err
+	}
+
+	if locked == 0 {
+		return false, tx.Rollback(ctx)
+	}
+
+	if _, err := tx.Exec(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY "+view); err != nil {
+		tx.Rollback(ctx)
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Run refreshes the registered views on their intervals until the given ctx is
+// done. Every refresh uses a ctx with the interval of the view as timeout, and
+// errors of the refreshes are passed to f.
+func (r *ViewRefresher) Run(ctx context.Context, f func(view string, err error)) {
+	r.mu.Lock()
+	views := make(map[string]time.Duration, len(r.views))
+	for view, interval := range r.views {
+		views[view] = interval
+	}
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for view, interval := range views {
+		wg.Add(1)
+		go func(view string, interval time.Duration) {
+			defer wg.Done()
+			r.run(ctx, view, interval, f)
+		}(view, interval)
+	}
+
+	wg.Wait()
+}
+
+func (r *ViewRefresher) run(ctx context.Context, view string, interval time.Duration, f func(view string, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		refreshCtx, cancel := context.WithTimeout(ctx, interval)
+		if _, err := r.Refresh(refreshCtx, view); err != nil && f != nil {
+			f(view, err)
+		}
+		cancel()
+	}
+}
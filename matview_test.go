@@ -0,0 +1,43 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestViewRefresherRefresh(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE MATERIALIZED VIEW IF NOT EXISTS nullable_view AS SELECT 1 AS id"); err != nil {
+		t.Fatalf("err while creating the view: %s", err)
+	}
+
+	if _, err := db.Exec(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS nullable_view_id ON nullable_view (id)"); err != nil {
+		t.Fatalf("err while creating the index: %s", err)
+	}
+
+	r := NewViewRefresher(db)
+	if err := r.Register("nullable_view", time.Minute); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := r.Register("nullable_view", 0); err != ErrInvalidRefreshInterval {
+		t.Fatalf("expected ErrInvalidRefreshInterval, got: %v", err)
+	}
+
+	refreshed, err := r.Refresh(ctx, "nullable_view")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if !refreshed {
+		t.Fatalf("expected view to be refreshed")
+	}
+
+	if _, err := db.Exec(ctx, "DROP MATERIALIZED VIEW nullable_view"); err != nil {
+		t.Fatalf("err while dropping the view: %s", err)
+	}
+}
@@ -0,0 +1,47 @@
+package ctxdb
+
+import "context"
+
+// Op is an Exec, Query or QueryRow call of the DB or its transactions passed
+// through the middlewares. Middlewares may change the query and the args
+// before calling the next executor, which sets the result of the call.
+type Op struct {
+	Name  string // e.g. "Exec" or "Tx.QueryRow"
+	Query string
+	Args  []interface{}
+
+	// Result is set by Exec, Rows by Query and Row by QueryRow, the latest
+	// call of the next executor sets them.
+	Result *Result
+	Rows   *Rows
+	Row    *Row
+}
+
+// Executor runs an operation. Errors of QueryRow returned from executors are
+// the ones which are known before Row.Scan.
+type Executor func(ctx context.Context, op *Op) error
+
+// Middleware wraps an executor, e.g. for logging, metrics, retries or tagging
+// the operations in one place. A middleware may stop an operation by returning
+// an error without calling next.
+type Middleware func(next Executor) Executor
+
+// WithMiddleware applies the middlewares to every Exec, Query and QueryRow of
+// the DB and its transactions, the first one is the outermost. Middlewares
+// wrap the other features of the operations, e.g. the retries and the spans.
+func WithMiddleware(m ...Middleware) Option {
+	return func(db *DB) {
+		db.middlewares = append(db.middlewares, m...)
+	}
+}
+
+// intercept runs the operation through the middlewares, then with f after
+// binding its args
+func (db *DB) intercept(ctx context.Context, op *Op, f Executor) error {
+	next := db.bind(f)
+	for i := len(db.middlewares) - 1; i >= 0; i-- {
+		next = db.middlewares[i](next)
+	}
+
+	return next(ctx, op)
+}
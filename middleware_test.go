@@ -0,0 +1,71 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWithMiddleware(t *testing.T) {
+	var calls []string
+	record := func(next Executor) Executor {
+		return func(ctx context.Context, op *Op) error {
+			calls = append(calls, op.Name)
+			return next(ctx, op)
+		}
+	}
+
+	errBlocked := errors.New("blocked")
+	tag := func(next Executor) Executor {
+		return func(ctx context.Context, op *Op) error {
+			if strings.Contains(op.Query, "blocked") {
+				return errBlocked
+			}
+
+			op.Query = "/* app */ " + op.Query
+			return next(ctx, op)
+		}
+	}
+
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMiddleware(record, tag),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	res, err := db.Exec(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if res.Query() != "/* app */ SELECT 1" {
+		t.Fatalf("expected the tagged query, got: %s", res.Query())
+	}
+
+	if err := db.QueryRow(ctx, "SELECT 'blocked'").Scan(ctx); err != errBlocked {
+		t.Fatalf("expected errBlocked, got: %v", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	rows.Close(ctx)
+
+	if strings.Join(calls, ",") != "Exec,QueryRow,Tx.Query" {
+		t.Fatalf("expected the calls of Exec, QueryRow and Tx.Query, got: %v", calls)
+	}
+}
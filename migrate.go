@@ -0,0 +1,146 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrInvalidMigrateInterval is returned by MigrateTo given a non-positive
+// policy interval.
+var ErrInvalidMigrateInterval = errors.New("migrate interval must be positive")
+
+// MigratePolicy configures the pace of a pool migration.
+type MigratePolicy struct {
+	// Interval is the duration waited between replacing two idle connections,
+	// it must be positive.
+	Interval time.Duration
+
+	// Progress, if set, is called every time a connection of the old backend
+	// is closed, with the number of closed connections and the number of
+	// connections the old backend had when the migration started.
+	Progress func(migrated, total int)
+}
+
+// MigrateTo migrates the pool to the connections created by the given factory,
+// while traffic continues. New connections are created with the new factory
+// immediately, idle connections of the old backend are replaced one by one
+// at each policy interval, and the ones in use are closed when they are put
+// back to the pool. Handles of the factory are limited to one open and one idle
+// connection, like the ones of NewWithDB.
+//
+// MigrateTo returns when all the connections of the old backend are closed, or
+// the given ctx is done. Cancelling the ctx does not revert the migration.
+func (db *DB) MigrateTo(ctx context.Context, f Factory, policy MigratePolicy) error {
+	if err := db.check(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	if db.conns == nil {
+		db.mu.Unlock()
+		return ErrClosed
+	}
+
+	if f == nil {
+		db.mu.Unlock()
+		return ErrNilFactory
+	}
+
+	if policy.Interval <= 0 {
+		db.mu.Unlock()
+		return ErrInvalidMigrateInterval
+	}
+
+	db.factory = singleConn(f)
+	db.gen++
+	total := len(db.infos)
+	db.mu.Unlock()
+
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	migrated := 0
+	for {
+		remaining := db.staleCount()
+		if remaining < total-migrated {
+			migrated = total - remaining
+			if policy.Progress != nil {
+				policy.Progress(migrated, total)
+			}
+		}
+
+		if remaining == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := db.replaceStale(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// staleCount returns the number of connections created by replaced factories
+func (db *DB) staleCount() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	count := 0
+	for _, info := range db.infos {
+		if info.gen != db.gen {
+			count++
+		}
+	}
+
+	return count
+}
+
+// replaceStale closes the first idle connection in the pool which is created
+// by a replaced factory, and puts a new one in its place
+func (db *DB) replaceStale(ctx context.Context) error {
+	conns := db.getConns()
+	if conns == nil {
+		return ErrClosed
+	}
+
+	for i := 0; i < cap(conns); i++ {
+		var conn *sql.DB
+		select {
+		case conn = <-conns:
+		default:
+			return nil
+		}
+
+		if conn == nil {
+			return ErrClosed
+		}
+
+		if !db.isStale(conn) {
+			if err := db.put(conn); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := db.closeConn(conn); err != nil {
+			return err
+		}
+
+		fresh, err := db.newConn(ctx)
+		if err != nil {
+			return err
+		}
+
+		return db.put(fresh)
+	}
+
+	return nil
+}
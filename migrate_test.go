@@ -0,0 +1,104 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMigrateTo(t *testing.T) {
+	db := getConn(t)
+	ctx := context.Background()
+
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("Err while pinging: %# v", err)
+	}
+
+	old, err := db.getFromPool(context.Background())
+	if err != nil {
+		t.Fatalf("Get error: %s", err)
+	}
+
+	// creates another idle connection in the pool
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("Err while pinging: %# v", err)
+	}
+
+	factory := func() (*sql.DB, error) {
+		return sql.Open(
+			os.Getenv("NISQL_TEST_DIALECT"),
+			os.Getenv("NISQL_TEST_DSN"),
+		)
+	}
+
+	var progress []int
+	policy := MigratePolicy{
+		Interval: time.Millisecond,
+		Progress: func(migrated, total int) {
+			progress = append(progress, migrated)
+		},
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- db.MigrateTo(ctx, factory, policy)
+	}()
+
+	// connection in use is closed when it is put back
+	time.Sleep(time.Millisecond * 10)
+	if err := db.put(old); err != nil {
+		t.Fatalf("Err while putting the connection: %# v", err)
+	}
+
+	if err := <-errc; err != nil {
+		t.Fatalf("Err while migrating: %# v", err)
+	}
+
+	if len(progress) == 0 || progress[len(progress)-1] != 2 {
+		t.Fatalf("expected 2 connections to be migrated, got: %+v", progress)
+	}
+
+	if db.staleCount() != 0 {
+		t.Fatalf("expected no stale connections, got: %d", db.staleCount())
+	}
+
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("Err while pinging: %# v", err)
+	}
+}
+
+func TestMigrateToClosed(t *testing.T) {
+	db := getConn(t)
+
+	if err := db.Close(context.Background()); err != nil {
+		t.Fatalf("Err while closing the connection: %# v", err)
+	}
+
+	if err := db.MigrateTo(context.Background(), nil, MigratePolicy{}); err != ErrClosed {
+		t.Fatalf("Err should be ErrClosed, got: %# v", err)
+	}
+}
+
+func TestMigrateToInvalid(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if err := db.MigrateTo(ctx, nil, MigratePolicy{Interval: time.Second}); err != ErrNilFactory {
+		t.Fatalf("expected ErrNilFactory, got: %v", err)
+	}
+
+	factory := func() (*sql.DB, error) {
+		return sql.Open(os.Getenv("NISQL_TEST_DIALECT"), os.Getenv("NISQL_TEST_DSN"))
+	}
+
+	if err := db.MigrateTo(ctx, factory, MigratePolicy{}); err != ErrInvalidMigrateInterval {
+		t.Fatalf("expected ErrInvalidMigrateInterval, got: %v", err)
+	}
+
+	if db.gen != 0 {
+		t.Fatalf("expected the factory not to be replaced, got generation: %d", db.gen)
+	}
+}
@@ -0,0 +1,101 @@
+package ctxdb
+
+import (
+	"context"
+	"time"
+)
+
+// minIdleInterval is the interval between two replenishments of the idle
+// connections
+const minIdleInterval = time.Second
+
+// SetMinIdleConns sets the minimum number of idle connections kept in the pool,
+// a background keeper replenishes the pool when it drops below n, so the
+// operations after a quiet period do not pay the cost of connecting. It can
+// not exceed the max idle conns. Connections in use are not counted as idle,
+// and the keeper does not exceed the max open conns. Zero disables the keeper.
+func (db *DB) SetMinIdleConns(n int) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.minIdleConns = n
+	if n > 0 && db.keeperStop == nil && db.conns != nil {
+		db.keeperStop = make(chan struct{})
+		go db.keep(db.keeperStop)
+	}
+
+	if n <= 0 {
+		// keeper can be started again by a later call
+		db.stopKeeperLocked()
+	}
+	db.mu.Unlock()
+}
+
+// stopKeeper stops the keeper if it is running
+func (db *DB) stopKeeper() {
+	db.mu.Lock()
+	db.stopKeeperLocked()
+	db.mu.Unlock()
+}
+
+// stopKeeperLocked stops the keeper if it is running, so it can be started
+// again. db.mu must be held.
+func (db *DB) stopKeeperLocked() {
+	if db.keeperStop != nil {
+		close(db.keeperStop)
+		db.keeperStop = nil
+	}
+}
+
+// keep replenishes the idle connections on every interval until stop is closed
+func (db *DB) keep(stop chan struct{}) {
+	ticker := time.NewTicker(minIdleInterval)
+	defer ticker.Stop()
+
+	for {
+		db.replenish()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// replenish creates connections until the pool has the min idle conns, a sem
+// is held while a connection is created, like any operation. Stops on the
+// first error, the failures of the factory are retried on the next interval.
+func (db *DB) replenish() {
+	for {
+		db.mu.Lock()
+		conns := db.conns
+		min := db.minIdleConns
+		if min > db.maxIdleConns {
+			min = db.maxIdleConns
+		}
+		db.mu.Unlock()
+
+		if conns == nil || len(conns) >= min {
+			return
+		}
+
+		if !db.tryAcquire() {
+			// all conns are in use
+			return
+		}
+
+		conn, err := db.newConn(context.Background())
+		if err == nil {
+			err = db.put(conn)
+		}
+
+		db.release()
+
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSetMinIdleConns(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(3),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	db.SetMinIdleConns(2)
+	time.Sleep(time.Millisecond * 100)
+
+	if n := len(db.getConns()); n != 2 {
+		t.Fatalf("expected 2 idle connections, got: %d", n)
+	}
+
+	// min idle conns can not exceed the max idle conns
+	db.SetMinIdleConns(5)
+	db.replenish()
+
+	if n := len(db.getConns()); n != 3 {
+		t.Fatalf("expected 3 idle connections, got: %d", n)
+	}
+}
+
+func TestSetMinIdleConnsRestart(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	db.SetMinIdleConns(1)
+	db.SetMinIdleConns(0)
+
+	db.mu.Lock()
+	stopped := db.keeperStop == nil
+	db.mu.Unlock()
+
+	if !stopped {
+		t.Fatal("expected the keeper to be stopped")
+	}
+
+	db.SetMinIdleConns(1)
+
+	db.mu.Lock()
+	started := db.keeperStop != nil
+	db.mu.Unlock()
+
+	if !started {
+		t.Fatal("expected the keeper to be started again")
+	}
+}
@@ -0,0 +1,197 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownQuery is returned by the named operations when the query name is
+// not registered.
+var ErrUnknownQuery = errors.New("unknown query")
+
+// sqlExt is the extension of the query files
+const sqlExt = ".sql"
+
+// Queries is a registry of queries by their names, typically loaded from .sql
+// files, e.g. queries/users/get_by_id.sql is registered as "users/get_by_id".
+// It's safe for concurrent use by multiple goroutines.
+type Queries struct {
+	mu      sync.RWMutex
+	queries map[string]string
+	reads   map[string][]string // names of the queries reading the tables
+}
+
+// NewQueries creates an empty query registry.
+func NewQueries() *Queries {
+	return &Queries{queries: make(map[string]string)}
+}
+
+// Add registers the query with the given name, replacing the previous one.
+func (q *Queries) Add(name, query string) {
+	q.mu.Lock()
+	q.queries[name] = strings.TrimSpace(query)
+	q.mu.Unlock()
+}
+
+// Get returns the query of the given name, ok is false if it is not
+// registered.
+func (q *Queries) Get(name string) (query string, ok bool) {
+	q.mu.RLock()
+	query, ok = q.queries[name]
+	q.mu.RUnlock()
+	return query, ok
+}
+
+// Names returns the sorted names of the registered queries.
+func (q *Queries) Names() []string {
+	q.mu.RLock()
+	names := make([]string, 0, len(q.queries))
+	for name := range q.queries {
+		names = append(names, name)
+	}
+	q.mu.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// LoadDir registers the .sql files under dir, named by their slash separated
+// paths relative to dir without the extension.
+func (q *Queries) LoadDir(dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() || filepath.Ext(path) != sqlExt {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		q.Add(queryName(filepath.ToSlash(rel)), string(data))
+		return nil
+	})
+}
+
+// queryName returns the name of the query file of the given slash separated
+// path
+func queryName(path string) string {
+	return strings.TrimSuffix(path, sqlExt)
+}
+
+// WithQueries sets the query registry used by the named operations.
+func WithQueries(q *Queries) Option {
+	return func(db *DB) {
+		db.queries = q
+	}
+}
+
+// named returns the registered query of the given name, and ctx labeled with
+// the name, unless it already has a label.
+func (db *DB) named(ctx context.Context, name string) (context.Context, string, error) {
+	if db.queries == nil {
+		return ctx, "", ErrUnknownQuery
+	}
+
+	query, ok := db.queries.Get(name)
+	if !ok {
+		return ctx, "", ErrUnknownQuery
+	}
+
+	if LabelFromContext(ctx) == "" {
+		ctx = WithLabel(ctx, name)
+	}
+
+	return ctx, query, nil
+}
+
+// Named executes the registered query of the given name which returns rows,
+// with Query. Operation is labeled with the name, unless ctx has a label.
+func (db *DB) Named(ctx context.Context, name string, args ...interface{}) (*Rows, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	ctx, query, err := db.named(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Query(ctx, query, args...)
+}
+
+// NamedExec executes the registered query of the given name without returning
+// any rows, with Exec. Operation is labeled with the name, unless ctx has a
+// label.
+func (db *DB) NamedExec(ctx context.Context, name string, args ...interface{}) (*Result, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	ctx, query, err := db.named(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Exec(ctx, query, args...)
+}
+
+// NamedRow executes the registered query of the given name which is expected
+// to return at most one row, with QueryRow. Operation is labeled with the name,
+// unless ctx has a label.
+func (db *DB) NamedRow(ctx context.Context, name string, args ...interface{}) *Row {
+	if err := db.check(); err != nil {
+		return &Row{id: nextOperationID(), err: err}
+	}
+
+	ctx, query, err := db.named(ctx, name)
+	if err != nil {
+		return &Row{id: nextOperationID(), err: err}
+	}
+
+	return db.QueryRow(ctx, query, args...)
+}
+
+// ValidateQueries prepares every registered query on the database, for
+// detecting the invalid ones at startup. Returns the first failure with the
+// name of its query.
+func (db *DB) ValidateQueries(ctx context.Context) error {
+	if err := db.check(); err != nil {
+		return err
+	}
+
+	if db.queries == nil {
+		return nil
+	}
+
+	for _, name := range db.queries.Names() {
+		query, _ := db.queries.Get(name)
+
+		stmt, err := db.Prepare(ctx, query)
+		if err != nil {
+			return fmt.Errorf("query %s: %s", name, err)
+		}
+
+		if err := stmt.Close(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
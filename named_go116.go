@@ -0,0 +1,41 @@
+// +build go1.16
+
+package ctxdb
+
+import (
+	"io/fs"
+	"path"
+)
+
+// LoadFS registers the .sql files under root of fsys, named by their paths
+// relative to root without the extension. It registers the queries embedded
+// with go:embed, e.g.
+//
+//     //go:embed queries
+//     var files embed.FS
+//
+//     err := queries.LoadFS(files, "queries")
+func (q *Queries) LoadFS(fsys fs.FS, root string) error {
+	return fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || path.Ext(p) != sqlExt {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+
+		rel := p
+		if root != "." {
+			rel = p[len(root)+1:]
+		}
+
+		q.Add(queryName(rel), string(data))
+		return nil
+	})
+}
@@ -0,0 +1,26 @@
+// +build go1.16
+
+package ctxdb
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestQueriesLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"queries/users/get_by_id.sql": {Data: []byte("SELECT * FROM users WHERE id = $1")},
+		"queries/ping.sql":            {Data: []byte("SELECT 1")},
+		"other.sql":                   {Data: []byte("SELECT 2")},
+	}
+
+	q := NewQueries()
+	if err := q.LoadFS(fsys, "queries"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	names := q.Names()
+	if len(names) != 2 || names[0] != "ping" || names[1] != "users/get_by_id" {
+		t.Fatalf("expected [ping users/get_by_id], got: %v", names)
+	}
+}
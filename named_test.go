@@ -0,0 +1,106 @@
+package ctxdb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueriesLoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ctxdb")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "users"), 0755); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	files := map[string]string{
+		"users/get_by_id.sql": "SELECT * FROM users WHERE id = $1\n",
+		"users/README.md":     "not a query",
+		"ping.sql":            "SELECT 1",
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	q := NewQueries()
+	if err := q.LoadDir(dir); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	names := q.Names()
+	if len(names) != 2 || names[0] != "ping" || names[1] != "users/get_by_id" {
+		t.Fatalf("expected [ping users/get_by_id], got: %v", names)
+	}
+
+	query, ok := q.Get("users/get_by_id")
+	if !ok || query != "SELECT * FROM users WHERE id = $1" {
+		t.Fatalf("expected the query of users/get_by_id, got: %q", query)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	q := NewQueries()
+	q.Add("nullable/insert", insertSQLStatement)
+	q.Add("nullable/count", "SELECT COUNT(*) FROM nullable")
+
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithQueries(q),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	if err := db.ValidateQueries(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := db.NamedExec(ctx, "nullable/insert", 42, nil, 12); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var count int64
+	if err := db.NamedRow(ctx, "nullable/count").Scan(ctx, &count); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected 1, got: %d", count)
+	}
+
+	rows, err := db.Named(ctx, "nullable/count")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := db.Named(ctx, "nullable/unknown"); err != ErrUnknownQuery {
+		t.Fatalf("expected ErrUnknownQuery, got: %v", err)
+	}
+
+	q.Add("nullable/invalid", "SELEC 1")
+	if err := db.ValidateQueries(ctx); err == nil {
+		t.Fatal("expected an error for nullable/invalid, got nil")
+	}
+}
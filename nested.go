@@ -0,0 +1,121 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Begin starts a nested transaction backed by a savepoint of tx, so the code
+// which needs a transaction composes with the callers which already opened
+// one. Commit of the nested transaction releases the savepoint and Rollback
+// rolls back to it, the enclosing transaction is committed or rolled back as
+// usual. Nested transactions can be nested, and must not be used
+// concurrently with their parents.
+func (tx *Tx) Begin(ctx context.Context) (*Tx, error) {
+	if err := tx.check(); err != nil {
+		return nil, err
+	}
+
+	nested := &Tx{
+		id:        tx.id,
+		tx:        tx.tx,
+		sqldb:     tx.sqldb,
+		db:        tx.db,
+		journal:   tx.journal,
+		stats:     tx.stats,
+		attrs:     tx.attrs,
+		spanCtx:   tx.spanCtx,
+		parent:    tx,
+		idle:      tx.idle,
+		savepoint: fmt.Sprintf("ctxdb_nested_%d", nextOperationID()),
+	}
+
+	if _, err := tx.exec(ctx, "SAVEPOINT "+nested.savepoint); err != nil {
+		return nil, err
+	}
+
+	return nested, nil
+}
+
+// endSavepoint releases or rolls back to the savepoint of the nested
+// transaction, unless the transactions sharing its connection are aborted.
+func (tx *Tx) endSavepoint(ctx context.Context, committed bool) error {
+	tx.Lock()
+	finished, stickyErr := tx.finished, tx.sticky()
+	tx.Unlock()
+
+	op := "Tx.Rollback"
+	if committed {
+		op = "Tx.Commit"
+	}
+
+	if finished {
+		if !committed {
+			// deferred Rollback after Commit
+			return sql.ErrTxDone
+		}
+
+		if err := tx.misuseTxDone(op); err != nil {
+			return err
+		}
+
+		return sql.ErrTxDone
+	}
+
+	if stickyErr != nil {
+		tx.Lock()
+		tx.finished = true
+		tx.Unlock()
+
+		return stickyErr
+	}
+
+	var err error
+	if committed {
+		_, err = tx.exec(ctx, "RELEASE SAVEPOINT "+tx.savepoint)
+	} else if _, err = tx.exec(ctx, "ROLLBACK TO SAVEPOINT "+tx.savepoint); err == nil {
+		_, err = tx.exec(ctx, "RELEASE SAVEPOINT "+tx.savepoint)
+	}
+
+	tx.Lock()
+	tx.finished = true
+	tx.committed = committed && err == nil
+	tx.Unlock()
+
+	return err
+}
+
+// fail rolls back the transaction after a timed out or canceled operation,
+// recycling its connection, and returns its sticky error. Sticky error is set
+// on the enclosing transactions too, since they share the connection. Caller
+// holds the lock of tx.
+func (tx *Tx) fail(err error) error {
+	tx.stickyErr = err
+	if shutdownErr := tx.shutdown(); shutdownErr != nil {
+		tx.stickyErr = shutdownErr
+	}
+
+	for p := tx.parent; p != nil; p = p.parent {
+		p.Lock()
+		if p.stickyErr == nil {
+			p.stickyErr = tx.stickyErr
+		}
+		p.Unlock()
+	}
+
+	return tx.stickyErr
+}
+
+// sticky returns the sticky error of the transaction, inheriting the one of an
+// enclosing transaction, since they share the connection. Caller holds the
+// lock of tx.
+func (tx *Tx) sticky() error {
+	for p := tx.parent; tx.stickyErr == nil && p != nil; p = p.parent {
+		p.Lock()
+		tx.stickyErr = p.stickyErr
+		p.Unlock()
+	}
+
+	return tx.stickyErr
+}
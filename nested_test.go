@@ -0,0 +1,130 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNestedTx(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS nested_txs (id int)",
+		"TRUNCATE nested_txs",
+	} {
+		if _, err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "INSERT INTO nested_txs VALUES (1)"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// rolled back nested transaction keeps the statements of its parent
+	nested, err := tx.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := nested.Exec(ctx, "INSERT INTO nested_txs VALUES (2)"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := nested.Exec(ctx, "SELECT 1/0"); err == nil {
+		t.Fatalf("expected division by zero")
+	}
+
+	if err := nested.Rollback(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// committed nested transaction, with a nested transaction of its own
+	nested, err = tx.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	inner, err := nested.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := inner.Exec(ctx, "INSERT INTO nested_txs VALUES (3)"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := inner.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := nested.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := nested.Rollback(ctx); err == nil {
+		t.Fatalf("expected an error for Rollback after Commit")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var sum int
+	if err := db.QueryRow(ctx, "SELECT sum(id) FROM nested_txs").Scan(ctx, &sum); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if sum != 4 {
+		t.Fatalf("expected the rows 1 and 3, got sum: %d", sum)
+	}
+}
+
+func TestNestedTxTimeout(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	nested, err := tx.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer cancel()
+
+	if _, err := nested.Exec(timeoutCtx, "SELECT pg_sleep(1)"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	// connection is recycled once, parent does not use it anymore
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != context.DeadlineExceeded {
+		t.Fatalf("expected the sticky error of the nested transaction, got: %v", err)
+	}
+
+	if err := tx.Rollback(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	if err := nested.Rollback(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	if s := db.PoolStats(); s.InUse != 0 || s.Idle > 1 {
+		t.Fatalf("expected the connection to be recycled once, got: %+v", s)
+	}
+}
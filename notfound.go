@@ -0,0 +1,48 @@
+package ctxdb
+
+import "database/sql"
+
+// ErrNotFound is returned by Row.Scan instead of sql.ErrNoRows, if the DB is
+// opened with WithErrNotFound. errors.Is reports it as sql.ErrNoRows as well.
+var ErrNotFound error = notFoundError{}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "not found" }
+
+// Is reports ErrNotFound as sql.ErrNoRows for errors.Is.
+func (notFoundError) Is(target error) bool {
+	return target == sql.ErrNoRows
+}
+
+// WithErrNotFound makes Row.Scan return ErrNotFound instead of sql.ErrNoRows.
+func WithErrNotFound() Option {
+	return func(db *DB) {
+		db.errNotFound = true
+	}
+}
+
+// Found maps the error of a Row.Scan to a found flag; it returns false and nil
+// if no rows are found, true and nil if err is nil, false and err otherwise.
+//
+//     found, err := ctxdb.Found(db.QueryRow(ctx, query, id).Scan(ctx, &name))
+func Found(err error) (bool, error) {
+	switch err {
+	case nil:
+		return true, nil
+	case sql.ErrNoRows, ErrNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// mapNoRows maps sql.ErrNoRows to ErrNotFound if the db is opened with
+// WithErrNotFound
+func (db *DB) mapNoRows(err error) error {
+	if err == sql.ErrNoRows && db.errNotFound {
+		return ErrNotFound
+	}
+
+	return err
+}
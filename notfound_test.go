@@ -0,0 +1,54 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestFound(t *testing.T) {
+	errOther := errors.New("other")
+
+	tests := []struct {
+		err   error
+		found bool
+		res   error
+	}{
+		{nil, true, nil},
+		{sql.ErrNoRows, false, nil},
+		{ErrNotFound, false, nil},
+		{errOther, false, errOther},
+	}
+
+	for _, test := range tests {
+		found, err := Found(test.err)
+		if found != test.found || err != test.res {
+			t.Fatalf("expected %t and %v for %v, got: %t and %v", test.found, test.res, test.err, found, err)
+		}
+	}
+
+	if !errors.Is(ErrNotFound, sql.ErrNoRows) {
+		t.Fatal("expected ErrNotFound to be sql.ErrNoRows")
+	}
+}
+
+func TestWithErrNotFound(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithErrNotFound(),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	var i int
+	if err := db.QueryRow(ctx, "SELECT 1 WHERE false").Scan(ctx, &i); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+)
+
+// maxNotifyPayload is the max size of a NOTIFY payload in bytes, in the default
+// configuration of postgres
+const maxNotifyPayload = 7999
+
+var (
+	// ErrPayloadTooLarge is returned by Notify if the payload exceeds the
+	// limit of postgres.
+	ErrPayloadTooLarge = errors.New("notify payload too large")
+
+	// ErrEmptyChannel is returned by Notify if the channel is empty.
+	ErrEmptyChannel = errors.New("notify channel is empty")
+)
+
+// Notify sends a notification with the given payload on the channel, with
+// postgres NOTIFY. It is sent immediately, Tx.Notify sends it on commit.
+func (db *DB) Notify(ctx context.Context, channel, payload string) error {
+	if err := validateNotify(channel, payload); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(ctx, notifySQLStatement, channel, payload)
+	return err
+}
+
+// Notify queues a notification with the given payload on the channel, with
+// postgres NOTIFY. It is sent when the transaction is committed, and dropped if
+// the transaction is rolled back.
+func (tx *Tx) Notify(ctx context.Context, channel, payload string) error {
+	if err := validateNotify(channel, payload); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, notifySQLStatement, channel, payload)
+	return err
+}
+
+// validateNotify validates the channel and the payload of a notification
+func validateNotify(channel, payload string) error {
+	if channel == "" {
+		return ErrEmptyChannel
+	}
+
+	if len(payload) > maxNotifyPayload {
+		return ErrPayloadTooLarge
+	}
+
+	return nil
+}
+
+// notifySQLStatement uses pg_notify, since NOTIFY does not accept parameters
+const notifySQLStatement = `SELECT pg_notify($1, $2)`
@@ -0,0 +1,47 @@
+package ctxdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNotify(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	if err := db.Notify(ctx, "ctxdb_test", "hello"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := db.Notify(ctx, "", "hello"); err != ErrEmptyChannel {
+		t.Fatalf("expected ErrEmptyChannel, got: %v", err)
+	}
+
+	payload := strings.Repeat("a", maxNotifyPayload+1)
+	if err := db.Notify(ctx, "ctxdb_test", payload); err != ErrPayloadTooLarge {
+		t.Fatalf("expected ErrPayloadTooLarge, got: %v", err)
+	}
+}
+
+func TestTxNotify(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := tx.Notify(ctx, "ctxdb_test", "hello"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
@@ -0,0 +1,20 @@
+package ctxdb
+
+import "context"
+
+// WithContextObserver calls f with the ctx and the name of every operation of
+// the DB and its transactions, e.g. "Exec" or "Tx.Commit", before it runs. It
+// is meant for verifying the propagation of the contexts in tests, see
+// ctxdbtest.Propagation, f should be fast.
+func WithContextObserver(f func(ctx context.Context, op string)) Option {
+	return func(db *DB) {
+		db.observer = f
+	}
+}
+
+// observe passes the ctx of the operation to the observer if it is set
+func (db *DB) observe(ctx context.Context, op string) {
+	if db.observer != nil {
+		db.observer(ctx, op)
+	}
+}
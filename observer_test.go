@@ -0,0 +1,52 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestWithContextObserver(t *testing.T) {
+	var ops []string
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithContextObserver(func(ctx context.Context, op string) {
+			ops = append(ops, op)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	if err := db.Ping(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	expected := []string{"Ping", "Begin", "Tx.Exec", "Tx.Commit"}
+	if len(ops) != len(expected) {
+		t.Fatalf("expected %v, got: %v", expected, ops)
+	}
+
+	for i, op := range expected {
+		if ops[i] != op {
+			t.Fatalf("expected %v, got: %v", expected, ops)
+		}
+	}
+}
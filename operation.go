@@ -0,0 +1,60 @@
+package ctxdb
+
+import "sync/atomic"
+
+// lastOperationID is the last ID assigned to a top-level operation
+var lastOperationID uint64
+
+// nextOperationID returns a new ID for a top-level operation, ID of the
+// operation is propagated to the resources derived from it, so logs and traces
+// can stitch the full lifecycle of an operation.
+func nextOperationID() uint64 {
+	return atomic.AddUint64(&lastOperationID, 1)
+}
+
+// OperationID returns the ID of the operation which created the result.
+func (r *Result) OperationID() uint64 {
+	if r == nil {
+		return 0
+	}
+
+	return r.id
+}
+
+// OperationID returns the ID of the QueryRow operation which created the row.
+func (r *Row) OperationID() uint64 {
+	if r == nil {
+		return 0
+	}
+
+	return r.id
+}
+
+// OperationID returns the ID of the Query operation which created the rows.
+func (rs *Rows) OperationID() uint64 {
+	if rs == nil {
+		return 0
+	}
+
+	return rs.id
+}
+
+// OperationID returns the ID of the Prepare operation which created the
+// statement. Executions of the statement carry the same ID.
+func (s *Stmt) OperationID() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return s.id
+}
+
+// OperationID returns the ID of the Begin operation which created the
+// transaction. Statements executed within the transaction carry the same ID.
+func (tx *Tx) OperationID() uint64 {
+	if tx == nil {
+		return 0
+	}
+
+	return tx.id
+}
@@ -0,0 +1,57 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOperationID(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	stmt, err := db.Prepare(ctx, "SELECT int64_val FROM nullable")
+	if err != nil {
+		t.Fatalf("Err while preparing: %# v", err)
+	}
+
+	if stmt.OperationID() == 0 {
+		t.Fatalf("expected operation ID to be set")
+	}
+
+	rows, err := stmt.Query(ctx)
+	if err != nil {
+		t.Fatalf("Err while querying: %# v", err)
+	}
+
+	if rows.OperationID() != stmt.OperationID() {
+		t.Fatalf("expected %d, got: %d", stmt.OperationID(), rows.OperationID())
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	row := db.QueryRow(ctx, "SELECT int64_val FROM nullable")
+	if row.OperationID() == stmt.OperationID() {
+		t.Fatalf("expected a new operation ID, got: %d", row.OperationID())
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("err while beginning the transaction: %s", err)
+	}
+
+	res, err := tx.Exec(ctx, deleteSQLStatement)
+	if err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	if res.OperationID() != tx.OperationID() {
+		t.Fatalf("expected %d, got: %d", tx.OperationID(), res.OperationID())
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("err while rolling back the tx: err : %s", err)
+	}
+}
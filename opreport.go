@@ -0,0 +1,36 @@
+package ctxdb
+
+import "time"
+
+// OpReport holds the outcome of an operation, passed to the report func set
+// with WithOpReport.
+type OpReport struct {
+	Op       string        // name of the operation, e.g. "Exec", or the transaction template
+	Duration time.Duration // from the start of the operation to its result
+	Err      error
+}
+
+// WithOpReport calls f with the report of every Exec, Query, QueryRow, Ping
+// and ExecTemplate after its result is known, for metrics, e.g. latency
+// histograms and error counters. Reports of QueryRow are sent after Row.Scan,
+// Query is reported when its rows are returned. f is called synchronously, it
+// should be fast.
+func WithOpReport(f func(r *OpReport)) Option {
+	return func(db *DB) {
+		db.opReport = f
+	}
+}
+
+// reportOp reports the result of the operation started at start, if the report
+// is enabled
+func (db *DB) reportOp(op string, start time.Time, err error) {
+	if db.opReport == nil {
+		return
+	}
+
+	db.opReport(&OpReport{
+		Op:       op,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+}
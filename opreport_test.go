@@ -0,0 +1,44 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestWithOpReport(t *testing.T) {
+	var reports []OpReport
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithOpReport(func(r *OpReport) {
+			reports = append(reports, *r)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var n int
+	if err := db.QueryRow(ctx, "SELECT 1 WHERE false").Scan(ctx, &n); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got: %+v", reports)
+	}
+
+	if reports[0].Op != "Exec" || reports[0].Err != nil || reports[0].Duration <= 0 {
+		t.Fatalf("expected the report of Exec, got: %+v", reports[0])
+	}
+
+	if reports[1].Op != "QueryRow" || reports[1].Err == nil {
+		t.Fatalf("expected the report of the failed QueryRow, got: %+v", reports[1])
+	}
+}
@@ -0,0 +1,44 @@
+package ctxdb
+
+import "time"
+
+// Option configures a DB created by Open.
+type Option func(db *DB)
+
+// WithMaxOpenConns sets the maximum number of open connections of the pool,
+// operations wait for a connection when the limit is reached. n must be
+// positive.
+func WithMaxOpenConns(n int) Option {
+	return func(db *DB) {
+		db.maxOpenConns = n
+	}
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections kept in the
+// pool, connections put back to a full pool are closed. It defaults to, and
+// can not exceed the max open conns. Zero means no idle connections are kept.
+func WithMaxIdleConns(n int) Option {
+	return func(db *DB) {
+		db.maxIdleConns = n
+	}
+}
+
+// WithNoWait disables waiting for a connection when all of them are in use,
+// operations fail fast with ErrPoolExhausted instead, so load shedding can
+// kick in upstream. Use WithNoWaitContext for failing fast per operation
+// instead.
+func WithNoWait() Option {
+	return func(db *DB) {
+		db.noWait = true
+	}
+}
+
+// WithAcquireTimeout limits the time an operation waits for a connection when
+// all of them are in use, independent of its ctx deadline, so the operation
+// does not spend all of its budget in the queue. Operations fail with
+// ErrPoolExhausted when it is exceeded.
+func WithAcquireTimeout(d time.Duration) Option {
+	return func(db *DB) {
+		db.acquireTimeout = d
+	}
+}
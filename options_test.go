@@ -0,0 +1,146 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestOpenWithOptions(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(5),
+		WithMaxIdleConns(3),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	if cap(db.sem) != 5 || len(db.sem) != 5 {
+		t.Fatalf("expected 5 sems, got: %d/%d", len(db.sem), cap(db.sem))
+	}
+
+	if cap(db.conns) != 3 {
+		t.Fatalf("expected 3 idle conns, got: %d", cap(db.conns))
+	}
+}
+
+func TestSetMaxIdleConns(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if err := db.Prefill(ctx, maxOpenConns); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	db.SetMaxIdleConns(1)
+	if len(db.conns) != 1 {
+		t.Fatalf("expected 1 idle conn, got: %d", len(db.conns))
+	}
+
+	// pool is not resized
+	db.SetMaxIdleConns(maxOpenConns + 1)
+	if db.maxIdleConns != maxOpenConns {
+		t.Fatalf("expected %d max idle conns, got: %d", maxOpenConns, db.maxIdleConns)
+	}
+}
+
+func TestOpenWithInvalidOptions(t *testing.T) {
+	_, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(0),
+	)
+	if err != ErrInvalidPoolSize {
+		t.Fatalf("expected ErrInvalidPoolSize, got: %v", err)
+	}
+
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+		WithMaxIdleConns(10),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	// idle conns can not exceed the open conns
+	if cap(db.conns) != 1 {
+		t.Fatalf("expected 1 idle conn, got: %d", cap(db.conns))
+	}
+}
+
+func TestWithNoWait(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	// exhaust the pool
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := db.Ping(WithNoWaitContext(ctx)); err != ErrPoolExhausted {
+		t.Fatalf("expected ErrPoolExhausted, got: %v", err)
+	}
+
+	WithNoWait()(db)
+	if _, err := db.Exec(ctx, "SELECT 1"); err != ErrPoolExhausted {
+		t.Fatalf("expected ErrPoolExhausted, got: %v", err)
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
+
+func TestWithAcquireTimeout(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+		WithAcquireTimeout(time.Millisecond*20),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// exhaust the pool
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer rows.Close(ctx)
+
+	start := time.Now()
+	if err := db.Ping(ctx); err != ErrPoolExhausted {
+		t.Fatalf("expected ErrPoolExhausted, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Fatalf("expected to wait about 20ms, waited: %s", elapsed)
+	}
+}
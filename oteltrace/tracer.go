@@ -0,0 +1,82 @@
+// Package oteltrace implements ctxdb.Tracer with OpenTelemetry, starting a
+// client span for every operation of a ctxdb.DB, and for every transaction
+// with the spans of its operations as children.
+package oteltrace
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/cihangir/ctxdb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultMaxStatementLen is the max length of the recorded statements, if the
+// tracer does not set one
+const defaultMaxStatementLen = 1024
+
+// Tracer starts the spans of the ctxdb operations with an OpenTelemetry
+// tracer. Usage:
+//
+//     db, err := ctxdb.Open(driver, dsn, oteltrace.New(tracer).Option())
+//
+// Spans are named after the operations, e.g. "ctxdb.Exec", and record the
+// truncated statement as db.statement, the affected rows as db.rows_affected
+// and the error status.
+type Tracer struct {
+	tracer trace.Tracer
+
+	// MaxStatementLen is the max length of the recorded statements in bytes,
+	// longer statements are truncated.
+	MaxStatementLen int
+}
+
+// New creates a Tracer starting the spans with the given tracer.
+func New(t trace.Tracer) *Tracer {
+	return &Tracer{tracer: t, MaxStatementLen: defaultMaxStatementLen}
+}
+
+// Option returns the ctxdb option tracing the operations of a DB.
+func (t *Tracer) Option() ctxdb.Option {
+	return ctxdb.WithTracer(t)
+}
+
+// Start implements ctxdb.Tracer.
+func (t *Tracer) Start(ctx, parent context.Context, op, query string) (context.Context, func(int64, error)) {
+	if parent != nil {
+		ctx = trace.ContextWithSpan(ctx, trace.SpanFromContext(parent))
+	}
+
+	ctx, span := t.tracer.Start(ctx, "ctxdb."+op, trace.WithSpanKind(trace.SpanKindClient))
+	if query != "" {
+		span.SetAttributes(attribute.String("db.statement", truncate(query, t.MaxStatementLen)))
+	}
+
+	return ctx, func(rowsAffected int64, err error) {
+		if rowsAffected >= 0 {
+			span.SetAttributes(attribute.Int64("db.rows_affected", rowsAffected))
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
+
+// truncate returns the first n bytes of s, without splitting a rune
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
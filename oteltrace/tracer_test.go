@@ -0,0 +1,80 @@
+package oteltrace
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/cihangir/ctxdb"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	db, err := ctxdb.Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		New(provider.Tracer("ctxdb")).Option(),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT invalid"); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	tx.Rollback(ctx)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got: %d", len(spans))
+	}
+
+	exec, failed, txSpan := spans[0], spans[1], spans[2]
+	if exec.Name() != "ctxdb.Tx.Exec" || txSpan.Name() != "ctxdb.Tx" {
+		t.Fatalf("expected the spans of Tx.Exec and Tx, got: %s, %s", exec.Name(), txSpan.Name())
+	}
+
+	if exec.Parent().SpanID() != txSpan.SpanContext().SpanID() {
+		t.Fatal("expected the span of Tx.Exec under the span of the transaction")
+	}
+
+	if failed.Status().Code != codes.Error {
+		t.Fatalf("expected the error status, got: %v", failed.Status())
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"SELECT 1", 0, "SELECT 1"},
+		{"SELECT 1", 20, "SELECT 1"},
+		{"SELECT 1", 6, "SELECT"},
+		{"SELECT 'ü'", 9, "SELECT '"},
+	}
+
+	for _, test := range tests {
+		if got := truncate(test.s, test.n); got != test.want {
+			t.Errorf("expected %q, got: %q", test.want, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ctxdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PartitionTable is the placeholder of the routed table name in the queries
+// of PartitionRouter.Exec.
+const PartitionTable = "{table}"
+
+// PartitionRouter routes the statements of a partitioned table directly to the
+// partition of their key, avoiding the trigger/constraint overhead of going
+// through the parent table. Statements of unknown partitions are routed to the
+// parent table.
+type PartitionRouter struct {
+	table    string
+	template string
+
+	mu    sync.RWMutex
+	known map[string]struct{}
+}
+
+// NewPartitionRouter creates a router for the given parent table. Template is
+// formatted with the parent table and the partition key for building the
+// partition names, e.g. "%s_%s" routes key "2015_10" of table "events" to
+// "events_2015_10".
+func NewPartitionRouter(table, template string) *PartitionRouter {
+	return &PartitionRouter{
+		table:    table,
+		template: template,
+		known:    make(map[string]struct{}),
+	}
+}
+
+// Add registers the given partitions as known.
+func (r *PartitionRouter) Add(partitions ...string) {
+	r.mu.Lock()
+	for _, partition := range partitions {
+		r.known[partition] = struct{}{}
+	}
+	r.mu.Unlock()
+}
+
+// Refresh replaces the known partitions with the child tables of the parent
+// table, read from the postgres catalog.
+func (r *PartitionRouter) Refresh(ctx context.Context, db *DB) error {
+	rows, err := db.Query(ctx, partitionsSQLStatement, r.table)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]struct{})
+	err = rows.Reduce(ctx, func(scan ScanFunc) error {
+		var partition string
+		if err := scan(&partition); err != nil {
+			return err
+		}
+
+		known[partition] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.known = known
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Route returns the partition of the given key, or the parent table if the
+// partition is not known.
+func (r *PartitionRouter) Route(key string) string {
+	partition := fmt.Sprintf(r.template, r.table, key)
+
+	r.mu.RLock()
+	_, ok := r.known[partition]
+	r.mu.RUnlock()
+
+	if !ok {
+		return r.table
+	}
+
+	return partition
+}
+
+// Exec executes the given query on the partition of the given key. Every
+// PartitionTable placeholder in query is replaced with the routed table name,
+// e.g. "INSERT INTO {table} VALUES ($1, $2)", the rest of the query is left
+// as is, so it may contain literals like '%'.
+func (r *PartitionRouter) Exec(ctx context.Context, db *DB, key, query string, args ...interface{}) (*Result, error) {
+	return db.Exec(ctx, r.bind(key, query), args...)
+}
+
+// bind replaces the table placeholders of query with the partition of key
+func (r *PartitionRouter) bind(key, query string) string {
+	return strings.Replace(query, PartitionTable, r.Route(key), -1)
+}
+
+const partitionsSQLStatement = `SELECT child.relname FROM pg_inherits
+    JOIN pg_class parent ON pg_inherits.inhparent = parent.oid
+    JOIN pg_class child ON pg_inherits.inhrelid = child.oid
+    WHERE parent.relname = $1`
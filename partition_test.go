@@ -0,0 +1,61 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPartitionRouterRoute(t *testing.T) {
+	r := NewPartitionRouter("events", "%s_%s")
+
+	if table := r.Route("2015_10"); table != "events" {
+		t.Fatalf("expected events, got: %s", table)
+	}
+
+	r.Add("events_2015_10")
+
+	if table := r.Route("2015_10"); table != "events_2015_10" {
+		t.Fatalf("expected events_2015_10, got: %s", table)
+	}
+
+	if table := r.Route("2015_11"); table != "events" {
+		t.Fatalf("expected events, got: %s", table)
+	}
+}
+
+func TestPartitionRouterBind(t *testing.T) {
+	r := NewPartitionRouter("events", "%s_%s")
+	r.Add("events_2015_10")
+
+	query := r.bind("2015_10", "SELECT * FROM {table} WHERE name LIKE '%s%'")
+	if query != "SELECT * FROM events_2015_10 WHERE name LIKE '%s%'" {
+		t.Fatalf("unexpected query: %s", query)
+	}
+}
+
+func TestPartitionRouterRefresh(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "CREATE TABLE IF NOT EXISTS nullable_42 () INHERITS (nullable)"); err != nil {
+		t.Fatalf("err while creating the partition: %s", err)
+	}
+
+	r := NewPartitionRouter("nullable", "%s_%s")
+	if err := r.Refresh(ctx, db); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := r.Exec(ctx, db, "42", "DELETE FROM {table}"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if table := r.Route("42"); table != "nullable_42" {
+		t.Fatalf("expected nullable_42, got: %s", table)
+	}
+
+	if _, err := db.Exec(ctx, "DROP TABLE nullable_42"); err != nil {
+		t.Fatalf("err while dropping the partition: %s", err)
+	}
+}
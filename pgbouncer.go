@@ -0,0 +1,215 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidPollInterval is returned by PollPgBouncer given a non-positive
+// interval.
+var ErrInvalidPollInterval = errors.New("poll interval must be positive")
+
+// PgBouncerPool is a row of the pgbouncer SHOW POOLS output.
+type PgBouncerPool struct {
+	Database      string
+	User          string
+	ClientActive  int64
+	ClientWaiting int64
+	ServerActive  int64
+	ServerIdle    int64
+	ServerUsed    int64
+	ServerTested  int64
+	ServerLogin   int64
+	MaxWait       int64 // in seconds
+	PoolMode      string
+}
+
+// PgBouncerStats is a row of the pgbouncer SHOW STATS output. Times are in
+// microseconds.
+type PgBouncerStats struct {
+	Database        string
+	TotalXactCount  int64
+	TotalQueryCount int64
+	TotalReceived   int64
+	TotalSent       int64
+	TotalXactTime   int64
+	TotalQueryTime  int64
+	TotalWaitTime   int64
+	AvgXactCount    int64
+	AvgQueryCount   int64
+	AvgReceived     int64
+	AvgSent         int64
+	AvgXactTime     int64
+	AvgQueryTime    int64
+	AvgWaitTime     int64
+}
+
+// PgBouncerReport holds the pool and stats outputs of pgbouncer.
+type PgBouncerReport struct {
+	Pools []PgBouncerPool
+	Stats []PgBouncerStats
+}
+
+// PgBouncerPools runs SHOW POOLS over the given pgbouncer admin connection.
+// Columns which are not known are skipped, since they differ between the
+// pgbouncer versions.
+func PgBouncerPools(ctx context.Context, admin *DB) ([]PgBouncerPool, error) {
+	var pools []PgBouncerPool
+	err := showColumns(ctx, admin, "SHOW POOLS", func() map[string]interface{} {
+		pools = append(pools, PgBouncerPool{})
+		p := &pools[len(pools)-1]
+		return map[string]interface{}{
+			"database":   &p.Database,
+			"user":       &p.User,
+			"cl_active":  &p.ClientActive,
+			"cl_waiting": &p.ClientWaiting,
+			"sv_active":  &p.ServerActive,
+			"sv_idle":    &p.ServerIdle,
+			"sv_used":    &p.ServerUsed,
+			"sv_tested":  &p.ServerTested,
+			"sv_login":   &p.ServerLogin,
+			"maxwait":    &p.MaxWait,
+			"pool_mode":  &p.PoolMode,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pools, nil
+}
+
+// PgBouncerStatistics runs SHOW STATS over the given pgbouncer admin
+// connection. Columns which are not known are skipped, since they differ
+// between the pgbouncer versions.
+func PgBouncerStatistics(ctx context.Context, admin *DB) ([]PgBouncerStats, error) {
+	var stats []PgBouncerStats
+	err := showColumns(ctx, admin, "SHOW STATS", func() map[string]interface{} {
+		stats = append(stats, PgBouncerStats{})
+		s := &stats[len(stats)-1]
+		return map[string]interface{}{
+			"database":          &s.Database,
+			"total_xact_count":  &s.TotalXactCount,
+			"total_query_count": &s.TotalQueryCount,
+			"total_received":    &s.TotalReceived,
+			"total_sent":        &s.TotalSent,
+			"total_xact_time":   &s.TotalXactTime,
+			"total_query_time":  &s.TotalQueryTime,
+			"total_wait_time":   &s.TotalWaitTime,
+			"avg_xact_count":    &s.AvgXactCount,
+			"avg_query_count":   &s.AvgQueryCount,
+			"avg_recv":          &s.AvgReceived,
+			"avg_sent":          &s.AvgSent,
+			"avg_xact_time":     &s.AvgXactTime,
+			"avg_query_time":    &s.AvgQueryTime,
+			"avg_wait_time":     &s.AvgWaitTime,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// PollPgBouncer polls SHOW POOLS and SHOW STATS over the given pgbouncer admin
+// connection at every interval, and calls f with the report, until the given
+// ctx is done, then returns the ctx error. Every poll uses a ctx with the
+// interval as timeout. Returns ErrInvalidPollInterval without polling if the
+// interval is not positive.
+func PollPgBouncer(ctx context.Context, admin *DB, interval time.Duration, f func(*PgBouncerReport, error)) error {
+	if interval <= 0 {
+		return ErrInvalidPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		pollCtx, cancel := context.WithTimeout(ctx, interval)
+		f(pollPgBouncer(pollCtx, admin))
+		cancel()
+	}
+}
+
+func pollPgBouncer(ctx context.Context, admin *DB) (*PgBouncerReport, error) {
+	pools, err := PgBouncerPools(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := PgBouncerStatistics(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PgBouncerReport{Pools: pools, Stats: stats}, nil
+}
+
+// showColumns runs the given admin console command, and assigns the known
+// columns of every row into the destinations returned from the next func,
+// which is called once per row.
+func showColumns(ctx context.Context, admin *DB, command string, next func() map[string]interface{}) error {
+	rows, err := admin.Query(ctx, command)
+	if err != nil {
+		return err
+	}
+
+	columns, err := rows.Columns(ctx)
+	if err != nil {
+		rows.drop(ctx)
+		return err
+	}
+
+	return rows.Reduce(ctx, func(scan ScanFunc) error {
+		values := make([]sql.RawBytes, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := scan(dest...); err != nil {
+			return err
+		}
+
+		fields := next()
+		for i, column := range columns {
+			if err := assignColumn(fields[column], values[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// assignColumn assigns the raw value into the given string or int64 dest, nil
+// dest is skipped.
+func assignColumn(dest interface{}, raw sql.RawBytes) error {
+	switch d := dest.(type) {
+	case *string:
+		*d = string(raw)
+	case *int64:
+		if raw == nil {
+			return nil
+		}
+
+		i, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil {
+			return err
+		}
+
+		*d = i
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestAssignColumn(t *testing.T) {
+	var s string
+	if err := assignColumn(&s, sql.RawBytes("transaction")); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if s != "transaction" {
+		t.Fatalf("expected transaction, got: %s", s)
+	}
+
+	var i int64
+	if err := assignColumn(&i, sql.RawBytes("42")); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if i != 42 {
+		t.Fatalf("expected 42, got: %d", i)
+	}
+
+	if err := assignColumn(&i, sql.RawBytes("NaN")); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	// unknown columns are skipped
+	if err := assignColumn(nil, sql.RawBytes("42")); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
+
+func TestPollPgBouncerInvalidInterval(t *testing.T) {
+	err := PollPgBouncer(context.Background(), nil, 0, func(*PgBouncerReport, error) {
+		t.Fatal("expected no poll")
+	})
+	if err != ErrInvalidPollInterval {
+		t.Fatalf("expected ErrInvalidPollInterval, got: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// PlanMode is how the statements of a label are sent to the database, which
+// controls the plans chosen by the planner of postgres.
+type PlanMode int
+
+const (
+	// PlanDefault sends the statements as the driver does. Drivers like
+	// lib/pq send the statements with args as unnamed statements, so postgres
+	// plans them for their args on every execution. database/sql can not
+	// force the unnamed statements on the other drivers.
+	PlanDefault PlanMode = iota
+
+	// PlanGeneric runs the statements as server-side prepared statements,
+	// prepared once per connection and reused. Postgres switches to a
+	// generic plan for them after a few executions, so the plan does not
+	// change with the args.
+	PlanGeneric
+)
+
+// WithPlan sets the plan mode of the Exec, Query and QueryRow statements of
+// the given label, set with WithLabel.
+func WithPlan(label string, mode PlanMode) Option {
+	return func(db *DB) {
+		if db.plans == nil {
+			db.plans = make(map[string]PlanMode)
+		}
+
+		db.plans[label] = mode
+	}
+}
+
+// pinned returns the prepared statement of the given query on sqldb, if the
+// label of ctx runs with generic plans. Statements are prepared with the tags
+// of ctx on their first use on every connection. Returns nil if the statement
+// should run unprepared, preparing is best-effort, the statement runs
+// unprepared on failure.
+func (db *DB) pinned(ctx context.Context, sqldb *sql.DB, query string) *sql.Stmt {
+	query = tagQuery(ctx, query)
+
+	db.mu.Lock()
+	mode := db.plans[LabelFromContext(ctx)]
+	info := db.infos[sqldb]
+	var stmt *sql.Stmt
+	if info != nil {
+		stmt = info.stmts[query]
+	}
+	db.mu.Unlock()
+
+	if mode != PlanGeneric || info == nil {
+		return nil
+	}
+
+	if stmt != nil {
+		return stmt
+	}
+
+	stmt, err := sqldb.Prepare(query)
+	if err != nil {
+		return nil
+	}
+
+	db.mu.Lock()
+	if info.stmts == nil {
+		info.stmts = make(map[string]*sql.Stmt)
+	}
+
+	info.stmts[query] = stmt
+	db.mu.Unlock()
+
+	return stmt
+}
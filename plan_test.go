@@ -0,0 +1,57 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestWithPlan(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+		WithPlan("hot", PlanGeneric),
+		WithPlan("cold", PlanDefault),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	query := "SELECT $1::int"
+
+	var i int
+	for _, label := range []string{"hot", "hot", "cold"} {
+		if err := db.QueryRow(WithLabel(ctx, label), query, 1).Scan(ctx, &i); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	// statement is prepared once on the single connection
+	var count int
+	err = db.QueryRow(ctx, "SELECT COUNT(*) FROM pg_prepared_statements WHERE statement = $1", query).Scan(ctx, &count)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected 1 prepared statement, got: %d", count)
+	}
+
+	// pinned statements are prepared with their tags
+	tagged := WithTag(WithLabel(ctx, "hot"), "route", "users")
+	if err := db.QueryRow(tagged, query, 1).Scan(ctx, &i); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	err = db.QueryRow(ctx, "SELECT COUNT(*) FROM pg_prepared_statements WHERE statement = $1", tagQuery(tagged, query)).Scan(ctx, &count)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected the tagged statement to be prepared, got: %d", count)
+	}
+}
@@ -0,0 +1,245 @@
+package ctxdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// ErrInvalidWatchInterval is passed to the func of WatchPolicy given a
+// non-positive interval.
+var ErrInvalidWatchInterval = errors.New("watch interval must be positive")
+
+// Duration is a time.Duration which is encoded as a string in JSON, e.g.
+// "250ms".
+type Duration time.Duration
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// Policy holds the timeouts and retries of the operations by their labels, set
+// with WithLabel. Operations without a label, or with a label which is not
+// listed use the default policy.
+//
+//     {
+//         "default": {"timeout": "1s"},
+//         "labels": {
+//             "users/get": {"timeout": "100ms", "retries": 2}
+//         }
+//     }
+type Policy struct {
+	Default LabelPolicy            `json:"default"`
+	Labels  map[string]LabelPolicy `json:"labels,omitempty"`
+}
+
+// LabelPolicy is the policy of the operations having a label.
+type LabelPolicy struct {
+	// Timeout is applied to the ctx of the operation if it is set, shorter
+	// deadline of the ctx is kept.
+	Timeout Duration `json:"timeout,omitempty"`
+
+	// Retries is attached to the ctx of the operation with WithRetries, if
+	// it's set and the ctx does not have any.
+	Retries int `json:"retries,omitempty"`
+
+	// Savepoint retries the failed statements of Tx.Exec under a savepoint,
+	// instead of failing the whole transaction, see WithRetryPolicy.
+	Savepoint bool `json:"savepoint,omitempty"`
+}
+
+// Validate checks the given policy for invalid values.
+func (p *Policy) Validate() error {
+	if err := p.Default.validate("default"); err != nil {
+		return err
+	}
+
+	for label, lp := range p.Labels {
+		if err := lp.validate(label); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clone returns a copy of the policy, nil if p is nil
+func (p *Policy) clone() *Policy {
+	if p == nil {
+		return nil
+	}
+
+	c := &Policy{Default: p.Default}
+	if p.Labels != nil {
+		c.Labels = make(map[string]LabelPolicy, len(p.Labels))
+		for label, lp := range p.Labels {
+			c.Labels[label] = lp
+		}
+	}
+
+	return c
+}
+
+func (lp LabelPolicy) validate(label string) error {
+	if lp.Timeout < 0 {
+		return fmt.Errorf("policy of %s: timeout can not be negative", label)
+	}
+
+	if lp.Retries < 0 {
+		return fmt.Errorf("policy of %s: retries can not be negative", label)
+	}
+
+	return nil
+}
+
+// LoadPolicy reads and validates the JSON policy file at the given path.
+// Unknown fields are rejected on Go 1.10 and later.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	disallowUnknownFields(dec)
+
+	p := &Policy{}
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("policy file %s: %s", path, err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// SetPolicy sets the policy applied to Exec, Query and QueryRow operations.
+// Passing nil removes the policy.
+func (db *DB) SetPolicy(p *Policy) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.policy = p
+	db.mu.Unlock()
+}
+
+// WatchPolicy loads the policy file at the given path into db, and reloads it
+// when the file is modified, checking the file at every interval until ctx is
+// done. Errors while loading the file are passed to f and the previous policy
+// is kept. Non-positive intervals are passed to f as ErrInvalidWatchInterval.
+func (db *DB) WatchPolicy(ctx context.Context, path string, interval time.Duration, f func(err error)) {
+	if interval <= 0 {
+		f(ErrInvalidWatchInterval)
+		return
+	}
+
+	var modTime time.Time
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if info, err := os.Stat(path); err != nil {
+			f(err)
+		} else if !info.ModTime().Equal(modTime) {
+			modTime = info.ModTime()
+
+			p, err := LoadPolicy(path)
+			if err != nil {
+				f(err)
+			} else {
+				db.SetPolicy(p)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SetDefaultTimeout sets the timeout applied to the Exec, Query and QueryRow
+// operations whose ctx has no deadline, after the policy is applied. Zero
+// disables the default timeout.
+func (db *DB) SetDefaultTimeout(d time.Duration) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.defaultTimeout = d
+	db.mu.Unlock()
+}
+
+// labelPolicy returns the policy of the label of ctx, ok is false if no policy
+// is set
+func (db *DB) labelPolicy(ctx context.Context) (lp LabelPolicy, ok bool) {
+	db.mu.Lock()
+	p := db.policy
+	db.mu.Unlock()
+
+	if p == nil {
+		return LabelPolicy{}, false
+	}
+
+	if lp, ok = p.Labels[LabelFromContext(ctx)]; !ok {
+		lp = p.Default
+	}
+
+	return lp, true
+}
+
+// applyPolicy applies the policy of the label of ctx, and the default timeout
+// if ctx has no deadline. Given cancel func must be called when the operation
+// is done.
+func (db *DB) applyPolicy(ctx context.Context) (context.Context, context.CancelFunc) {
+	db.mu.Lock()
+	d := db.defaultTimeout
+	db.mu.Unlock()
+
+	cancel := func() {}
+
+	if lp, ok := db.labelPolicy(ctx); ok {
+		if _, ok := RetriesFromContext(ctx); !ok && lp.Retries > 0 {
+			ctx = WithRetries(ctx, lp.Retries)
+		}
+
+		if lp.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(lp.Timeout))
+		}
+	}
+
+	if _, ok := ctx.Deadline(); !ok && d > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d)
+	}
+
+	return ctx, cancel
+}
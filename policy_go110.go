@@ -0,0 +1,10 @@
+// +build go1.10
+
+package ctxdb
+
+import "encoding/json"
+
+// disallowUnknownFields makes dec reject the unknown fields of the policy.
+func disallowUnknownFields(dec *json.Decoder) {
+	dec.DisallowUnknownFields()
+}
@@ -0,0 +1,9 @@
+// +build !go1.10
+
+package ctxdb
+
+import "encoding/json"
+
+// Before Go 1.10, json.Decoder can not reject the unknown fields, they are
+// ignored.
+func disallowUnknownFields(dec *json.Decoder) {}
@@ -0,0 +1,128 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePolicyFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("err while writing the policy file: %s", err)
+	}
+
+	return path
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := writePolicyFile(t, `{
+        "default": {"timeout": "1s"},
+        "labels": {"users/get": {"timeout": "100ms", "retries": 2}}
+    }`)
+
+	p, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if time.Duration(p.Default.Timeout) != time.Second {
+		t.Fatalf("expected 1s default timeout, got: %s", time.Duration(p.Default.Timeout))
+	}
+
+	if p.Labels["users/get"].Retries != 2 {
+		t.Fatalf("expected 2 retries, got: %d", p.Labels["users/get"].Retries)
+	}
+}
+
+func TestLoadPolicyInvalid(t *testing.T) {
+	invalids := []string{
+		`{"default": {"timeout": "forever"}}`,
+		`{"default": {"timeout": "-1s"}}`,
+		`{"labels": {"users/get": {"retries": -1}}}`,
+		`{"default": {"priority": 1}}`,
+	}
+
+	for _, invalid := range invalids {
+		if _, err := LoadPolicy(writePolicyFile(t, invalid)); err == nil {
+			t.Fatalf("expected error for %s, got nil", invalid)
+		}
+	}
+}
+
+func TestApplyPolicy(t *testing.T) {
+	db := getConn(t)
+
+	db.SetPolicy(&Policy{
+		Labels: map[string]LabelPolicy{
+			"users/get": {Timeout: Duration(time.Second), Retries: 2},
+		},
+	})
+
+	ctx, cancel := db.applyPolicy(context.Background())
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatalf("expected no deadline for unlabeled ctx")
+	}
+
+	ctx, cancel = db.applyPolicy(WithLabel(context.Background(), "users/get"))
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); !ok {
+		t.Fatalf("expected deadline for labeled ctx")
+	}
+
+	if n, _ := RetriesFromContext(ctx); n != 2 {
+		t.Fatalf("expected 2 retries, got: %d", n)
+	}
+}
+
+func TestSetDefaultTimeout(t *testing.T) {
+	db := getConn(t)
+	db.SetDefaultTimeout(time.Minute)
+
+	ctx, cancel := db.applyPolicy(context.Background())
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatalf("expected deadline for ctx without deadline")
+	}
+
+	if remaining := deadline.Sub(time.Now()); remaining > time.Minute {
+		t.Fatalf("expected at most a minute, got: %s", remaining)
+	}
+
+	// deadline of ctx is kept
+	given, cancelGiven := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelGiven()
+
+	ctx, cancel = db.applyPolicy(given)
+	defer cancel()
+
+	if deadline, _ := ctx.Deadline(); deadline.Sub(time.Now()) < time.Minute {
+		t.Fatalf("expected the deadline of the given ctx, got: %s", deadline)
+	}
+
+	db.SetDefaultTimeout(time.Millisecond)
+	if _, err := db.Exec(context.Background(), "SELECT pg_sleep(1)"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestWatchPolicyInvalidInterval(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	var got error
+	db.WatchPolicy(context.Background(), writePolicyFile(t, `{}`), 0, func(err error) {
+		got = err
+	})
+
+	if got != ErrInvalidWatchInterval {
+		t.Fatalf("expected ErrInvalidWatchInterval, got: %v", got)
+	}
+}
@@ -1,10 +1,16 @@
 package ctxdb
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 )
 
+// connSetupTimeout is the max time of setting up a new connection, e.g.
+// running its warm-up queries
+const connSetupTimeout = time.Second * 5
+
 var (
 	// ErrClosed represents closed ctxdb error
 	ErrClosed = errors.New("connection is closed")
@@ -14,13 +20,21 @@ var (
 
 	// ErrMaxConnLimitReached represents overuse of connections
 	ErrMaxConnLimitReached = errors.New("connection limit reached")
-)
 
-// func (db *DB) SetMaxIdleConns(i int) {
-// 	db.mu.Lock()
-// 	db.maxIdleConns = i
-// 	db.confMu.Unlock()
-// }
+	// ErrNotInitialized represents usage of a nil or zero value DB, Tx, Stmt,
+	// Rows, Row or Result
+	ErrNotInitialized = errors.New("not initialized")
+
+	// ErrInvalidPoolSize represents a non-positive max open conns option
+	ErrInvalidPoolSize = errors.New("max open conns must be positive")
+
+	// ErrNilFactory represents given nil connection factory error
+	ErrNilFactory = errors.New("factory is nil")
+
+	// ErrPoolExhausted represents a busy pool when waiting for a connection is
+	// disabled
+	ErrPoolExhausted = errors.New("pool is exhausted")
+)
 
 func (db *DB) getConns() chan *sql.DB {
 	db.mu.Lock()
@@ -29,22 +43,195 @@ func (db *DB) getConns() chan *sql.DB {
 	return conns
 }
 
-func (db *DB) getFromPool() (*sql.DB, error) {
+func (db *DB) getFromPool(ctx context.Context) (*sql.DB, error) {
 	conns := db.getConns()
 	if conns == nil {
 		return nil, ErrClosed
 	}
 
-	select {
-	case conn := <-conns:
-		if conn == nil {
-			return nil, ErrClosed
+	for {
+		select {
+		case conn := <-conns:
+			if conn == nil {
+				return nil, ErrClosed
+			}
+
+			if db.checkout(conn) {
+				return conn, nil
+			}
+		default:
+			if conn := db.takeStandby(); conn != nil {
+				if db.checkout(conn) {
+					return conn, nil
+				}
+
+				continue
+			}
+
+			conn, err := db.newConn(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			db.checkout(conn)
+			return conn, nil
 		}
+	}
+}
 
-		return conn, nil
-	default:
-		return db.factory()
+// checkout marks the conn taken from the pool as in use, closing it and
+// returning false if it is expired
+func (db *DB) checkout(conn *sql.DB) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.isExpiredLocked(conn, time.Now()) {
+		db.closeConnLocked(conn)
+		return false
+	}
+
+	if info, ok := db.infos[conn]; ok {
+		info.idleSince = time.Time{}
+		info.uses++
+		db.checkouts++
+		fireLocked(db.hooks.OnCheckout, info)
+	}
+
+	return true
+}
+
+// connInfo holds the bookkeeping of a connection created by the factory
+type connInfo struct {
+	gen       int                  // generation of the factory which created the connection
+	stmts     map[string]*sql.Stmt // pinned statements by their queries
+	backendID int64                // server side id, read if a canceler is set
+	cancelled bool                 // running query is cancelled on the server
+	createdAt time.Time            // creation time, for the max lifetime
+	idleSince time.Time            // time the conn is put to the pool, zero while in use
+	leak      *time.Timer          // leak timer of the checkout, if the leak detector is enabled
+	attrs     *ConnAttributes      // server side attributes, read if enabled
+	uses      int64                // number of checkouts
+}
+
+// newConn creates a new connection with the current factory, and sets it up
+// within ctx, see setupConn
+func (db *DB) newConn(ctx context.Context) (*sql.DB, error) {
+	db.mu.Lock()
+	factory := db.factory
+	gen := db.gen
+	db.mu.Unlock()
+
+	if factory == nil {
+		return nil, ErrClosed
+	}
+
+	conn, err := factory()
+	if err != nil {
+		db.mu.Lock()
+		db.factoryFailures++
+		db.mu.Unlock()
+
+		return nil, err
 	}
+
+	info := &connInfo{gen: gen, createdAt: time.Now()}
+	if err := db.setupConn(ctx, conn, info); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	db.mu.Lock()
+	if db.infos == nil {
+		db.infos = make(map[*sql.DB]*connInfo)
+	}
+
+	db.infos[conn] = info
+	fireLocked(db.hooks.OnConnectionNew, info)
+	db.mu.Unlock()
+
+	return conn, nil
+}
+
+// setupConn runs the warm-up queries on the new conn and reads its server side
+// id and attributes into info, bounded by ctx and connSetupTimeout, since they
+// run while a sem is held. Setup is best-effort, but the conn is not used if
+// it does not finish in time.
+func (db *DB) setupConn(ctx context.Context, conn *sql.DB, info *connInfo) error {
+	ctx, cancel := context.WithTimeout(ctx, connSetupTimeout)
+	defer cancel()
+
+	done := make(chan struct{}, 1)
+	var backendID int64
+	var attrs *ConnAttributes
+	go func() {
+		db.warm(ctx, conn)
+
+		if db.canceler != nil {
+			// best-effort, queries of the conn are not cancelled without an id
+			dbScanRow(ctx, conn, db.canceler.IDQuery, nil, &backendID)
+		}
+
+		if db.connAttrs {
+			attrs = readConnAttributes(ctx, conn)
+		}
+
+		close(done)
+	}()
+
+	if err := wait(ctx, done); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		// setup is stopped by the driver
+		return err
+	}
+
+	info.backendID, info.attrs = backendID, attrs
+	return nil
+}
+
+// isStale reports whether the conn is created by a replaced factory
+func (db *DB) isStale(conn *sql.DB) bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.isStaleLocked(conn)
+}
+
+func (db *DB) isStaleLocked(conn *sql.DB) bool {
+	info, ok := db.infos[conn]
+	return ok && info.gen != db.gen
+}
+
+// closeConn closes the given connection and drops its bookkeeping. Close is
+// idempotent
+func (db *DB) closeConn(conn *sql.DB) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.closeConnLocked(conn)
+}
+
+// closeCancelled closes the conn of an operation whose ctx is done
+func (db *DB) closeCancelled(conn *sql.DB) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.cancelCloses++
+	return db.closeConnLocked(conn)
+}
+
+func (db *DB) closeConnLocked(conn *sql.DB) error {
+	if info := db.infos[conn]; info != nil && info.leak != nil {
+		// closed conns are not leaked
+		info.leak.Stop()
+	}
+
+	fireLocked(db.hooks.OnClose, db.infos[conn])
+
+	delete(db.infos, conn)
+	return conn.Close()
 }
 
 func (db *DB) put(conn *sql.DB) error {
@@ -57,14 +244,36 @@ func (db *DB) put(conn *sql.DB) error {
 
 	if db.conns == nil {
 		// pool is closed, close passed connection
-		return conn.Close()
+		return db.closeConnLocked(conn)
+	}
+
+	if db.isStaleLocked(conn) {
+		// factory is replaced, close passed connection
+		return db.closeConnLocked(conn)
+	}
+
+	now := time.Now()
+	if db.isExpiredLocked(conn, now) {
+		// max lifetime is exceeded, close passed connection
+		return db.closeConnLocked(conn)
+	}
+
+	if len(db.conns) >= db.maxIdleConns {
+		// max idle conns is lowered, close passed connection
+		return db.closeConnLocked(conn)
 	}
 
 	select {
 	case db.conns <- conn:
+		// idle time is kept if the conn is put back without use
+		if info, ok := db.infos[conn]; ok && info.idleSince.IsZero() {
+			info.idleSince = now
+			fireLocked(db.hooks.OnCheckin, info)
+		}
+
 		return nil
 	default:
 		// pool is full, close passed connection
-		return conn.Close()
+		return db.closeConnLocked(conn)
 	}
 }
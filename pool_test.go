@@ -1,6 +1,7 @@
 package ctxdb
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -23,7 +24,7 @@ func getConn(t *testing.T) *DB {
 func TestGetFromPool(t *testing.T) {
 	p := getConn(t)
 
-	conn, err := p.getFromPool()
+	conn, err := p.getFromPool(context.Background())
 	if err != nil {
 		t.Errorf("Get error: %s", err)
 	}
@@ -34,7 +35,7 @@ func TestGetFromPool(t *testing.T) {
 
 	close(p.conns)
 
-	conn, err = p.getFromPool()
+	conn, err = p.getFromPool(context.Background())
 	if err != ErrClosed {
 		t.Errorf("Error should be ErrClosed, got: %s", err)
 	}
@@ -43,11 +44,11 @@ func TestGetFromPool(t *testing.T) {
 func TestGetFromPoolClosed(t *testing.T) {
 	p := getConn(t)
 
-	if err := p.Close(); err != nil {
+	if err := p.Close(context.Background()); err != nil {
 		t.Errorf("Err while closing the connection: %# v", err)
 	}
 
-	_, err := p.getFromPool()
+	_, err := p.getFromPool(context.Background())
 	if err != ErrClosed {
 		t.Errorf("Error should be ErrClosed, got: %s", err)
 	}
@@ -64,7 +65,7 @@ func TestPutPoolNilConn(t *testing.T) {
 func TestPutPool(t *testing.T) {
 	p := getConn(t)
 
-	conn, err := p.getFromPool()
+	conn, err := p.getFromPool(context.Background())
 	if err != nil {
 		t.Errorf("Error should be nil, got: %s", err)
 	}
@@ -81,7 +82,7 @@ func TestPutPool(t *testing.T) {
 func TestPutPoolClosedConn(t *testing.T) {
 	p := getConn(t)
 
-	conn, err := p.getFromPool()
+	conn, err := p.getFromPool(context.Background())
 	if err != nil {
 		t.Errorf("Error should be nil, got: %s", err)
 	}
@@ -90,7 +91,7 @@ func TestPutPoolClosedConn(t *testing.T) {
 		t.Errorf("conn is nil")
 	}
 
-	if err := p.Close(); err != nil {
+	if err := p.Close(context.Background()); err != nil {
 		t.Errorf("Err while closing the connection: %# v", err)
 	}
 
@@ -110,7 +111,7 @@ func TestPutPoolClosedConn(t *testing.T) {
 func TestPutPoolClosedPool(t *testing.T) {
 	p := getConn(t)
 
-	conn, err := p.getFromPool()
+	conn, err := p.getFromPool(context.Background())
 	if err != nil {
 		t.Errorf("Error should be nil, got: %s", err)
 	}
@@ -139,17 +140,17 @@ func TestPutPoolClosedPool(t *testing.T) {
 func TestPutPoolFull(t *testing.T) {
 	p := getConn(t)
 
-	conn1, err := p.getFromPool()
+	conn1, err := p.getFromPool(context.Background())
 	if err != nil {
 		t.Errorf("Error should be nil, got: %s", err)
 	}
 
-	conn2, err := p.getFromPool()
+	conn2, err := p.getFromPool(context.Background())
 	if err != nil {
 		t.Errorf("Error should be nil, got: %s", err)
 	}
 
-	conn3, err := p.getFromPool()
+	conn3, err := p.getFromPool(context.Background())
 	if err != nil {
 		t.Errorf("Error should be nil, got: %s", err)
 	}
@@ -174,17 +175,17 @@ func TestPutPoolFull(t *testing.T) {
 func TestClose(t *testing.T) {
 	p := getConn(t)
 
-	_, err := p.getFromPool()
+	_, err := p.getFromPool(context.Background())
 	if err != nil {
 		t.Errorf("Error should be nil, got: %s", err)
 	}
 
 	p.conns <- nil
-	if err := p.Close(); err != nil {
+	if err := p.Close(context.Background()); err != nil {
 		t.Errorf("Error should be nil while trying to close a nil connection, got: %s", err)
 	}
 
-	if err := p.Close(); err != ErrClosed {
+	if err := p.Close(context.Background()); err != ErrClosed {
 		t.Errorf("Err should be Closed:  got %# v", err)
 	}
 }
@@ -192,4 +193,9 @@ func TestClose(t *testing.T) {
 func TestSetMaxOpenConns(t *testing.T) {
 	p := getConn(t)
 	p.SetMaxOpenConns(1)
+
+	// pool is not resized, the config reports its real size
+	if c := p.Config(); c.MaxOpenConns != cap(p.sem) || c.MaxOpenConns != maxOpenConns {
+		t.Fatalf("expected %d max open conns, got: %d", maxOpenConns, c.MaxOpenConns)
+	}
 }
@@ -0,0 +1,75 @@
+package ctxdb
+
+import (
+	"context"
+	"time"
+)
+
+// Profile is a group of safety defaults for an environment, set with
+// WithProfile.
+type Profile int
+
+const (
+	// ProfileDev logs every operation, warns about the operations without a
+	// deadline, and logs the slow queries over 100ms with their plans. Schema
+	// changes and dry runs are allowed.
+	ProfileDev Profile = iota
+
+	// ProfileStaging warns about the operations without a deadline, gates
+	// the schema changes, and logs the slow queries over 500ms with their
+	// plans. Dry runs are allowed.
+	ProfileStaging
+
+	// ProfileProd rejects the operations without a deadline, gates the
+	// schema changes, disables the dry runs, and logs the slow queries over
+	// 1s without their plans.
+	ProfileProd
+)
+
+// slow query thresholds of the profiles
+var profileSlowThresholds = map[Profile]time.Duration{
+	ProfileDev:     100 * time.Millisecond,
+	ProfileStaging: 500 * time.Millisecond,
+	ProfileProd:    time.Second,
+}
+
+// WithProfile applies the safety defaults of the given profile, writing the
+// logs of the profile with logf, e.g. log.Printf. Options given after the
+// profile override its defaults. See WithDeadlineGuard, WithDDLGate,
+// WithoutDryRun, SetLogger and WithSlowQueryLog for the behaviors.
+func WithProfile(p Profile, logf func(format string, args ...interface{})) Option {
+	return func(db *DB) {
+		var warn func(query string)
+		if p != ProfileProd {
+			warn = func(query string) {
+				logf("ctxdb: operation without deadline: %s", query)
+			}
+		}
+
+		WithDeadlineGuard(warn)(db)
+
+		if p != ProfileDev {
+			WithDDLGate()(db)
+		}
+
+		if p == ProfileProd {
+			WithoutDryRun()(db)
+		}
+
+		if p == ProfileDev {
+			db.logger = LoggerFunc(func(ctx context.Context, e *LogEntry) {
+				logf("ctxdb: %s %q with %d args took %s, rows affected: %d, err: %v",
+					e.Op, e.Query, e.ArgCount, e.Duration, e.RowsAffected, e.Err)
+			})
+		}
+
+		WithSlowQueryLog(profileSlowThresholds[p], p != ProfileProd, func(q *SlowQuery) {
+			if q.Plan != "" {
+				logf("ctxdb: slow %s %q took %s, err: %v, plan:\n%s", q.Op, q.Query, q.Duration, q.Err, q.Plan)
+				return
+			}
+
+			logf("ctxdb: slow %s %q took %s, err: %v", q.Op, q.Query, q.Duration, q.Err)
+		})(db)
+	}
+}
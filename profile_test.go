@@ -0,0 +1,64 @@
+package ctxdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithProfile(t *testing.T) {
+	var logs []string
+	logf := func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithProfile(ProfileProd, logf),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	if _, err := db.Exec(context.Background(), "SELECT 1"); err != ErrNoDeadline {
+		t.Fatalf("expected ErrNoDeadline, got: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err := db.Exec(ctx, "DROP TABLE IF EXISTS profiles"); err != ErrDDLNotAllowed {
+		t.Fatalf("expected ErrDDLNotAllowed, got: %v", err)
+	}
+
+	if _, err := db.Exec(WithDryRun(ctx), "SELECT 1"); err != ErrDryRunDisabled {
+		t.Fatalf("expected ErrDryRunDisabled, got: %v", err)
+	}
+
+	if len(logs) != 0 {
+		t.Fatalf("expected no logs in prod, got: %v", logs)
+	}
+
+	dev, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithProfile(ProfileDev, logf),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer dev.Close(context.Background())
+
+	// operations without deadline are logged and run in dev
+	if _, err := dev.Exec(context.Background(), "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(logs) != 2 {
+		t.Fatalf("expected the deadline warning and the log of the operation, got: %v", logs)
+	}
+}
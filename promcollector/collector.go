@@ -0,0 +1,201 @@
+// Package promcollector exports the metrics of a ctxdb.DB to prometheus; the
+// pool gauges, the latency histograms and the error counters of the
+// operations, and the cancellation counts.
+package promcollector
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/cihangir/ctxdb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements prometheus.Collector for a ctxdb.DB. Usage:
+//
+//     c := promcollector.New("myapp")
+//     db, err := ctxdb.Open(driver, dsn, c.Option())
+//     ...
+//     c.Attach(db)
+//     prometheus.MustRegister(c)
+type Collector struct {
+	mu sync.RWMutex
+	db *ctxdb.DB
+
+	latency       *prometheus.HistogramVec
+	errors        *prometheus.CounterVec
+	cancellations *prometheus.CounterVec
+
+	maxOpenConns    *prometheus.Desc
+	openConns       *prometheus.Desc
+	inUse           *prometheus.Desc
+	idle            *prometheus.Desc
+	waiting         *prometheus.Desc
+	waitCount       *prometheus.Desc
+	waitDuration    *prometheus.Desc
+	checkouts       *prometheus.Desc
+	factoryFailures *prometheus.Desc
+	cancelCloses    *prometheus.Desc
+}
+
+// New creates a collector, names of the metrics are prefixed with the given
+// namespace and "ctxdb", e.g. myapp_ctxdb_open_connections.
+func New(namespace string) *Collector {
+	const subsystem = "ctxdb"
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, nil, nil)
+	}
+
+	return &Collector{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of the operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"op"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "operation_errors_total",
+			Help:      "Failed operations by their error classes.",
+		}, []string{"op", "class"}),
+		cancellations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "operation_cancellations_total",
+			Help:      "Operations stopped by a timeout or a cancel of their ctx.",
+		}, []string{"op"}),
+
+		maxOpenConns:    desc("max_open_connections", "Max number of open connections."),
+		openConns:       desc("open_connections", "Number of open connections, idle or in use."),
+		inUse:           desc("in_use_connections", "Number of connections in use."),
+		idle:            desc("idle_connections", "Number of idle connections."),
+		waiting:         desc("waiting_operations", "Number of operations waiting for a connection."),
+		waitCount:       desc("waits_total", "Total number of operations waited for a connection."),
+		waitDuration:    desc("wait_duration_seconds_total", "Total time waited for a connection."),
+		checkouts:       desc("checkouts_total", "Total number of connections taken for operations."),
+		factoryFailures: desc("factory_failures_total", "Total number of failed connection creations."),
+		cancelCloses:    desc("cancel_closes_total", "Total number of connections closed on cancellation."),
+	}
+}
+
+// Option returns the ctxdb option recording the operations of a DB. It sets
+// ctxdb.WithOpReport, so it replaces another report func set on the DB.
+func (c *Collector) Option() ctxdb.Option {
+	return ctxdb.WithOpReport(c.record)
+}
+
+// Attach sets the DB whose pool statistics are collected.
+func (c *Collector) Attach(db *ctxdb.DB) {
+	c.mu.Lock()
+	c.db = db
+	c.mu.Unlock()
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.latency.Describe(ch)
+	c.errors.Describe(ch)
+	c.cancellations.Describe(ch)
+
+	for _, d := range c.poolDescs() {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.latency.Collect(ch)
+	c.errors.Collect(ch)
+	c.cancellations.Collect(ch)
+
+	c.mu.RLock()
+	db := c.db
+	c.mu.RUnlock()
+
+	if db == nil {
+		return
+	}
+
+	s, err := db.Stats(context.Background())
+	if err != nil {
+		return
+	}
+
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+
+	gauge(c.maxOpenConns, float64(s.MaxOpenConns))
+	gauge(c.openConns, float64(s.OpenConns))
+	gauge(c.inUse, float64(s.InUse))
+	gauge(c.idle, float64(s.Idle))
+	gauge(c.waiting, float64(s.Waiting))
+	counter(c.waitCount, float64(s.WaitCount))
+	counter(c.waitDuration, s.WaitDuration.Seconds())
+	counter(c.checkouts, float64(s.Checkouts))
+	counter(c.factoryFailures, float64(s.FactoryFailures))
+	counter(c.cancelCloses, float64(s.CancelCloses))
+}
+
+func (c *Collector) poolDescs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		c.maxOpenConns,
+		c.openConns,
+		c.inUse,
+		c.idle,
+		c.waiting,
+		c.waitCount,
+		c.waitDuration,
+		c.checkouts,
+		c.factoryFailures,
+		c.cancelCloses,
+	}
+}
+
+// record records the report of an operation
+func (c *Collector) record(r *ctxdb.OpReport) {
+	c.latency.WithLabelValues(r.Op).Observe(r.Duration.Seconds())
+
+	class := ErrorClass(r.Err)
+	if class == "" {
+		return
+	}
+
+	c.errors.WithLabelValues(r.Op, class).Inc()
+
+	if class == "timeout" || class == "canceled" {
+		c.cancellations.WithLabelValues(r.Op).Inc()
+	}
+}
+
+// ErrorClass returns the class of an operation error used as the label of the
+// error counter; "timeout", "canceled", "pool_exhausted", "circuit_open",
+// "transient", "no_rows" or "other". Returns an empty string for nil.
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case err == context.DeadlineExceeded:
+		return "timeout"
+	case err == context.Canceled:
+		return "canceled"
+	case err == ctxdb.ErrPoolExhausted:
+		return "pool_exhausted"
+	case err == ctxdb.ErrCircuitOpen:
+		return "circuit_open"
+	case err == sql.ErrNoRows || err == ctxdb.ErrNotFound:
+		return "no_rows"
+	case ctxdb.IsTransient(err):
+		return "transient"
+	default:
+		return "other"
+	}
+}
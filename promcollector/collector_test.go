@@ -0,0 +1,84 @@
+package promcollector
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cihangir/ctxdb"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	c := New("test")
+
+	db, err := ctxdb.Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		c.Option(),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	// pool gauges are collected once a DB is attached
+	if n := testutil.CollectAndCount(c, "test_ctxdb_open_connections"); n != 0 {
+		t.Fatalf("expected no pool metrics, got: %d", n)
+	}
+
+	c.Attach(db)
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+
+	if _, err := db.Exec(timeoutCtx, "SELECT pg_sleep(1)"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	if n := testutil.CollectAndCount(c, "test_ctxdb_open_connections"); n != 1 {
+		t.Fatalf("expected the open connections gauge, got: %d", n)
+	}
+
+	if n := testutil.CollectAndCount(c, "test_ctxdb_operation_duration_seconds"); n != 1 {
+		t.Fatalf("expected the latency histogram of Exec, got: %d", n)
+	}
+
+	if v := testutil.ToFloat64(c.cancellations.WithLabelValues("Exec")); v != 1 {
+		t.Fatalf("expected 1 cancellation, got: %f", v)
+	}
+
+	if v := testutil.ToFloat64(c.errors.WithLabelValues("Exec", "timeout")); v != 1 {
+		t.Fatalf("expected 1 timeout error, got: %f", v)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{nil, ""},
+		{context.DeadlineExceeded, "timeout"},
+		{context.Canceled, "canceled"},
+		{ctxdb.ErrPoolExhausted, "pool_exhausted"},
+		{ctxdb.ErrCircuitOpen, "circuit_open"},
+		{sql.ErrNoRows, "no_rows"},
+		{errors.New("read: connection reset by peer"), "transient"},
+		{errors.New("syntax error"), "other"},
+	}
+
+	for _, test := range tests {
+		if class := ErrorClass(test.err); class != test.class {
+			t.Errorf("expected %q for %v, got: %q", test.class, test.err, class)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package ctxdb
+
+import "context"
+
+const readOnlyTxSQLStatement = `SET TRANSACTION READ ONLY`
+
+// WithReplica sets the pool of a replica, which the transactions of
+// BeginReadOnly are begun on. Replica is opened and closed by the caller, its
+// own options apply to the transactions begun on it.
+func WithReplica(replica *DB) Option {
+	return func(db *DB) {
+		db.replica = replica
+	}
+}
+
+// BeginReadOnly starts a read-only transaction, its statements fail on the
+// server if they write, so report-style code can not modify the database by
+// accident. Transaction is begun on the replica set with WithReplica, on the
+// pool of db like the transactions of Begin if there is none or ctx is marked
+// with WithPrimary.
+func (db *DB) BeginReadOnly(ctx context.Context) (*Tx, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	pool := db
+	if db.replica != nil && !IsPrimary(ctx) {
+		pool = db.replica
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.exec(ctx, readOnlyTxSQLStatement); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return tx, nil
+}
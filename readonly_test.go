@@ -0,0 +1,69 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestBeginReadOnly(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	tx, err := db.BeginReadOnly(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var n int
+	if err := tx.QueryRow(ctx, "SELECT 1").Scan(ctx, &n); err != nil || n != 1 {
+		t.Fatalf("expected 1, got: %d, %v", n, err)
+	}
+
+	if _, err := tx.Exec(ctx, "CREATE TABLE ctxdb_readonly (id int)"); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+
+	tx.Rollback(ctx)
+}
+
+func TestBeginReadOnlyWithReplica(t *testing.T) {
+	replica := getConn(t)
+	defer replica.Close(context.Background())
+
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithReplica(replica),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	tx, err := db.BeginReadOnly(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if tx.db != replica {
+		t.Fatal("expected the transaction to be begun on the replica")
+	}
+
+	if s := replica.PoolStats(); s.InUse != 1 {
+		t.Fatalf("expected a connection of the replica in use, got: %+v", s)
+	}
+
+	primaryTx, err := db.BeginReadOnly(WithPrimary(ctx))
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer primaryTx.Rollback(ctx)
+
+	if primaryTx.db != db {
+		t.Fatal("expected the transaction of a primary ctx to be begun on the primary")
+	}
+}
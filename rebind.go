@@ -0,0 +1,126 @@
+package ctxdb
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Rebind converts the placeholders of the query to the ones of the dialect, ?
+// to $1..$n for DialectPostgres, and $n to ? for DialectMySQL, so the query
+// constants can be shared between the databases. Queries of DialectUnknown are
+// returned as they are. $n placeholders converted to ? must be in order and
+// not reused, since the args are not reordered; the rebinding of the DB does
+// reorder them, see WithRebind.
+func Rebind(d Dialect, query string) string {
+	switch d {
+	case DialectPostgres:
+		var n int
+		return scanPlaceholders(query, func(i int) string {
+			if i > 0 {
+				return "$" + strconv.Itoa(i)
+			}
+
+			n++
+			return "$" + strconv.Itoa(n)
+		})
+	case DialectMySQL:
+		return scanPlaceholders(query, func(int) string {
+			return "?"
+		})
+	default:
+		return query
+	}
+}
+
+// WithRebind rebinds the queries of the DB and its transactions with Rebind
+// before they are executed or prepared, for the dialect detected by Open or
+// set with WithDialect. Args of the $n placeholders rebound to ? are
+// reordered, except the ones of the prepared statements. Postgres operators
+// containing ?, e.g. the jsonb ones, can not be used with rebinding.
+func WithRebind() Option {
+	return func(db *DB) {
+		db.rebind = true
+	}
+}
+
+// rebindArgs rebinds the query, and reorders the args of the $n placeholders
+// for the ? ones. Query is returned as it is if a placeholder has no arg.
+func rebindArgs(d Dialect, query string, args []interface{}) (string, []interface{}) {
+	if d != DialectMySQL {
+		return Rebind(d, query), args
+	}
+
+	var order []int // indexes of the args, -1 for the ? placeholders
+	var reordered bool
+	q := scanPlaceholders(query, func(n int) string {
+		if n > 0 {
+			reordered = true
+		}
+
+		order = append(order, n-1)
+		return "?"
+	})
+
+	if !reordered {
+		return q, args
+	}
+
+	rebound := make([]interface{}, len(order))
+
+	// args of the ? placeholders are taken in order
+	var next int
+	for k, i := range order {
+		if i < 0 {
+			i = next
+			next++
+		}
+
+		if i >= len(args) {
+			return query, args
+		}
+
+		rebound[k] = args[i]
+	}
+
+	return q, rebound
+}
+
+// scanPlaceholders replaces the ? and the $n placeholders of the query with the
+// results of f, with 0 for ?, skipping the quoted strings and the comments
+func scanPlaceholders(query string, f func(n int) string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(query); i++ {
+		if n := skipped(query, i); n > 0 {
+			b.WriteString(query[i : i+n])
+			i += n - 1
+			continue
+		}
+
+		c := query[i]
+		switch {
+		case c == '?':
+			b.WriteString(f(0))
+			continue
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]) &&
+			// $ of the identifiers are not placeholders
+			(i == 0 || !isNameChar(query[i-1])):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+
+			n, _ := strconv.Atoi(query[i+1 : j])
+			b.WriteString(f(n))
+			i = j - 1
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
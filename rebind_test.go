@@ -0,0 +1,80 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		{DialectPostgres, "SELECT * FROM t WHERE a = ? AND b = ?", "SELECT * FROM t WHERE a = $1 AND b = $2"},
+		{DialectPostgres, "SELECT '?', a FROM t WHERE b = ? -- ?", "SELECT '?', a FROM t WHERE b = $1 -- ?"},
+		{DialectPostgres, "SELECT $1", "SELECT $1"},
+		{DialectMySQL, "SELECT * FROM t WHERE a = $1 AND b = $2", "SELECT * FROM t WHERE a = ? AND b = ?"},
+		{DialectMySQL, "SELECT a$1 FROM t WHERE b = '$1'", "SELECT a$1 FROM t WHERE b = '$1'"},
+		{DialectUnknown, "SELECT ?", "SELECT ?"},
+	}
+
+	for _, test := range tests {
+		if query := Rebind(test.dialect, test.query); query != test.want {
+			t.Errorf("expected %q, got: %q", test.want, query)
+		}
+	}
+}
+
+func TestRebindArgs(t *testing.T) {
+	query, args := rebindArgs(DialectMySQL, "SELECT $2, $1, $2", []interface{}{1, 2})
+	if query != "SELECT ?, ?, ?" || len(args) != 3 || args[0] != 2 || args[1] != 1 || args[2] != 2 {
+		t.Fatalf("expected the reordered args, got: %q, %v", query, args)
+	}
+
+	// placeholders without args are left to the driver
+	query, args = rebindArgs(DialectMySQL, "SELECT $3", []interface{}{1})
+	if query != "SELECT $3" || len(args) != 1 {
+		t.Fatalf("expected the query as it is, got: %q, %v", query, args)
+	}
+}
+
+func TestWithRebind(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithRebind(),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	var a, b int
+	if err := db.QueryRow(ctx, "SELECT ?::int, ?::int", 1, 2).Scan(ctx, &a, &b); err != nil || a != 1 || b != 2 {
+		t.Fatalf("expected 1 and 2, got: %d, %d, %v", a, b, err)
+	}
+
+	stmt, err := db.Prepare(ctx, "SELECT ?::int")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer stmt.Close(ctx)
+
+	if err := stmt.QueryRow(ctx, 3).Scan(ctx, &a); err != nil || a != 3 {
+		t.Fatalf("expected 3, got: %d, %v", a, err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.ExecBatch(ctx, []Statement{{Query: "SELECT ?::int", Args: []interface{}{1}}}); err != nil {
+		t.Fatalf("expected the batch to be rebound, got: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Result summarizes an executed SQL command.
+//
+// Result is lazy, underlying driver is not asked for LastInsertId or
+// RowsAffected until the accessors are called. Some drivers hit the network
+// for them, so both of the accessors accept a context for deadlines.
+type Result struct {
+	id    uint64
+	res   sql.Result
+	query string
+}
+
+// Query returns the statement that produced the result, useful for logging.
+func (r *Result) Query() string {
+	if r == nil {
+		return ""
+	}
+
+	return r.query
+}
+
+// check returns ErrNotInitialized if r is nil or not created by an Exec
+func (r *Result) check() error {
+	if r == nil || r.res == nil {
+		return ErrNotInitialized
+	}
+
+	return nil
+}
+
+// LastInsertId returns the integer generated by the database in response to a
+// command. Typically this will be from an "auto increment" column when
+// inserting a new row. Not all databases support this feature, and the syntax
+// of such statements varies.
+func (r *Result) LastInsertId(ctx context.Context) (int64, error) {
+	if err := r.check(); err != nil {
+		return 0, err
+	}
+
+	return r.handle(ctx, r.res.LastInsertId)
+}
+
+// RowsAffected returns the number of rows affected by an update, insert, or
+// delete. Not every database or database driver may support this.
+func (r *Result) RowsAffected(ctx context.Context) (int64, error) {
+	if err := r.check(); err != nil {
+		return 0, err
+	}
+
+	return r.handle(ctx, r.res.RowsAffected)
+}
+
+// handle runs the given f against the underlying result. Connection of the
+// result is already put back to the pool, so on timeout or cancel case, we just
+// stop waiting for the driver.
+func (r *Result) handle(ctx context.Context, f func() (int64, error)) (int64, error) {
+	done := make(chan struct{}, 1)
+
+	var res int64
+	var err error
+
+	go func() {
+		res, err = f()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-done:
+		return res, err
+	}
+}
@@ -0,0 +1,61 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResultRowsAffected(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	for i := 1; i < 3; i++ {
+		if _, err := db.Exec(ctx, insertSQLStatement, i, nil, 42); err != nil {
+			t.Fatalf("err while adding null item: %s", err.Error())
+		}
+	}
+
+	res, err := db.Exec(ctx, deleteSQLStatement)
+	if err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	if res.Query() != deleteSQLStatement {
+		t.Fatalf("expected %s, got: %s", deleteSQLStatement, res.Query())
+	}
+
+	affected, err := res.RowsAffected(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if affected != 2 {
+		t.Fatalf("expected 2 affected rows, got: %d", affected)
+	}
+}
+
+func TestResultRowsAffectedWithTimeout(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	res, err := db.Exec(ctx, deleteSQLStatement)
+	if err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	timeout := time.Millisecond * 10
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	time.Sleep(timeout * 2)
+
+	if _, err := res.RowsAffected(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %s", err)
+	}
+}
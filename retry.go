@@ -0,0 +1,125 @@
+package ctxdb
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures the retries of Exec, Query and QueryRow on transient
+// errors, set with WithRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts of an operation, including the
+	// first one. Retries set on the ctx with WithRetries take precedence.
+	MaxAttempts int
+
+	// Backoff is the wait before the first retry, doubled on every retry up
+	// to MaxBackoff, if it is set.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Jitter randomizes the waits by the given fraction of them, e.g. 0.2
+	// waits between 80% and 120% of the backoff.
+	Jitter float64
+
+	// Retryable reports whether an operation failed with err can be retried,
+	// defaults to IsTransient.
+	Retryable func(err error) bool
+}
+
+// WithRetryPolicy retries Exec, Query and QueryRow on transient errors with the
+// given policy, within the deadline of their ctx. Retries are not attempted if
+// the ctx is done, or the deadline can not cover the backoff. Errors of
+// QueryRow are deferred to Scan, so only the errors of acquiring a connection
+// are retried for it.
+//
+// Statements of the transactions are not retried, unless the label policy of
+// their ctx enables the savepoint retries, see LabelPolicy.Savepoint.
+//
+// Note that an Exec failed with a reset connection might have been applied on
+// the server, so statements should be idempotent when retries are enabled.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(db *DB) {
+		db.retryPolicy = &p
+	}
+}
+
+// attempts returns the max number of attempts of the operations using ctx
+func (p *RetryPolicy) attempts(ctx context.Context) int {
+	if n, ok := RetriesFromContext(ctx); ok {
+		return n + 1
+	}
+
+	return p.MaxAttempts
+}
+
+// retryable reports whether err can be retried by the policy
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	return IsTransient(err)
+}
+
+// backoff returns the wait before the nth retry, starting from 1
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < n; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			d = p.MaxBackoff
+			break
+		}
+	}
+
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+
+	return d
+}
+
+// retry calls f until it succeeds, fails with an error which is not retryable,
+// or the attempts of the policy are exhausted. f is called once if the retry
+// policy is not set.
+func (db *DB) retry(ctx context.Context, f func() error) error {
+	p := db.retryPolicy
+	if p == nil {
+		return f()
+	}
+
+	attempts := p.attempts(ctx)
+	for n := 1; ; n++ {
+		err := f()
+		if err == nil || n >= attempts || !p.retryable(err) {
+			return err
+		}
+
+		if !p.wait(ctx, n) {
+			return err
+		}
+	}
+}
+
+// wait waits the backoff of the nth retry, returns false without waiting the
+// whole backoff if ctx is done, or the deadline can not cover the backoff
+func (p *RetryPolicy) wait(ctx context.Context, n int) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	wait := p.backoff(n)
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return false
+	}
+
+	t := time.NewTimer(wait)
+	select {
+	case <-ctx.Done():
+		t.Stop()
+		return false
+	case <-t.C:
+		return true
+	}
+}
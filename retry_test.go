@@ -0,0 +1,113 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{nil, false},
+		{driver.ErrBadConn, true},
+		{errors.New("dial tcp 127.0.0.1:5432: connect: connection refused"), true},
+		{errors.New("read tcp 127.0.0.1:5432: read: connection reset by peer"), true},
+		{errors.New("pq: syntax error at or near \"SELEC\""), false},
+		{context.DeadlineExceeded, false},
+	}
+
+	for _, test := range tests {
+		if IsTransient(test.err) != test.transient {
+			t.Fatalf("expected %t for %v", test.transient, test.err)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	db := &DB{}
+	WithRetryPolicy(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})(db)
+	ctx := context.Background()
+
+	calls := 0
+	err := db.retry(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return driver.ErrBadConn
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got: %d", calls)
+	}
+
+	// errors which are not transient are not retried
+	calls = 0
+	errSyntax := errors.New("syntax error")
+	err = db.retry(ctx, func() error {
+		calls++
+		return errSyntax
+	})
+	if err != errSyntax || calls != 1 {
+		t.Fatalf("expected 1 call with errSyntax, got: %d calls with %v", calls, err)
+	}
+
+	// retries of the ctx take precedence
+	calls = 0
+	err = db.retry(WithRetries(ctx, 1), func() error {
+		calls++
+		return driver.ErrBadConn
+	})
+	if err != driver.ErrBadConn || calls != 2 {
+		t.Fatalf("expected 2 calls with ErrBadConn, got: %d calls with %v", calls, err)
+	}
+}
+
+func TestRetryWithinDeadline(t *testing.T) {
+	db := &DB{}
+	WithRetryPolicy(RetryPolicy{MaxAttempts: 10, Backoff: time.Millisecond * 20})(db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	calls := 0
+	err := db.retry(ctx, func() error {
+		calls++
+		return driver.ErrBadConn
+	})
+	if err != driver.ErrBadConn {
+		t.Fatalf("expected ErrBadConn, got: %v", err)
+	}
+
+	// waits of 20ms and 40ms can not fit in the deadline together
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got: %d", calls)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Millisecond * 10, MaxBackoff: time.Millisecond * 30}
+
+	expected := []time.Duration{10, 20, 30, 30}
+	for i, e := range expected {
+		if d := p.backoff(i + 1); d != e*time.Millisecond {
+			t.Fatalf("expected %s, got: %s", e*time.Millisecond, d)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.backoff(1); d < time.Millisecond*5 || d > time.Millisecond*15 {
+			t.Fatalf("expected backoff within 5ms and 15ms, got: %s", d)
+		}
+	}
+}
@@ -1,11 +1,11 @@
 package ctxdb
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"sync"
-
-	"golang.org/x/net/context"
+	"time"
 )
 
 var (
@@ -16,10 +16,15 @@ var (
 
 // Row is the result of calling QueryRow to select a single row.
 type Row struct {
+	id    uint64
 	row   *sql.Row
 	sqldb *sql.DB
 	db    *DB
 	err   error
+	attrs *ConnAttributes
+
+	started time.Time          // start of QueryRow
+	spanEnd func(int64, error) // ends the span of QueryRow
 }
 
 // Rows is the result of a query. Its cursor starts before the first row
@@ -37,14 +42,33 @@ type Row struct {
 //     err = rows.Err() // get any error encountered during iteration
 //     ...
 type Rows struct {
-	rows  *sql.Rows
-	sqldb *sql.DB
-	db    *DB
-	err   error
-	mu    sync.Mutex
+	id     uint64
+	rows   *sql.Rows
+	sqldb  *sql.DB
+	db     *DB
+	origin context.Context // ctx of the query created the rows
+	err    error
+	guard  guard
+	attrs  *ConnAttributes
+
+	mu       sync.Mutex  // guards released against the iteration timeout
+	released bool        // connection is given back
+	expiry   *time.Timer // iteration timeout
+	expired  int32       // iteration timed out, accessed atomically
+
+	scope *Scope  // scope tracking the rows
+	idle  *txIdle // idle timer of the transaction of the rows
+
+	// misuse tracking of the strict mode, guarded by the guard of the rows
+	onRow  bool // last Next returned true
+	closed bool // Close is called
 }
 
 func (r *Row) Scan(ctx context.Context, dest ...interface{}) error {
+	if r == nil {
+		return ErrNotInitialized
+	}
+
 	// we can safely return here since db connections are handled on previous step
 	if r.err != nil {
 		return r.err
@@ -63,6 +87,7 @@ func (r *Row) Scan(ctx context.Context, dest ...interface{}) error {
 		return errNoSQLDB
 	}
 
+	dest = prepareDest(dest)
 	done := make(chan struct{}, 1)
 
 	f := func() {
@@ -71,17 +96,83 @@ func (r *Row) Scan(ctx context.Context, dest ...interface{}) error {
 	}
 
 	if err := r.db.processWithGivenSQL(ctx, f, done, r.sqldb); err != nil {
+		r.db.reportOp("QueryRow", r.started, err)
+		r.endSpan(err)
 		return err
 	}
 
-	return r.err
+	// errors of QueryRow are deferred to here
+	r.db.recordBreaker(r.err)
+	r.db.reportOp("QueryRow", r.started, r.err)
+	r.endSpan(r.err)
+	return r.db.mapDriverError(r.db.mapNoRows(r.err))
+}
+
+// check returns ErrNotInitialized if rs is nil or not created by a query
+func (rs *Rows) check() error {
+	if rs == nil || rs.rows == nil || rs.db == nil {
+		return ErrNotInitialized
+	}
+
+	return nil
+}
+
+// handle runs f with the connection of the rows, observing both the given ctx
+// and the ctx of the query created the rows. So iteration stops when the
+// query's ctx is done, even if the given ctx is not.
+func (rs *Rows) handle(ctx context.Context, f func(), done chan struct{}) error {
+	return rs.db.handleWithOrigin(ctx, rs.origin, f, done, rs.sqldb)
 }
 
 func (rs *Rows) Close(ctx context.Context) error {
+	if err := rs.check(); err != nil {
+		return err
+	}
+
+	if err := rs.guard.enter(); err != nil {
+		return err
+	}
+	defer rs.guard.leave()
+
+	if rs.closed {
+		if err := rs.db.misuse("Rows.Close", "rows are already closed",
+			"close the rows once, e.g. with a defer right after checking the error of Query"); err != nil {
+			return err
+		}
+	}
+	rs.closed = true
+
+	return rs.close(ctx)
+}
+
+// drop closes the rows on behalf of the caller, e.g. on the errors of the
+// helpers consuming the rows, so a later Close is not a misuse
+func (rs *Rows) drop(ctx context.Context) error {
+	if err := rs.check(); err != nil {
+		return err
+	}
+
+	if err := rs.guard.enter(); err != nil {
+		return err
+	}
+	defer rs.guard.leave()
+
+	return rs.close(ctx)
+}
+
+// close closes the rows, caller must enter the guard of the rows
+func (rs *Rows) close(ctx context.Context) error {
+	rs.checkExpired()
 	if rs.err != nil {
+		// connection of the failed rows is given back as well
+		rs.release(rs.err)
 		return rs.err
 	}
 
+	if rs.isReleased() {
+		return nil
+	}
+
 	done := make(chan struct{}, 1)
 	var err error
 	f := func() {
@@ -89,14 +180,54 @@ func (rs *Rows) Close(ctx context.Context) error {
 		close(done)
 	}
 
-	if err := rs.db.processWithGivenSQL(ctx, f, done, rs.sqldb); err != nil {
+	opErr := rs.handle(ctx, f, done)
+	if err := rs.release(opErr); err != nil {
 		return err
 	}
 
 	return err
 }
 
+// release gives the connection of the rows back with restoreOrClose, at most
+// once
+func (rs *Rows) release(err error) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.released {
+		return err
+	}
+
+	rs.released = true
+	if rs.expiry != nil {
+		rs.expiry.Stop()
+	}
+
+	rs.scope.untrack(rs, nil, nil)
+	rs.idle.rowsClosed()
+
+	return rs.db.restoreOrClose(err, rs.sqldb)
+}
+
+// isReleased reports whether the connection of the rows is given back
+func (rs *Rows) isReleased() bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.released
+}
+
 func (rs *Rows) Columns(ctx context.Context) ([]string, error) {
+	if err := rs.check(); err != nil {
+		return nil, err
+	}
+
+	if err := rs.guard.enter(); err != nil {
+		return nil, err
+	}
+	defer rs.guard.leave()
+
+	rs.checkExpired()
 	if rs.err != nil {
 		return nil, rs.err
 	}
@@ -109,7 +240,7 @@ func (rs *Rows) Columns(ctx context.Context) ([]string, error) {
 		close(done)
 	}
 
-	if err := rs.db.handleWithGivenSQL(ctx, f, done, rs.sqldb); err != nil {
+	if err := rs.handle(ctx, f, done); err != nil {
 		return nil, err
 	}
 
@@ -117,14 +248,44 @@ func (rs *Rows) Columns(ctx context.Context) ([]string, error) {
 }
 
 func (rs *Rows) Err() error {
+	if err := rs.check(); err != nil {
+		return err
+	}
+
+	if err := rs.guard.err(); err != nil {
+		return err
+	}
+
 	if rs.err != nil {
 		return rs.err
 	}
 
+	if rs.isExpired() {
+		return ErrIterationTimeout
+	}
+
 	return rs.rows.Err()
 }
 
 func (rs *Rows) Next(ctx context.Context) bool {
+	if err := rs.check(); err != nil {
+		return false
+	}
+
+	if err := rs.guard.enter(); err != nil {
+		return false
+	}
+	defer rs.guard.leave()
+
+	if rs.closed {
+		if err := rs.db.misuse("Rows.Next", "rows are iterated after Close",
+			"iterate the rows before closing them, Close releases the connection"); err != nil {
+			rs.err = err
+			return false
+		}
+	}
+
+	rs.checkExpired()
 	if rs.err != nil {
 		return false
 	}
@@ -136,19 +297,38 @@ func (rs *Rows) Next(ctx context.Context) bool {
 		close(done)
 	}
 
-	if err := rs.db.handleWithGivenSQL(ctx, f, done, rs.sqldb); err != nil {
+	if err := rs.handle(ctx, f, done); err != nil {
 		rs.err = err
 		return false
 	}
 
+	rs.onRow = res
 	return res
 }
 
 func (rs *Rows) Scan(ctx context.Context, dest ...interface{}) error {
+	if err := rs.check(); err != nil {
+		return err
+	}
+
+	if err := rs.guard.enter(); err != nil {
+		return err
+	}
+	defer rs.guard.leave()
+
+	if !rs.onRow {
+		if err := rs.db.misuse("Rows.Scan", "Scan is called without a successful Next",
+			"call Scan only after Next returns true"); err != nil {
+			return err
+		}
+	}
+
+	rs.checkExpired()
 	if rs.err != nil {
 		return rs.err
 	}
 
+	dest = prepareDest(dest)
 	done := make(chan struct{}, 1)
 	var err error
 	f := func() {
@@ -156,5 +336,9 @@ func (rs *Rows) Scan(ctx context.Context, dest ...interface{}) error {
 		close(done)
 	}
 
-	return rs.db.handleWithGivenSQL(ctx, f, done, rs.sqldb)
+	if opErr := rs.handle(ctx, f, done); opErr != nil {
+		return opErr
+	}
+
+	return err
 }
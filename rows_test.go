@@ -1,10 +1,9 @@
 package ctxdb
 
 import (
+	"context"
 	"testing"
 	"time"
-
-	"golang.org/x/net/context"
 )
 
 func TestScanWithTimeout(t *testing.T) {
@@ -227,3 +226,37 @@ func TestRowsNextWithTimeout(t *testing.T) {
 		t.Fatalf("expected context.DeadlineExceeded, got: %s", err)
 	}
 }
+
+func TestRowsNextWithOriginTimeout(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, insertSQLStatement, 42, nil, 12); err != nil {
+		t.Fatalf("err while adding null item: %s", err.Error())
+	}
+
+	timeout := time.Millisecond * 50
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	rows, err := db.Query(queryCtx, "SELECT string_n_val FROM nullable")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	time.Sleep(timeout * 2)
+
+	// iteration observes the ctx of the query
+	for rows.Next(ctx) {
+		t.Fatalf("expected no result, but got")
+	}
+
+	if err := rows.Err(); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %s", err)
+	}
+
+	if _, err := db.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+}
@@ -0,0 +1,177 @@
+package ctxdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SagaStep is a step of a saga, typically an operation on one of the DBs of
+// the workflow.
+type SagaStep struct {
+	Name string
+
+	// Timeout, if set, is the deadline of Do and Compensate of the step.
+	// Compensate runs with the compensation timeout of the saga otherwise.
+	Timeout time.Duration
+
+	// Do runs the step.
+	Do func(ctx context.Context) error
+
+	// Compensate, if set, reverts a completed step when a later one fails.
+	Compensate func(ctx context.Context) error
+}
+
+// SagaState is the state of a saga step, passed to the progress func.
+type SagaState int
+
+// States of a saga step
+const (
+	StepStarted SagaState = iota
+	StepDone
+	StepFailed
+	StepCompensated
+	StepCompensationFailed
+)
+
+func (s SagaState) String() string {
+	switch s {
+	case StepStarted:
+		return "started"
+	case StepDone:
+		return "done"
+	case StepFailed:
+		return "failed"
+	case StepCompensated:
+		return "compensated"
+	case StepCompensationFailed:
+		return "compensation failed"
+	default:
+		return fmt.Sprintf("SagaState(%d)", int(s))
+	}
+}
+
+// SagaError is returned by Saga.Run when a step fails.
+type SagaError struct {
+	// Step is the name of the failed step, and Err is its error.
+	Step string
+	Err  error
+
+	// Compensations holds the errors of the compensations which failed, by
+	// their step names. Steps in it are left in an inconsistent state.
+	Compensations map[string]error
+}
+
+func (e *SagaError) Error() string {
+	msg := fmt.Sprintf("saga step %s: %s", e.Step, e.Err)
+	if len(e.Compensations) > 0 {
+		msg += fmt.Sprintf(", %d compensations failed", len(e.Compensations))
+	}
+
+	return msg
+}
+
+// Unwrap returns the error of the failed step.
+func (e *SagaError) Unwrap() error {
+	return e.Err
+}
+
+// defaultCompensationTimeout is the compensation timeout of the sagas not
+// setting one
+const defaultCompensationTimeout = time.Minute
+
+// Saga coordinates a sequence of steps spanning different databases, where a
+// distributed transaction is not available. Steps run in order, when one of
+// them fails, the completed ones are compensated in reverse order.
+type Saga struct {
+	steps []SagaStep
+
+	// Progress, if set, is called on every state change of the steps.
+	Progress func(step string, state SagaState, err error)
+
+	// CompensationTimeout is the deadline of the compensations of the steps
+	// without a timeout, defaults to a minute.
+	CompensationTimeout time.Duration
+}
+
+// NewSaga creates a saga of the given steps.
+func NewSaga(steps ...SagaStep) *Saga {
+	return &Saga{steps: steps}
+}
+
+// Add appends the given step to the saga.
+func (s *Saga) Add(step SagaStep) *Saga {
+	s.steps = append(s.steps, step)
+	return s
+}
+
+// Run runs the steps of the saga with the given ctx, returns a *SagaError if a
+// step fails. Compensations run with the values of ctx, e.g. its labels and
+// tags, but not its cancellation, so they are not skipped when the failure is
+// the ctx itself; they are bounded by their timeouts instead.
+func (s *Saga) Run(ctx context.Context) error {
+	for i, step := range s.steps {
+		s.progress(step.Name, StepStarted, nil)
+
+		if err := runStep(ctx, step.Timeout, step.Do); err != nil {
+			s.progress(step.Name, StepFailed, err)
+			return s.compensate(ctx, i, &SagaError{Step: step.Name, Err: err})
+		}
+
+		s.progress(step.Name, StepDone, nil)
+	}
+
+	return nil
+}
+
+// compensate reverts the steps before the failed one, in reverse order
+func (s *Saga) compensate(ctx context.Context, failed int, sagaErr *SagaError) error {
+	ctx = detached(ctx)
+
+	for i := failed - 1; i >= 0; i-- {
+		step := s.steps[i]
+		if step.Compensate == nil {
+			continue
+		}
+
+		timeout := step.Timeout
+		if timeout <= 0 {
+			timeout = s.CompensationTimeout
+		}
+
+		if timeout <= 0 {
+			timeout = defaultCompensationTimeout
+		}
+
+		if err := runStep(ctx, timeout, step.Compensate); err != nil {
+			if sagaErr.Compensations == nil {
+				sagaErr.Compensations = make(map[string]error)
+			}
+
+			sagaErr.Compensations[step.Name] = err
+			s.progress(step.Name, StepCompensationFailed, err)
+			continue
+		}
+
+		s.progress(step.Name, StepCompensated, nil)
+	}
+
+	return sagaErr
+}
+
+func (s *Saga) progress(step string, state SagaState, err error) {
+	if s.Progress != nil {
+		s.Progress(step, state, err)
+	}
+}
+
+// runStep runs f with the given timeout applied to ctx
+func runStep(ctx context.Context, timeout time.Duration, f func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return f(ctx)
+}
@@ -0,0 +1,120 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaga(t *testing.T) {
+	var events []string
+	step := func(name string, err error) SagaStep {
+		return SagaStep{
+			Name: name,
+			Do: func(ctx context.Context) error {
+				events = append(events, "do "+name)
+				return err
+			},
+			Compensate: func(ctx context.Context) error {
+				events = append(events, "undo "+name)
+				return nil
+			},
+		}
+	}
+
+	errFailed := errors.New("failed")
+	s := NewSaga(step("a", nil), step("b", nil)).Add(step("c", errFailed))
+
+	err := s.Run(context.Background())
+	sagaErr, ok := err.(*SagaError)
+	if !ok {
+		t.Fatalf("expected *SagaError, got: %v", err)
+	}
+
+	if sagaErr.Step != "c" || sagaErr.Err != errFailed || len(sagaErr.Compensations) != 0 {
+		t.Fatalf("expected failure of c, got: %+v", sagaErr)
+	}
+
+	expected := []string{"do a", "do b", "do c", "undo b", "undo a"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("expected %v, got: %v", expected, events)
+	}
+}
+
+func TestSagaWithStepTimeout(t *testing.T) {
+	errUndo := errors.New("undo")
+
+	var states []SagaState
+	s := NewSaga(
+		SagaStep{
+			Name:       "a",
+			Do:         func(ctx context.Context) error { return nil },
+			Compensate: func(ctx context.Context) error { return errUndo },
+		},
+		SagaStep{
+			Name:    "b",
+			Timeout: time.Millisecond,
+			Do: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+	)
+	s.Progress = func(step string, state SagaState, err error) {
+		states = append(states, state)
+	}
+
+	sagaErr, ok := s.Run(context.Background()).(*SagaError)
+	if !ok || sagaErr.Err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", sagaErr)
+	}
+
+	if sagaErr.Compensations["a"] != errUndo {
+		t.Fatalf("expected compensation error of a, got: %v", sagaErr.Compensations)
+	}
+
+	expected := []SagaState{StepStarted, StepDone, StepStarted, StepFailed, StepCompensationFailed}
+	if !reflect.DeepEqual(states, expected) {
+		t.Fatalf("expected %v, got: %v", expected, states)
+	}
+}
+
+func TestSagaCompensationCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(WithLabel(context.Background(), "orders"))
+
+	var label string
+	var compensateErr error
+	var deadline bool
+	s := NewSaga(
+		SagaStep{
+			Name: "a",
+			Do:   func(ctx context.Context) error { return nil },
+			Compensate: func(ctx context.Context) error {
+				label = LabelFromContext(ctx)
+				_, deadline = ctx.Deadline()
+				compensateErr = ctx.Err()
+				return nil
+			},
+		},
+		SagaStep{
+			Name: "b",
+			Do: func(ctx context.Context) error {
+				cancel()
+				return ctx.Err()
+			},
+		},
+	)
+	s.CompensationTimeout = time.Second
+
+	sagaErr, ok := s.Run(ctx).(*SagaError)
+	if !ok || sagaErr.Unwrap() != context.Canceled {
+		t.Fatalf("expected context.Canceled, got: %v", sagaErr)
+	}
+
+	// compensations keep the values of ctx, but not its cancellation
+	if label != "orders" || compensateErr != nil || !deadline {
+		t.Fatalf("expected a detached ctx with a deadline, got: %q, %v, %t", label, compensateErr, deadline)
+	}
+}
@@ -0,0 +1,53 @@
+package ctxdb
+
+import "context"
+
+const (
+	savepointSQLStatement         = `SAVEPOINT ctxdb_retry`
+	releaseSavepointSQLStatement  = `RELEASE SAVEPOINT ctxdb_retry`
+	rollbackSavepointSQLStatement = `ROLLBACK TO SAVEPOINT ctxdb_retry`
+)
+
+// execSavepoint runs exec under a savepoint if the retry policy is set and the
+// label policy of ctx enables the savepoint retries, rolling back to the
+// savepoint and retrying the statement on the retryable errors. Transaction is
+// left as it is if the statement can not be retried, so it fails as without
+// the savepoint.
+func (tx *Tx) execSavepoint(ctx context.Context, query string, args ...interface{}) (*Result, error) {
+	p := tx.db.retryPolicy
+	if p == nil {
+		return tx.exec(ctx, query, args...)
+	}
+
+	if lp, ok := tx.db.labelPolicy(ctx); !ok || !lp.Savepoint {
+		return tx.exec(ctx, query, args...)
+	}
+
+	if _, err := tx.exec(ctx, savepointSQLStatement); err != nil {
+		return nil, err
+	}
+
+	attempts := p.attempts(ctx)
+	for n := 1; ; n++ {
+		res, err := tx.exec(ctx, query, args...)
+		if err == nil {
+			if _, err := tx.exec(ctx, releaseSavepointSQLStatement); err != nil {
+				return nil, err
+			}
+
+			return res, nil
+		}
+
+		if n >= attempts || !p.retryable(err) {
+			return nil, err
+		}
+
+		if _, rbErr := tx.exec(ctx, rollbackSavepointSQLStatement); rbErr != nil {
+			return nil, err
+		}
+
+		if !p.wait(ctx, n) {
+			return nil, err
+		}
+	}
+}
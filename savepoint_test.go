@@ -0,0 +1,57 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSavepointRetries(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     time.Millisecond,
+			Retryable:   func(err error) bool { return true },
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	db.SetPolicy(&Policy{
+		Labels: map[string]LabelPolicy{
+			"retried": {Savepoint: true},
+		},
+	})
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "CREATE TEMP SEQUENCE IF NOT EXISTS savepoint_retries"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// fails on the first attempt with division by zero
+	const flaky = "SELECT 1 / (nextval('savepoint_retries') % 2 - 1)"
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(WithLabel(ctx, "retried"), flaky); err != nil {
+		t.Fatalf("expected the statement to be retried, got: %s", err)
+	}
+
+	// transaction is not aborted
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := tx.Exec(ctx, flaky); err == nil {
+		t.Fatalf("expected the statement without the label not to be retried")
+	}
+}
@@ -0,0 +1,148 @@
+package ctxdb
+
+import (
+	"context"
+	"sync"
+)
+
+// Scope tracks the rows, transactions and statements created with a ctx
+// derived from the ctx of the scope, and closes the ones still open when the
+// scope ends, so no database resources outlive a request. Usage:
+//
+//     ctx, scope := ctxdb.NewScope(r.Context())
+//     defer scope.End()
+//
+// It's safe for concurrent use by multiple goroutines.
+type Scope struct {
+	mu    sync.Mutex
+	ended bool
+	rows  map[*Rows]struct{}
+	stmts map[*Stmt]struct{}
+	txs   map[*Tx]struct{}
+}
+
+// NewScope returns a copy of the parent context carrying a new scope, and the
+// scope.
+func NewScope(parent context.Context) (context.Context, *Scope) {
+	s := &Scope{
+		rows:  make(map[*Rows]struct{}),
+		stmts: make(map[*Stmt]struct{}),
+		txs:   make(map[*Tx]struct{}),
+	}
+
+	return context.WithValue(parent, scopeKey, s), s
+}
+
+// ScopeFromContext returns the scope set by NewScope, or nil if none is set.
+func ScopeFromContext(ctx context.Context) *Scope {
+	s, _ := ctx.Value(scopeKey).(*Scope)
+	return s
+}
+
+// End closes the rows and the statements, and rolls the transactions back,
+// which are created in the scope and still open. Returns the first error of
+// them. Resources created in the scope after End are closed immediately.
+func (s *Scope) End() error {
+	s.mu.Lock()
+	s.ended = true
+	rows, stmts, txs := s.rows, s.stmts, s.txs
+	s.rows, s.stmts, s.txs = nil, nil, nil
+	s.mu.Unlock()
+
+	// ctx of the scope is typically done by now
+	ctx := context.Background()
+
+	var firstErr error
+	keep := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// rows and statements may belong to the transactions
+	for rs := range rows {
+		keep(rs.drop(ctx))
+	}
+
+	for stmt := range stmts {
+		keep(stmt.Close(ctx))
+	}
+
+	for tx := range txs {
+		keep(tx.Rollback(ctx))
+	}
+
+	return firstErr
+}
+
+// trackRows tracks the rows in the scope of the ctx, if there is one
+func trackRows(ctx context.Context, rs *Rows) {
+	s := ScopeFromContext(ctx)
+	if s == nil || rs == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if !s.ended {
+		s.rows[rs] = struct{}{}
+		rs.scope = s
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	rs.drop(ctx)
+}
+
+// trackStmt tracks the statement in the scope of the ctx, if there is one
+func trackStmt(ctx context.Context, stmt *Stmt) {
+	s := ScopeFromContext(ctx)
+	if s == nil || stmt == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if !s.ended {
+		s.stmts[stmt] = struct{}{}
+		stmt.scope = s
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	stmt.Close(ctx)
+}
+
+// trackTx tracks the transaction in the scope of the ctx, if there is one
+func trackTx(ctx context.Context, tx *Tx) {
+	s := ScopeFromContext(ctx)
+	if s == nil || tx == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if !s.ended {
+		s.txs[tx] = struct{}{}
+		tx.scope = s
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	tx.Rollback(ctx)
+}
+
+// untrack removes a closed resource from the scope
+func (s *Scope) untrack(rs *Rows, stmt *Stmt, tx *Tx) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if !s.ended {
+		delete(s.rows, rs)
+		delete(s.stmts, stmt)
+		delete(s.txs, tx)
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,70 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx, scope := NewScope(context.Background())
+	if ScopeFromContext(ctx) != scope {
+		t.Fatal("expected the scope in the ctx")
+	}
+
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := db.Prepare(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// closed resources are not tracked anymore
+	closed, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := closed.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(scope.rows) != 1 || len(scope.txs) != 1 || len(scope.stmts) != 1 {
+		t.Fatalf("expected 1 rows, 1 tx and 1 stmt, got: %d, %d, %d", len(scope.rows), len(scope.txs), len(scope.stmts))
+	}
+
+	if err := scope.End(); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if !rows.isReleased() {
+		t.Fatal("expected the rows to be closed")
+	}
+
+	if _, err := tx.Exec(context.Background(), "SELECT 1"); err == nil {
+		t.Fatal("expected the transaction to be rolled back")
+	}
+
+	if stats := db.PoolStats(); stats.InUse != 0 {
+		t.Fatalf("expected no connection in use, got: %d", stats.InUse)
+	}
+
+	// resources created after the end are closed immediately
+	late, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if !late.isReleased() {
+		t.Fatal("expected the rows created after the end to be closed")
+	}
+}
@@ -0,0 +1,184 @@
+package ctxdb
+
+import (
+	"context"
+	"time"
+)
+
+// waiter is an operation waiting for a sem in the queue
+type waiter struct {
+	ready   chan struct{} // closed when the sem is granted
+	granted bool          // guarded by waitMu
+}
+
+// PoolStats holds the statistics of the pool.
+type PoolStats struct {
+	MaxOpenConns int // max number of open connections
+	OpenConns    int // number of the open connections, idle or in use
+	InUse        int // number of the connections in use
+	Idle         int // number of the idle connections
+	Standby      int // number of the pre-authenticated standby connections
+
+	// Waiting is the number of the operations waiting for a connection, it
+	// is the queue position of the next operation which waits.
+	Waiting int
+
+	// WaitCount is the total number of the operations waited for a
+	// connection, and WaitDuration is the total time they waited.
+	WaitCount    int64
+	WaitDuration time.Duration
+
+	Checkouts       int64 // total number of the connections taken for operations
+	FactoryFailures int64 // total number of the failed connection creations
+	CancelCloses    int64 // total number of the connections closed on cancellation
+}
+
+// Stats returns the statistics of the pool, aggregated over all the pooled
+// connections.
+func (db *DB) Stats(ctx context.Context) (PoolStats, error) {
+	if err := db.check(); err != nil {
+		return PoolStats{}, err
+	}
+
+	return db.PoolStats(), nil
+}
+
+// PoolStats returns the statistics of the pool, zero if the db is not
+// initialized.
+func (db *DB) PoolStats() PoolStats {
+	if err := db.check(); err != nil {
+		return PoolStats{}
+	}
+
+	db.waitMu.Lock()
+	s := PoolStats{
+		MaxOpenConns: cap(db.sem),
+		InUse:        cap(db.sem) - len(db.sem),
+		Waiting:      db.waiters.Len(),
+		WaitCount:    db.waitCount,
+		WaitDuration: db.waitDuration,
+	}
+	db.waitMu.Unlock()
+
+	db.mu.Lock()
+	s.OpenConns = len(db.infos)
+	s.Checkouts = db.checkouts
+	s.FactoryFailures = db.factoryFailures
+	s.CancelCloses = db.cancelCloses
+	s.Standby = len(db.standby)
+	db.mu.Unlock()
+
+	s.Idle = len(db.getConns())
+	return s
+}
+
+// acquire waits for a sem until ctx is done, or fails with ErrPoolExhausted
+// if no sem is free and waiting is disabled, or no sem is granted within the
+// acquire timeout. Waiting operations are granted the sems in FIFO order.
+func (db *DB) acquire(ctx context.Context) error {
+	db.waitMu.Lock()
+	if db.waiters.Len() == 0 {
+		select {
+		case <-db.sem:
+			db.waitMu.Unlock()
+			return nil
+		default:
+		}
+	}
+
+	if db.noWait || IsNoWait(ctx) {
+		db.waitMu.Unlock()
+		return ErrPoolExhausted
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	elem := db.waiters.PushBack(w)
+	acquireTimeout := db.acquireTimeout
+	db.waitMu.Unlock()
+
+	start := time.Now()
+	defer func() {
+		db.waitMu.Lock()
+		db.waitCount++
+		db.waitDuration += time.Since(start)
+		db.waitMu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if acquireTimeout > 0 {
+		t := time.NewTimer(acquireTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrPoolExhausted
+	}
+
+	db.waitMu.Lock()
+	granted := w.granted
+	if !granted {
+		db.waiters.Remove(elem)
+	}
+	db.waitMu.Unlock()
+
+	if granted {
+		// sem is granted in the meantime, pass it to the next one
+		db.release()
+	}
+
+	return err
+}
+
+// tryAcquire acquires a sem if one is free and no operation is waiting for one
+func (db *DB) tryAcquire() bool {
+	db.waitMu.Lock()
+	defer db.waitMu.Unlock()
+
+	if db.waiters.Len() > 0 {
+		return false
+	}
+
+	select {
+	case <-db.sem:
+		return true
+	default:
+		return false
+	}
+}
+
+// release gives the sem to the first waiting operation, or back to the pool.
+// Returns false if the sem overflows, when more sems are released than
+// acquired.
+func (db *DB) release() bool {
+	db.waitMu.Lock()
+	defer db.waitMu.Unlock()
+
+	if front := db.waiters.Front(); front != nil {
+		w := db.waiters.Remove(front).(*waiter)
+		w.granted = true
+		close(w.ready)
+		return true
+	}
+
+	select {
+	case db.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSaturated reports whether all the sems are in use or waited for
+func (db *DB) isSaturated() bool {
+	db.waitMu.Lock()
+	defer db.waitMu.Unlock()
+
+	return db.waiters.Len() > 0 || len(db.sem) == 0
+}
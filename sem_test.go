@@ -0,0 +1,160 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func waitForWaiting(t *testing.T, db *DB, n int) {
+	for i := 0; db.PoolStats().Waiting != n; i++ {
+		if i == 100 {
+			t.Fatalf("expected %d waiting, got: %d", n, db.PoolStats().Waiting)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAcquireFIFO(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	if err := db.acquire(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	order := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		i := i
+		go func() {
+			if err := db.acquire(ctx); err != nil {
+				t.Errorf("expected nil, got: %s", err)
+			}
+
+			order <- i
+			db.release()
+		}()
+
+		// enqueue the waiters one by one
+		waitForWaiting(t, db, i+1)
+	}
+
+	db.release()
+
+	for i := 0; i < 3; i++ {
+		if n := <-order; n != i {
+			t.Fatalf("expected waiter %d to be granted, got: %d", i, n)
+		}
+	}
+
+	stats := db.PoolStats()
+	if stats.WaitCount != 3 || stats.Waiting != 0 || stats.InUse != 0 {
+		t.Fatalf("expected 3 waits without any in use, got: %+v", stats)
+	}
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(1),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	if err := db.acquire(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+
+	if err := db.acquire(timeoutCtx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	// timed out waiter leaves the queue
+	if n := db.PoolStats().Waiting; n != 0 {
+		t.Fatalf("expected 0 waiting, got: %d", n)
+	}
+
+	if !db.release() {
+		t.Fatal("expected the sem to be released")
+	}
+
+	if db.release() {
+		t.Fatal("expected the sem to overflow")
+	}
+}
+
+func TestStats(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+
+	if _, err := db.Exec(timeoutCtx, "SELECT pg_sleep(1)"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	stats, err := db.Stats(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if stats.Checkouts != 2 || stats.CancelCloses != 1 || stats.OpenConns != 0 {
+		t.Fatalf("expected 2 checkouts and 1 close on cancellation, got: %+v", stats)
+	}
+}
+
+func TestStatsFactoryFailures(t *testing.T) {
+	db, err := OpenWithFactory(func() (*sql.DB, error) {
+		return nil, errors.New("unreachable")
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "SELECT 1"); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	stats, err := db.Stats(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if stats.FactoryFailures != 1 || stats.OpenConns != 0 {
+		t.Fatalf("expected 1 factory failure, got: %+v", stats)
+	}
+
+	var nilDB *DB
+	if _, err := nilDB.Stats(ctx); err != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got: %v", err)
+	}
+}
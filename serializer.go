@@ -0,0 +1,231 @@
+package ctxdb
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RowSerializer converts rows into an encoding, e.g. JSON, so the features
+// materializing rows share one conversion layer. Implementations for other
+// encodings are registered with RegisterSerializer.
+type RowSerializer interface {
+	// NewEncoder returns an encoder writing the rows with the given columns
+	// to w.
+	NewEncoder(w io.Writer, columns []string) (RowEncoder, error)
+}
+
+// RowEncoder writes the rows of a RowSerializer.
+type RowEncoder interface {
+	// Encode writes a row, values are in the order of the columns.
+	Encode(values []interface{}) error
+
+	// Close flushes the buffered rows, it does not close the writer.
+	Close() error
+}
+
+var (
+	serializersMu sync.RWMutex
+	serializers   = map[string]RowSerializer{
+		"json": JSONSerializer{},
+		"csv":  CSVSerializer{},
+		"gob":  GobSerializer{},
+	}
+)
+
+func init() {
+	// values of the time columns are encoded in interface values
+	gob.Register(time.Time{})
+}
+
+// RegisterSerializer makes a serializer available by the provided name. If
+// RegisterSerializer is called twice with the same name or if s is nil, it
+// panics.
+func RegisterSerializer(name string, s RowSerializer) {
+	serializersMu.Lock()
+	defer serializersMu.Unlock()
+
+	if s == nil {
+		panic("ctxdb: RegisterSerializer serializer is nil")
+	}
+
+	if _, dup := serializers[name]; dup {
+		panic("ctxdb: RegisterSerializer called twice for serializer " + name)
+	}
+
+	serializers[name] = s
+}
+
+// Serializer returns the serializer registered with the given name, ok is
+// false if there is none. "json", "csv" and "gob" are built in.
+func Serializer(name string) (s RowSerializer, ok bool) {
+	serializersMu.RLock()
+	s, ok = serializers[name]
+	serializersMu.RUnlock()
+	return s, ok
+}
+
+// Serializers returns the sorted names of the registered serializers.
+func Serializers() []string {
+	serializersMu.RLock()
+	names := make([]string, 0, len(serializers))
+	for name := range serializers {
+		names = append(names, name)
+	}
+	serializersMu.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// JSONSerializer writes every row as a JSON object keyed by the columns, one
+// object per line. Byte slices are written as strings.
+type JSONSerializer struct{}
+
+// NewEncoder implements RowSerializer.
+func (JSONSerializer) NewEncoder(w io.Writer, columns []string) (RowEncoder, error) {
+	bw := bufio.NewWriter(w)
+	return &jsonEncoder{w: bw, enc: json.NewEncoder(bw), columns: columns}, nil
+}
+
+type jsonEncoder struct {
+	w       *bufio.Writer
+	enc     *json.Encoder
+	columns []string
+}
+
+func (e *jsonEncoder) Encode(values []interface{}) error {
+	row := make(map[string]interface{}, len(e.columns))
+	for i, column := range e.columns {
+		if b, ok := values[i].([]byte); ok {
+			row[column] = string(b)
+			continue
+		}
+
+		row[column] = values[i]
+	}
+
+	return e.enc.Encode(row)
+}
+
+func (e *jsonEncoder) Close() error {
+	return e.w.Flush()
+}
+
+// CSVSerializer writes the rows as CSV records, after a header record of the
+// columns. NULL values are written as empty fields, times in RFC 3339.
+type CSVSerializer struct{}
+
+// NewEncoder implements RowSerializer.
+func (CSVSerializer) NewEncoder(w io.Writer, columns []string) (RowEncoder, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return nil, err
+	}
+
+	return &csvEncoder{w: cw, record: make([]string, len(columns))}, nil
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (e *csvEncoder) Encode(values []interface{}) error {
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+			e.record[i] = ""
+		case []byte:
+			e.record[i] = string(v)
+		case time.Time:
+			e.record[i] = v.Format(time.RFC3339Nano)
+		default:
+			e.record[i] = fmt.Sprint(v)
+		}
+	}
+
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// GobSerializer writes the columns as a []string, then every row as an
+// []interface{}, with encoding/gob.
+type GobSerializer struct{}
+
+// NewEncoder implements RowSerializer.
+func (GobSerializer) NewEncoder(w io.Writer, columns []string) (RowEncoder, error) {
+	bw := bufio.NewWriter(w)
+	enc := gob.NewEncoder(bw)
+	if err := enc.Encode(columns); err != nil {
+		return nil, err
+	}
+
+	return &gobEncoder{w: bw, enc: enc}, nil
+}
+
+type gobEncoder struct {
+	w   *bufio.Writer
+	enc *gob.Encoder
+}
+
+func (e *gobEncoder) Encode(values []interface{}) error {
+	return e.enc.Encode(values)
+}
+
+func (e *gobEncoder) Close() error {
+	return e.w.Flush()
+}
+
+// ExportTo runs Export, writing the rows to w with the given serializer. The
+// encoder is created with the columns of the first row, so nothing is written
+// if the export has no rows.
+func (db *DB) ExportTo(ctx context.Context, query string, w io.Writer, s RowSerializer, policy ExportPolicy) (ExportToken, error) {
+	// sink may outlive Export on timeout, mu guards the encoder against it
+	var mu sync.Mutex
+	var enc RowEncoder
+	var closed bool
+
+	sink := func(columns []string, values []interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if closed {
+			return ErrClosed
+		}
+
+		if enc == nil {
+			var err error
+			if enc, err = s.NewEncoder(w, columns); err != nil {
+				return err
+			}
+		}
+
+		return enc.Encode(values)
+	}
+
+	token, err := db.Export(ctx, query, sink, policy)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	closed = true
+	if enc != nil {
+		if closeErr := enc.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return token, err
+}
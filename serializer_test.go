@@ -0,0 +1,120 @@
+package ctxdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"io"
+	"testing"
+)
+
+func encodeRows(t *testing.T, s RowSerializer, columns []string, rows ...[]interface{}) string {
+	var buf bytes.Buffer
+	enc, err := s.NewEncoder(&buf, columns)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	return buf.String()
+}
+
+func TestJSONSerializer(t *testing.T) {
+	out := encodeRows(t, JSONSerializer{}, []string{"id", "name"},
+		[]interface{}{int64(1), []byte("foo")},
+		[]interface{}{int64(2), nil},
+	)
+
+	expected := "{\"id\":1,\"name\":\"foo\"}\n{\"id\":2,\"name\":null}\n"
+	if out != expected {
+		t.Fatalf("expected %q, got: %q", expected, out)
+	}
+}
+
+func TestCSVSerializer(t *testing.T) {
+	out := encodeRows(t, CSVSerializer{}, []string{"id", "name"},
+		[]interface{}{int64(1), []byte("foo, bar")},
+		[]interface{}{int64(2), nil},
+	)
+
+	expected := "id,name\n1,\"foo, bar\"\n2,\n"
+	if out != expected {
+		t.Fatalf("expected %q, got: %q", expected, out)
+	}
+}
+
+func TestGobSerializer(t *testing.T) {
+	out := encodeRows(t, GobSerializer{}, []string{"id", "name"},
+		[]interface{}{int64(1), "foo"},
+	)
+
+	dec := gob.NewDecoder(bytes.NewBufferString(out))
+
+	var columns []string
+	if err := dec.Decode(&columns); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var values []interface{}
+	if err := dec.Decode(&values); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(columns) != 2 || values[0] != int64(1) || values[1] != "foo" {
+		t.Fatalf("expected [id name] [1 foo], got: %v %v", columns, values)
+	}
+}
+
+type nopSerializer struct{}
+
+func (nopSerializer) NewEncoder(w io.Writer, columns []string) (RowEncoder, error) {
+	return nil, nil
+}
+
+func TestRegisterSerializer(t *testing.T) {
+	RegisterSerializer("nop", nopSerializer{})
+
+	if _, ok := Serializer("nop"); !ok {
+		t.Fatal("expected nop serializer to be registered")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for duplicate registration")
+		}
+	}()
+
+	RegisterSerializer("json", nopSerializer{})
+}
+
+func TestExportTo(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	query := "SELECT i AS id FROM generate_series(1, 3) i ORDER BY i"
+
+	var buf bytes.Buffer
+	s, _ := Serializer("csv")
+	token, err := db.ExportTo(ctx, query, &buf, s, ExportPolicy{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if token.Offset != 3 {
+		t.Fatalf("expected offset 3, got: %d", token.Offset)
+	}
+
+	if buf.String() != "id\n1\n2\n3\n" {
+		t.Fatalf("expected the csv of 3 rows, got: %q", buf.String())
+	}
+}
@@ -0,0 +1,159 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencySplit holds the client observed latency and the server reported
+// execution time of the sampled statements of a label, the difference is
+// spent on the network, the pool and the driver.
+type LatencySplit struct {
+	Label   string
+	Samples int64
+
+	// Client is the total latency of the sampled operations, Server is the
+	// total of the planning and execution times reported for them.
+	Client time.Duration
+	Server time.Duration
+}
+
+// Overhead returns the average time of a sample which is not spent on the
+// server.
+func (s LatencySplit) Overhead() time.Duration {
+	if s.Samples == 0 {
+		return 0
+	}
+
+	return (s.Client - s.Server) / time.Duration(s.Samples)
+}
+
+// WithServerTimeSampling samples the given rate of the SELECT statements run by
+// Query and QueryRow of the DB, e.g. 0.01 for 1%, and reruns them with
+// EXPLAIN (ANALYZE, TIMING) on a separate connection of the pool, for
+// correlating the client observed latency with the server execution time by
+// the statement labels, see WithLabel and LatencySplits. Sampled statements
+// run twice, so they must be read-only. Samples are skipped if no connection
+// is free. The database must support EXPLAIN ANALYZE, e.g. postgres.
+func WithServerTimeSampling(rate float64) Option {
+	return func(db *DB) {
+		db.serverTiming = &serverTiming{
+			rate:   rate,
+			splits: make(map[string]*LatencySplit),
+		}
+	}
+}
+
+// serverTiming holds the latency splits by labels
+type serverTiming struct {
+	rate float64
+
+	mu     sync.Mutex
+	splits map[string]*LatencySplit
+}
+
+// LatencySplits returns the latency splits of the sampled statements sorted by
+// their labels. It is nil if the sampling is not enabled.
+func (db *DB) LatencySplits() []LatencySplit {
+	if db == nil || db.serverTiming == nil {
+		return nil
+	}
+
+	st := db.serverTiming
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	labels := make([]string, 0, len(st.splits))
+	for label := range st.splits {
+		labels = append(labels, label)
+	}
+
+	sort.Strings(labels)
+
+	splits := make([]LatencySplit, len(labels))
+	for i, label := range labels {
+		splits[i] = *st.splits[label]
+	}
+
+	return splits
+}
+
+// sampleServerTime samples the server time of a successful operation if the
+// sampling is enabled
+func (db *DB) sampleServerTime(ctx context.Context, op, query string, args []interface{}, d time.Duration, err error) {
+	st := db.serverTiming
+	if st == nil || err != nil || (op != "Query" && op != "QueryRow") {
+		return
+	}
+
+	if verb, _ := parseStatement(query); verb != "SELECT" {
+		return
+	}
+
+	if rand.Float64() >= st.rate {
+		return
+	}
+
+	label := LabelFromContext(ctx)
+	go func() {
+		server, err := db.serverTime(query, args)
+		if err != nil {
+			return
+		}
+
+		st.mu.Lock()
+		s, ok := st.splits[label]
+		if !ok {
+			s = &LatencySplit{Label: label}
+			st.splits[label] = s
+		}
+
+		s.Samples++
+		s.Client += d
+		s.Server += server
+		st.mu.Unlock()
+	}()
+}
+
+// serverTime returns the planning and execution time of the query reported by
+// EXPLAIN ANALYZE, run on a pooled connection without waiting for one
+func (db *DB) serverTime(query string, args []interface{}) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(WithNoWaitContext(context.Background()), slowExplainTimeout)
+	defer cancel()
+
+	var data []byte
+	err := db.Raw(ctx, func(sqldb *sql.DB) error {
+		return sqldb.QueryRow("EXPLAIN (ANALYZE, TIMING, FORMAT JSON) "+query, args...).Scan(&data)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return parseServerTime(data)
+}
+
+// parseServerTime returns the total of the planning and execution times of the
+// given JSON formatted EXPLAIN ANALYZE output
+func parseServerTime(data []byte) (time.Duration, error) {
+	var plans []struct {
+		Planning  float64 `json:"Planning Time"`
+		Execution float64 `json:"Execution Time"`
+	}
+
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return 0, err
+	}
+
+	if len(plans) == 0 {
+		return 0, nil
+	}
+
+	// times are reported in milliseconds
+	ms := plans[0].Planning + plans[0].Execution
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
@@ -0,0 +1,65 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithServerTimeSampling(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithServerTimeSampling(1),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := WithLabel(context.Background(), "users/get")
+
+	var n int
+	if err := db.QueryRow(ctx, "SELECT 1").Scan(ctx, &n); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// writes are not sampled
+	if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var splits []LatencySplit
+	for i := 0; i < 100; i++ {
+		if splits = db.LatencySplits(); len(splits) > 0 {
+			break
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if len(splits) != 1 || splits[0].Label != "users/get" || splits[0].Samples != 1 {
+		t.Fatalf("expected 1 sample of users/get, got: %+v", splits)
+	}
+
+	if s := splits[0]; s.Client <= 0 || s.Server <= 0 {
+		t.Fatalf("expected the client and the server times, got: %+v", s)
+	}
+}
+
+func TestParseServerTime(t *testing.T) {
+	d, err := parseServerTime([]byte(`[{"Plan": {}, "Planning Time": 0.5, "Execution Time": 1.5}]`))
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if d != time.Millisecond*2 {
+		t.Fatalf("expected 2ms, got: %s", d)
+	}
+
+	s := LatencySplit{Samples: 2, Client: time.Millisecond * 10, Server: time.Millisecond * 4}
+	if s.Overhead() != time.Millisecond*3 {
+		t.Fatalf("expected 3ms overhead, got: %s", s.Overhead())
+	}
+}
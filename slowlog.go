@@ -0,0 +1,106 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// slowExplainTimeout is the timeout of explaining a slow query
+const slowExplainTimeout = 5 * time.Second
+
+// SlowQuery is the record of an operation exceeding the slow query threshold,
+// passed to the func set with WithSlowQueryLog.
+type SlowQuery struct {
+	Op       string
+	Query    string
+	Duration time.Duration
+	Err      error
+
+	// Plan is the text EXPLAIN output of the query if explaining is enabled,
+	// ExplainErr is the error of explaining it.
+	Plan       string
+	ExplainErr error
+}
+
+// WithSlowQueryLog calls f with the record of every Exec, Query and QueryRow of
+// the DB and its transactions lasting at least threshold. If explain is set,
+// SELECT, INSERT, UPDATE, DELETE and WITH statements are explained with their
+// args, without running them, on a separate connection of the pool before f is
+// called, in a separate goroutine. Explaining is skipped if no connection is
+// free, so it does not wait for the ones in use. The database must support
+// EXPLAIN, e.g. postgres.
+func WithSlowQueryLog(threshold time.Duration, explain bool, f func(q *SlowQuery)) Option {
+	return func(db *DB) {
+		db.slowLog = &slowLog{threshold: threshold, explain: explain, report: f}
+	}
+}
+
+// slowLog is the config of the slow query log
+type slowLog struct {
+	threshold time.Duration
+	explain   bool
+	report    func(q *SlowQuery)
+}
+
+// logSlow reports the operation if the slow query log is enabled and it is
+// slow
+func (db *DB) logSlow(op, query string, args []interface{}, d time.Duration, err error) {
+	l := db.slowLog
+	if l == nil || query == "" || d < l.threshold {
+		return
+	}
+
+	q := &SlowQuery{Op: op, Query: query, Duration: d, Err: err}
+
+	verb, _ := parseStatement(query)
+	switch verb {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+	default:
+		l.report(q)
+		return
+	}
+
+	if !l.explain {
+		l.report(q)
+		return
+	}
+
+	go func() {
+		q.Plan, q.ExplainErr = db.explainText(query, args)
+		l.report(q)
+	}()
+}
+
+// explainText returns the text plan of the query, explained on a pooled
+// connection without waiting for one
+func (db *DB) explainText(query string, args []interface{}) (string, error) {
+	ctx, cancel := context.WithTimeout(WithNoWaitContext(context.Background()), slowExplainTimeout)
+	defer cancel()
+
+	var lines []string
+	err := db.Raw(ctx, func(sqldb *sql.DB) error {
+		rows, err := sqldb.Query("EXPLAIN "+query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return err
+			}
+
+			lines = append(lines, line)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,54 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithSlowQueryLog(t *testing.T) {
+	slow := make(chan *SlowQuery, 2)
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithSlowQueryLog(time.Millisecond*50, true, func(q *SlowQuery) {
+			slow <- q
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := db.Exec(ctx, "SELECT pg_sleep($1)", 0.1); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var q *SlowQuery
+	select {
+	case q = <-slow:
+	case <-time.After(time.Second):
+		t.Fatal("expected the slow query to be logged")
+	}
+
+	if q.Op != "Exec" || q.Query != "SELECT pg_sleep($1)" || q.Duration < time.Millisecond*50 {
+		t.Fatalf("expected the record of the slow Exec, got: %+v", q)
+	}
+
+	if q.ExplainErr != nil || !strings.Contains(q.Plan, "Result") {
+		t.Fatalf("expected the plan of the slow query, got: %q, %v", q.Plan, q.ExplainErr)
+	}
+
+	select {
+	case q := <-slow:
+		t.Fatalf("expected only the slow query to be logged, got: %+v", q)
+	default:
+	}
+}
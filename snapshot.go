@@ -0,0 +1,259 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ErrInvalidSnapshotDest is returned by SnapshotTable if the dest is not a
+// non-nil pointer to a slice or a map of structs, struct pointers or
+// map[string]interface{}.
+var ErrInvalidSnapshotDest = errors.New("dest must be a pointer to a slice or a map of structs or map[string]interface{}")
+
+// Snapshot is an in-memory copy of a small, read-mostly table, e.g. a lookup
+// table, refreshed in the background. Reads are served from memory.
+type Snapshot struct {
+	db    *DB
+	query string
+	typ   reflect.Type // type of the slice or the map
+	every time.Duration
+	stop  chan struct{}
+	once  sync.Once
+
+	mu       sync.RWMutex
+	value    interface{}
+	loadedAt time.Time
+	err      error // error of the last refresh
+}
+
+// SnapshotTable loads the rows of the query into dest, a pointer to a slice or
+// a map, and keeps refreshing a snapshot of them at every refreshEvery, until
+// the Snapshot is closed. Elements of the slice or the map can be structs or
+// struct pointers, whose fields are matched with the columns like CollectIndex,
+// or map[string]interface{}. Maps are keyed by the first column.
+//
+// dest is only filled by the initial load, the latest rows are read from the
+// Snapshot. Zero refreshEvery disables the periodic refreshes, Refresh can be
+// called on demand, e.g. when a NOTIFY is received for a change of the table.
+func (db *DB) SnapshotTable(ctx context.Context, query string, dest interface{}, refreshEvery time.Duration) (*Snapshot, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, ErrInvalidSnapshotDest
+	}
+
+	typ := v.Elem().Type()
+	if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Map {
+		return nil, ErrInvalidSnapshotDest
+	}
+
+	if _, ok := snapshotElem(typ.Elem()); !ok {
+		return nil, ErrInvalidSnapshotDest
+	}
+
+	s := &Snapshot{
+		db:    db,
+		query: query,
+		typ:   typ,
+		every: refreshEvery,
+		stop:  make(chan struct{}),
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	v.Elem().Set(reflect.ValueOf(s.Value()))
+
+	if refreshEvery > 0 {
+		go s.run()
+	}
+
+	return s, nil
+}
+
+// Value returns the latest loaded slice or map, with its type of dest. Value
+// is shared between the readers, it must not be modified.
+func (s *Snapshot) Value() interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.value
+}
+
+// LoadedAt returns the time of the latest successful load.
+func (s *Snapshot) LoadedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.loadedAt
+}
+
+// Err returns the error of the last refresh, nil if it succeeded. Failed
+// refreshes keep the previous value.
+func (s *Snapshot) Err() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.err
+}
+
+// Refresh reloads the snapshot with the given ctx. Snapshot is replaced only
+// if the rows are loaded successfully.
+func (s *Snapshot) Refresh(ctx context.Context) error {
+	value, err := s.load(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+	if err != nil {
+		return err
+	}
+
+	s.value = value
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// Close stops the periodic refreshes. Snapshot keeps serving the latest value.
+func (s *Snapshot) Close() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+// run refreshes the snapshot on every interval until it is closed, or the db
+// is closed
+func (s *Snapshot) run() {
+	ticker := time.NewTicker(s.every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.every)
+		err := s.Refresh(ctx)
+		cancel()
+
+		if err == ErrNotInitialized {
+			return
+		}
+	}
+}
+
+// load reads the rows of the query into a new slice or map
+func (s *Snapshot) load(ctx context.Context) (interface{}, error) {
+	rows, err := s.db.Query(ctx, s.query)
+	if err != nil {
+		return nil, err
+	}
+
+	dest := reflect.New(s.typ)
+	if s.typ.Kind() == reflect.Map {
+		columns, err := rows.Columns(ctx)
+		if err != nil {
+			rows.drop(ctx)
+			return nil, err
+		}
+
+		if len(columns) == 0 {
+			rows.drop(ctx)
+			return nil, ErrInvalidSnapshotDest
+		}
+
+		if err := rows.CollectIndex(ctx, columns[0], dest.Interface()); err != nil {
+			return nil, err
+		}
+
+		return dest.Elem().Interface(), nil
+	}
+
+	if err := rows.collectSlice(ctx, dest.Elem()); err != nil {
+		return nil, err
+	}
+
+	return dest.Elem().Interface(), nil
+}
+
+// collectSlice consumes the rows appending them to the given slice, elements
+// are filled like CollectIndex
+func (rs *Rows) collectSlice(ctx context.Context, slice reflect.Value) error {
+	columns, err := rs.Columns(ctx)
+	if err != nil {
+		rs.drop(ctx)
+		return err
+	}
+
+	elemType := slice.Type().Elem()
+	structType, _ := snapshotElem(elemType)
+
+	var fields []int
+	if structType != nil {
+		fields = fieldIndexes(structType, columns)
+	}
+
+	slice.Set(reflect.MakeSlice(slice.Type(), 0, 0))
+	return rs.Reduce(ctx, func(scan ScanFunc) error {
+		if structType == nil {
+			values, err := scanValues(scan, len(columns))
+			if err != nil {
+				return err
+			}
+
+			row := make(map[string]interface{}, len(columns))
+			for i, column := range columns {
+				row[column] = values[i]
+			}
+
+			slice.Set(reflect.Append(slice, reflect.ValueOf(row)))
+			return nil
+		}
+
+		val := reflect.New(structType)
+		dest := make([]interface{}, len(columns))
+		for i, field := range fields {
+			if field >= 0 {
+				dest[i] = val.Elem().Field(field).Addr().Interface()
+			} else {
+				dest[i] = new(interface{})
+			}
+		}
+
+		if err := scan(dest...); err != nil {
+			return err
+		}
+
+		if elemType.Kind() == reflect.Ptr {
+			slice.Set(reflect.Append(slice, val))
+		} else {
+			slice.Set(reflect.Append(slice, val.Elem()))
+		}
+
+		return nil
+	})
+}
+
+// snapshotElem returns the struct type of the given element type, nil if the
+// elements are map[string]interface{}. ok is false if the type is neither.
+func snapshotElem(t reflect.Type) (structType reflect.Type, ok bool) {
+	if t == reflect.TypeOf(map[string]interface{}(nil)) {
+		return nil, true
+	}
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	return t, true
+}
@@ -0,0 +1,76 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSnapshotTable(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+	ctx := context.Background()
+
+	type user struct {
+		ID   int64
+		Name string `db:"name"`
+	}
+
+	var users []user
+	s, err := db.SnapshotTable(ctx, collectSQLStatement, &users, 0)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer s.Close()
+
+	if len(users) != 3 || users[1].ID != 2 || users[1].Name != "name2" {
+		t.Fatalf("expected 3 users, got: %+v", users)
+	}
+
+	if got := s.Value().([]user); len(got) != 3 {
+		t.Fatalf("expected 3 users in the snapshot, got: %+v", got)
+	}
+
+	loadedAt := s.LoadedAt()
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if !s.LoadedAt().After(loadedAt) {
+		t.Fatal("expected the snapshot to be reloaded")
+	}
+}
+
+func TestSnapshotTableMap(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+	ctx := context.Background()
+
+	var byID map[int64]map[string]interface{}
+	s, err := db.SnapshotTable(ctx, collectSQLStatement, &byID, time.Millisecond*10)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer s.Close()
+
+	if len(byID) != 3 || byID[3]["name"] != "name3" {
+		t.Fatalf("expected 3 rows by ids, got: %v", byID)
+	}
+
+	loadedAt := s.LoadedAt()
+	time.Sleep(time.Millisecond * 50)
+
+	if !s.LoadedAt().After(loadedAt) {
+		t.Fatal("expected the snapshot to be refreshed periodically")
+	}
+}
+
+func TestSnapshotTableInvalidDest(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	var n int
+	if _, err := db.SnapshotTable(context.Background(), collectSQLStatement, &n, 0); err != ErrInvalidSnapshotDest {
+		t.Fatalf("expected ErrInvalidSnapshotDest, got: %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package ctxdb
+
+import (
+	"context"
+	"strings"
+)
+
+// softDeleteCondition is appended to the SELECTs of the soft deleted tables,
+// qualified with the table or its alias
+const softDeleteCondition = ".deleted_at IS NULL"
+
+// SoftDelete returns a middleware which filters the soft deleted rows of the
+// given tables out of the SELECTs, appending deleted_at IS NULL to their WHERE
+// clauses, unless the ctx of the operation is marked with WithIncludeDeleted,
+// e.g.
+//
+//     db, err := ctxdb.Open(driver, dsn,
+//         ctxdb.WithMiddleware(ctxdb.SoftDelete("users", "orders")))
+//
+// Rewriting is naive like the statement detection of the other features, only
+// the table right after the top-level FROM of a SELECT is filtered; joined
+// tables, subqueries and the other SELECTs of a UNION are left as they are.
+// Tables are matched case insensitively, with or without their schemas.
+func SoftDelete(tables ...string) Middleware {
+	registered := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		registered[strings.ToLower(table)] = true
+	}
+
+	return func(next Executor) Executor {
+		return func(ctx context.Context, op *Op) error {
+			if !IsIncludeDeleted(ctx) {
+				op.Query = filterDeleted(op.Query, registered)
+			}
+
+			return next(ctx, op)
+		}
+	}
+}
+
+// word is a word of a query outside of the parentheses
+type word struct {
+	text       string
+	start, end int
+}
+
+// clauseKeywords end the WHERE clause of a SELECT, or the FROM clause of one
+// without a WHERE clause
+var clauseKeywords = map[string]bool{
+	"GROUP": true, "HAVING": true, "WINDOW": true, "ORDER": true, "LIMIT": true,
+	"OFFSET": true, "FETCH": true, "FOR": true, "UNION": true, "INTERSECT": true,
+	"EXCEPT": true, "RETURNING": true,
+}
+
+// joinKeywords can follow a table in a FROM clause, they are not aliases
+var joinKeywords = map[string]bool{
+	"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true,
+	"FULL": true, "CROSS": true, "NATURAL": true, "ON": true, "USING": true,
+	"TABLESAMPLE": true,
+}
+
+// filterDeleted appends the soft delete condition to the WHERE clause of the
+// given SELECT, if the table of its top-level FROM is one of the given ones
+func filterDeleted(query string, tables map[string]bool) string {
+	if verb, _ := parseStatement(query); verb != "SELECT" {
+		return query
+	}
+
+	words := topLevelWords(query)
+
+	from := -1
+	for i, w := range words {
+		if strings.EqualFold(w.text, "FROM") {
+			from = i
+			break
+		}
+	}
+
+	t := from + 1
+	if from < 0 || t >= len(words) {
+		return query
+	}
+
+	if strings.EqualFold(words[t].text, "ONLY") {
+		t++
+		if t >= len(words) {
+			return query
+		}
+	}
+
+	// FROM (SELECT ...) alias is a subquery, its alias is not a table
+	if !adjacent(query, words[t-1], words[t]) || !isSoftDeleted(words[t].text, tables) {
+		return query
+	}
+
+	qualifier := words[t].text
+	next := t + 1
+	if next < len(words) && strings.EqualFold(words[next].text, "AS") && next+1 < len(words) {
+		qualifier = words[next+1].text
+		next += 2
+	} else if next < len(words) && adjacent(query, words[t], words[next]) &&
+		!joinKeywords[strings.ToUpper(words[next].text)] &&
+		!clauseKeywords[strings.ToUpper(words[next].text)] {
+		qualifier = words[next].text
+		next++
+	}
+
+	where := -1
+	end := len(query)
+	for i := next; i < len(words); i++ {
+		keyword := strings.ToUpper(words[i].text)
+		if keyword == "WHERE" && where < 0 {
+			where = i
+			continue
+		}
+
+		if clauseKeywords[keyword] {
+			end = words[i].start
+			break
+		}
+	}
+
+	if end == len(query) {
+		// trailing semicolon is kept at the end
+		end = len(strings.TrimRight(query, " \t\r\n;"))
+	}
+
+	condition := qualifier + softDeleteCondition
+
+	tail := query[end:]
+	if tail != "" && tail[0] != ';' {
+		tail = " " + tail
+	}
+
+	if where < 0 {
+		return strings.TrimRight(query[:end], " \t\r\n") + " WHERE " + condition + tail
+	}
+
+	expr := strings.TrimSpace(query[words[where].end:end])
+	return query[:words[where].end] + " (" + expr + ") AND " + condition + tail
+}
+
+// topLevelWords returns the words of the query outside of the parentheses, the
+// quoted strings and the comments. Qualified names and quoted identifiers are
+// single words.
+func topLevelWords(query string) []word {
+	var words []word
+	var depth int
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		case isNameChar(c) || c == '"' || c == '.':
+			j := i
+			for j < len(query) {
+				if query[j] == '"' {
+					j += skipped(query, j)
+					continue
+				}
+
+				if !isNameChar(query[j]) && query[j] != '.' {
+					break
+				}
+
+				j++
+			}
+
+			if depth == 0 {
+				words = append(words, word{text: query[i:j], start: i, end: j})
+			}
+
+			i = j
+		default:
+			if n := skipped(query, i); n > 0 {
+				i += n
+				continue
+			}
+
+			i++
+		}
+	}
+
+	return words
+}
+
+// adjacent reports whether only spaces are between the given words
+func adjacent(query string, a, b word) bool {
+	return strings.TrimSpace(query[a.end:b.start]) == ""
+}
+
+// isSoftDeleted reports whether the given table is one of the tables, with or
+// without its schema
+func isSoftDeleted(table string, tables map[string]bool) bool {
+	table = strings.ToLower(strings.Replace(table, `"`, "", -1))
+	if tables[table] {
+		return true
+	}
+
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		return tables[table[i+1:]]
+	}
+
+	return false
+}
@@ -0,0 +1,70 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFilterDeleted(t *testing.T) {
+	tables := map[string]bool{"users": true}
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{
+			"SELECT * FROM users",
+			"SELECT * FROM users WHERE users.deleted_at IS NULL",
+		},
+		{
+			"SELECT * FROM users;",
+			"SELECT * FROM users WHERE users.deleted_at IS NULL;",
+		},
+		{
+			"SELECT id FROM users WHERE id = $1 OR name = $2 ORDER BY id LIMIT 1",
+			"SELECT id FROM users WHERE (id = $1 OR name = $2) AND users.deleted_at IS NULL ORDER BY id LIMIT 1",
+		},
+		{
+			"SELECT u.id FROM public.users u JOIN orders o ON o.user_id = u.id",
+			"SELECT u.id FROM public.users u JOIN orders o ON o.user_id = u.id WHERE u.deleted_at IS NULL",
+		},
+		{
+			`SELECT x.id FROM "Users" AS x GROUP BY x.id`,
+			`SELECT x.id FROM "Users" AS x WHERE x.deleted_at IS NULL GROUP BY x.id`,
+		},
+		{
+			"SELECT id FROM users WHERE id IN (SELECT user_id FROM orders WHERE total > 0)",
+			"SELECT id FROM users WHERE (id IN (SELECT user_id FROM orders WHERE total > 0)) AND users.deleted_at IS NULL",
+		},
+		// other tables, subqueries and statements are left as they are
+		{"SELECT * FROM orders", "SELECT * FROM orders"},
+		{"SELECT * FROM (SELECT * FROM orders) users", "SELECT * FROM (SELECT * FROM orders) users"},
+		{"DELETE FROM users WHERE id = 1", "DELETE FROM users WHERE id = 1"},
+		{"SELECT 'FROM users'", "SELECT 'FROM users'"},
+	}
+
+	for _, test := range tests {
+		if got := filterDeleted(test.query, tables); got != test.expected {
+			t.Fatalf("expected %q, got: %q", test.expected, got)
+		}
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	var query string
+	next := SoftDelete("users")(func(ctx context.Context, op *Op) error {
+		query = op.Query
+		return nil
+	})
+
+	ctx := context.Background()
+	next(ctx, &Op{Name: "Query", Query: "SELECT * FROM users"})
+	if query != "SELECT * FROM users WHERE users.deleted_at IS NULL" {
+		t.Fatalf("expected the deleted rows to be filtered, got: %q", query)
+	}
+
+	next(WithIncludeDeleted(ctx), &Op{Name: "Query", Query: "SELECT * FROM users"})
+	if query != "SELECT * FROM users" {
+		t.Fatalf("expected the deleted rows to be included, got: %q", query)
+	}
+}
@@ -0,0 +1,151 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// standbyPingTimeout is the max time of authenticating a standby connection
+const standbyPingTimeout = time.Second * 5
+
+// SetStandbyConns sets the number of the standby connections, which are
+// created and pinged by a background filler before they are needed, so the
+// TLS handshake and the authentication are done out of the request path.
+// When the pool has no idle connection, e.g. after a connection is closed on
+// cancellation, operations take a standby connection instead of connecting,
+// and the filler replaces it. Standby connections are not counted against the
+// max open conns until they are taken. Zero disables the standby connections,
+// closing the existing ones.
+func (db *DB) SetStandbyConns(n int) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.standbyConns = n
+	if n > 0 && db.standbyStop == nil && db.conns != nil {
+		db.standbyWake = make(chan struct{}, 1)
+		db.standbyStop = make(chan struct{})
+		go db.fillStandby(db.standbyWake, db.standbyStop)
+	}
+	db.wakeStandbyLocked()
+	db.mu.Unlock()
+}
+
+// stopStandby stops the filler if it is running, the filler closes the
+// standby connections
+func (db *DB) stopStandby() {
+	db.mu.Lock()
+	if db.standbyStop != nil {
+		close(db.standbyStop)
+	}
+	db.mu.Unlock()
+}
+
+// wakeStandbyLocked wakes the filler if it is running, without waiting for it
+func (db *DB) wakeStandbyLocked() {
+	if db.standbyWake == nil {
+		return
+	}
+
+	select {
+	case db.standbyWake <- struct{}{}:
+	default:
+	}
+}
+
+// takeStandby takes a standby connection, or returns nil if there is none.
+// Connections of a replaced factory are closed.
+func (db *DB) takeStandby() *sql.DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for len(db.standby) > 0 {
+		conn := db.standby[0]
+		db.standby = db.standby[1:]
+
+		if db.isStaleLocked(conn) {
+			db.closeConnLocked(conn)
+			continue
+		}
+
+		db.wakeStandbyLocked()
+		return conn
+	}
+
+	return nil
+}
+
+// fillStandby keeps the standby connections filled until stop is closed, on
+// every wake and interval, then closes them
+func (db *DB) fillStandby(wake, stop chan struct{}) {
+	ticker := time.NewTicker(minIdleInterval)
+	defer ticker.Stop()
+
+	for {
+		db.refillStandby()
+
+		select {
+		case <-stop:
+			db.mu.Lock()
+			for _, conn := range db.standby {
+				db.closeConnLocked(conn)
+			}
+			db.standby = nil
+			db.mu.Unlock()
+
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// refillStandby closes the excess, stale and expired standby connections, and
+// creates and pings new ones until there are enough. Stops on the first error,
+// it is retried on the next interval.
+func (db *DB) refillStandby() {
+	db.mu.Lock()
+	now := time.Now()
+	kept := db.standby[:0]
+	for _, conn := range db.standby {
+		if len(kept) >= db.standbyConns || db.isStaleLocked(conn) || db.isExpiredLocked(conn, now) {
+			db.closeConnLocked(conn)
+			continue
+		}
+
+		kept = append(kept, conn)
+	}
+	db.standby = kept
+	db.mu.Unlock()
+
+	for {
+		db.mu.Lock()
+		full := db.conns == nil || len(db.standby) >= db.standbyConns
+		db.mu.Unlock()
+
+		if full {
+			return
+		}
+
+		conn, err := db.newConn(context.Background())
+		if err != nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), standbyPingTimeout)
+		err = dbPing(ctx, conn)
+		cancel()
+
+		db.mu.Lock()
+		if err != nil || db.conns == nil {
+			db.closeConnLocked(conn)
+			db.mu.Unlock()
+			return
+		}
+
+		db.standby = append(db.standby, conn)
+		db.mu.Unlock()
+	}
+}
@@ -0,0 +1,57 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSetStandbyConns(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(2),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	var created int
+	db.hooks.OnConnectionNew = func(e ConnEvent) { created++ }
+
+	db.SetStandbyConns(1)
+	time.Sleep(time.Millisecond * 100)
+
+	if s := db.PoolStats(); s.Standby != 1 || s.Idle != 0 {
+		t.Fatalf("expected 1 standby and no idle connections, got: %+v", s)
+	}
+
+	// operation takes the standby connection, instead of connecting
+	if err := db.Ping(context.Background()); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	if s := db.PoolStats(); s.Standby != 1 || s.Idle != 1 {
+		t.Fatalf("expected the standby connection to be replaced, got: %+v", s)
+	}
+
+	// hooks are fired under mu
+	db.mu.Lock()
+	n := created
+	db.mu.Unlock()
+
+	if n != 2 {
+		t.Fatalf("expected 2 connections to be created in the background, got: %d", n)
+	}
+
+	db.SetStandbyConns(0)
+	time.Sleep(time.Millisecond * 100)
+
+	if s := db.PoolStats(); s.Standby != 0 || s.OpenConns != 1 {
+		t.Fatalf("expected the standby connections to be closed, got: %+v", s)
+	}
+}
@@ -0,0 +1,73 @@
+package ctxdb
+
+import "strings"
+
+// parseStatement detects the verb and the target table of the given statement.
+// Detection is naive, it only looks at the leading keywords of the statement,
+// skipping the leading comments. Verb is upper cased, table is returned as
+// written in the statement without its quotes. Table is empty if not detected.
+func parseStatement(query string) (verb, table string) {
+	words := strings.Fields(stripLeadingComments(query))
+	if len(words) == 0 {
+		return "", ""
+	}
+
+	verb = strings.ToUpper(words[0])
+
+	var after string
+	switch verb {
+	case "UPDATE":
+		after = "UPDATE"
+	case "DELETE", "SELECT":
+		after = "FROM"
+	case "INSERT":
+		after = "INTO"
+	default:
+		return verb, ""
+	}
+
+	for i, word := range words {
+		if strings.ToUpper(word) != after || i+1 >= len(words) {
+			continue
+		}
+
+		table = words[i+1]
+		if strings.ToUpper(table) == "ONLY" && i+2 < len(words) {
+			table = words[i+2]
+		}
+
+		break
+	}
+
+	if i := strings.IndexAny(table, "(;,"); i >= 0 {
+		table = table[:i]
+	}
+
+	table = strings.Replace(table, `"`, "", -1)
+	return verb, table
+}
+
+// stripLeadingComments removes the comments at the beginning of the query
+func stripLeadingComments(query string) string {
+	for {
+		query = strings.TrimSpace(query)
+		switch {
+		case strings.HasPrefix(query, "--"):
+			i := strings.Index(query, "\n")
+			if i < 0 {
+				return ""
+			}
+
+			query = query[i+1:]
+		case strings.HasPrefix(query, "/*"):
+			i := strings.Index(query, "*/")
+			if i < 0 {
+				return ""
+			}
+
+			query = query[i+2:]
+		default:
+			return query
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package ctxdb
+
+import "testing"
+
+func TestParseStatement(t *testing.T) {
+	tests := []struct {
+		query string
+		verb  string
+		table string
+	}{
+		{"UPDATE users SET name = $1", "UPDATE", "users"},
+		{"update ONLY users set name = $1", "UPDATE", "users"},
+		{"DELETE FROM \"users\" WHERE id = $1", "DELETE", "users"},
+		{"  -- comment\n/* another */ DELETE FROM public.users;", "DELETE", "public.users"},
+		{"INSERT INTO users(id) VALUES ($1)", "INSERT", "users"},
+		{"SELECT id FROM users, groups", "SELECT", "users"},
+		{"SELECT 1", "SELECT", ""},
+		{"VACUUM users", "VACUUM", ""},
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		verb, table := parseStatement(test.query)
+		if verb != test.verb || table != test.table {
+			t.Fatalf("expected %s %s for %q, got: %s %s", test.verb, test.table, test.query, verb, table)
+		}
+	}
+}
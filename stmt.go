@@ -1,24 +1,47 @@
 package ctxdb
 
 import (
+	"context"
 	"database/sql"
-
-	"golang.org/x/net/context"
 )
 
 type Stmt struct {
+	id    uint64
 	stmt  *sql.Stmt
 	query string
+	names []string // names of the named params, in the order of the placeholders
 	err   error
 	sqldb *sql.DB
 	db    *DB
+	tx    *Tx    // transaction of the statements of Tx.Prepare and Tx.Stmt
+	scope *Scope // scope tracking the statement
+}
+
+// check returns ErrNotInitialized if s is nil or not created by a Prepare
+func (s *Stmt) check() error {
+	if s == nil || (s.err == nil && (s.stmt == nil || s.db == nil)) {
+		return ErrNotInitialized
+	}
+
+	return nil
 }
 
 func (s *Stmt) Close(ctx context.Context) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+
 	if s.err != nil {
 		return s.err
 	}
 
+	s.scope.untrack(nil, s, nil)
+
+	if s.tx != nil {
+		// closed with the transaction if it is not closed before
+		return s.stmt.Close()
+	}
+
 	done := make(chan struct{}, 0)
 
 	var err error
@@ -27,7 +50,7 @@ func (s *Stmt) Close(ctx context.Context) error {
 		close(done)
 	}
 
-	if opErr := s.db.handleWithGivenSQL(ctx, f, done, s.sqldb); err != nil {
+	if opErr := s.db.handleWithGivenSQL(ctx, f, done, s.sqldb); opErr != nil {
 		return opErr
 	}
 
@@ -37,12 +60,37 @@ func (s *Stmt) Close(ctx context.Context) error {
 // Exec executes a prepared statement with the given arguments and returns a Result
 // summarizing the effect of the statement.
 //
-// Exec prepares the same statement on another connection and executes it
-func (s *Stmt) Exec(ctx context.Context, args ...interface{}) (sql.Result, error) {
+// Exec prepares the same statement on another connection and executes it,
+// statements of a transaction are executed on the connection of the
+// transaction.
+func (s *Stmt) Exec(ctx context.Context, args ...interface{}) (*Result, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
 	if s.err != nil {
 		return nil, s.err
 	}
 
+	args, bindErr := s.bind(args)
+	if bindErr != nil {
+		return nil, bindErr
+	}
+
+	if s.tx != nil {
+		return s.tx.execWith(ctx, s.query, args, func() (sql.Result, error) {
+			return stmtExec(ctx, s.stmt, args)
+		})
+	}
+
+	if err := prepareArgs(args); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.guardDryRun(ctx); err != nil {
+		return nil, err
+	}
+
 	done := make(chan struct{}, 0)
 
 	var res sql.Result
@@ -51,31 +99,59 @@ func (s *Stmt) Exec(ctx context.Context, args ...interface{}) (sql.Result, error
 		defer close(done)
 
 		var stmt *sql.Stmt
-		stmt, err = sqldb.Prepare(s.query)
+		stmt, err = dbPrepare(ctx, sqldb, s.query)
 		if err != nil {
 			return
 		}
 
-		res, err = stmt.Exec(args...)
-
+		res, err = stmtExec(ctx, stmt, args)
 	}
 
-	if opErr := s.db.process(ctx, f, done); opErr != nil {
+	if opErr := s.db.processContext(ctx, f, done); opErr != nil {
 		return nil, opErr
 	}
 
-	return res, err
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{id: s.id, res: res, query: s.query}, nil
 }
 
 // Query executes a prepared query statement with the given arguments and
 // returns the query results as a *Rows.
 //
-// Query prepares the same statement on another connection and queries it
+// Query prepares the same statement on another connection and queries it,
+// statements of a transaction are queried on the connection of the
+// transaction.
 func (s *Stmt) Query(ctx context.Context, args ...interface{}) (*Rows, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
 	if s.err != nil {
 		return nil, s.err
 	}
 
+	args, bindErr := s.bind(args)
+	if bindErr != nil {
+		return nil, bindErr
+	}
+
+	if s.tx != nil {
+		return s.tx.queryWith(ctx, s.query, args, func() (*sql.Rows, error) {
+			return s.stmt.Query(args...)
+		})
+	}
+
+	if err := prepareArgs(args); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.guardDryRun(ctx); err != nil {
+		return nil, err
+	}
+
 	done := make(chan struct{}, 0)
 
 	var res *sql.Rows
@@ -104,9 +180,11 @@ func (s *Stmt) Query(ctx context.Context, args ...interface{}) (*Rows, error) {
 	}
 
 	return &Rows{
-		rows:  res,
-		sqldb: sqldb,
-		db:    s.db,
+		id:     s.id,
+		rows:   res,
+		sqldb:  sqldb,
+		db:     s.db,
+		origin: ctx,
 	}, nil
 }
 
@@ -116,10 +194,35 @@ func (s *Stmt) Query(ctx context.Context, args ...interface{}) (*Rows, error) {
 // selects no rows, the *Row's Scan will return ErrNoRows. Otherwise, the *Row's
 // Scan scans the first selected row and discards the rest.
 //
-// QueryRow prepares the same statement on another connection and queries it
+// QueryRow prepares the same statement on another connection and queries it,
+// statements of a transaction are queried on the connection of the
+// transaction.
 func (s *Stmt) QueryRow(ctx context.Context, args ...interface{}) *Row {
+	if err := s.check(); err != nil {
+		return &Row{err: err}
+	}
+
 	if s.err != nil {
-		return &Row{err: s.err}
+		return &Row{id: s.id, err: s.err}
+	}
+
+	args, bindErr := s.bind(args)
+	if bindErr != nil {
+		return &Row{id: s.id, err: bindErr}
+	}
+
+	if s.tx != nil {
+		return s.tx.queryRowWith(ctx, s.query, args, func() *sql.Row {
+			return s.stmt.QueryRow(args...)
+		})
+	}
+
+	if err := prepareArgs(args); err != nil {
+		return &Row{id: s.id, err: err}
+	}
+
+	if err := s.db.guardDryRun(ctx); err != nil {
+		return &Row{id: s.id, err: err}
 	}
 
 	done := make(chan struct{}, 0)
@@ -139,12 +242,28 @@ func (s *Stmt) QueryRow(ctx context.Context, args ...interface{}) *Row {
 
 	sqldb, opErr := s.db.handleWithSQL(ctx, f, done)
 	if opErr != nil {
-		return &Row{err: opErr}
+		return &Row{id: s.id, err: opErr}
 	}
 
 	return &Row{
+		id:    s.id,
 		row:   res,
 		sqldb: sqldb,
 		db:    s.db,
 	}
 }
+
+// bind returns the positional args of the statement for the given args, which
+// are either named or positional
+func (s *Stmt) bind(args []interface{}) ([]interface{}, error) {
+	if len(s.names) == 0 {
+		return args, nil
+	}
+
+	values, err := namedValues(args)
+	if values == nil || err != nil {
+		return args, err
+	}
+
+	return bindArgs(s.names, values)
+}
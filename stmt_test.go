@@ -1,9 +1,8 @@
 package ctxdb
 
 import (
+	"context"
 	"testing"
-
-	"golang.org/x/net/context"
 )
 
 func TestStmt(t *testing.T) {
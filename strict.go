@@ -0,0 +1,87 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrMisuse is matched by the MisuseErrors of the strict mode with errors.Is.
+var ErrMisuse = errors.New("misuse of ctxdb")
+
+// MisuseError describes a misuse of the API found by the strict mode, with
+// the guidance to fix it.
+type MisuseError struct {
+	Op      string // misused operation, e.g. "Rows.Scan"
+	Problem string // what is wrong
+	Hint    string // how to fix it
+}
+
+func (e *MisuseError) Error() string {
+	return "ctxdb: misuse of " + e.Op + ": " + e.Problem + "; " + e.Hint
+}
+
+// Is reports the MisuseError as ErrMisuse for errors.Is.
+func (e *MisuseError) Is(target error) bool {
+	return target == ErrMisuse
+}
+
+// WithStrictMode turns the misuses of the API which are otherwise silent, or
+// fail with an obscure error, into MisuseErrors; Rows.Scan without a
+// successful Next, Rows.Next after Close, Rows.Close called twice, using a
+// Tx after its Commit or Rollback, and Exec with a ctx without a deadline.
+// Rollback after Commit is not a misuse, so it can be deferred. Misuses panic
+// with the MisuseError if panics is set, e.g. to surface them in CI.
+// Next reports its misuse from Err, if it does not panic.
+func WithStrictMode(panics bool) Option {
+	return func(db *DB) {
+		db.strict = true
+		db.strictPanics = panics
+	}
+}
+
+// misuse returns the MisuseError of the given misuse, or nil if the strict
+// mode is not enabled. Panics with it if the strict mode panics.
+func (db *DB) misuse(op, problem, hint string) error {
+	if !db.strict {
+		return nil
+	}
+
+	err := &MisuseError{Op: op, Problem: problem, Hint: hint}
+	if db.strictPanics {
+		panic(err)
+	}
+
+	return err
+}
+
+// misuseNoDeadline returns the misuse of a write without a deadline, nil if
+// the ctx has a deadline. Policies do not apply to the writes of the
+// transactions, so they are not hinted for them.
+func (db *DB) misuseNoDeadline(ctx context.Context, op string) error {
+	if !db.strict {
+		return nil
+	}
+
+	if _, ok := ctx.Deadline(); ok {
+		return nil
+	}
+
+	hint := "bound the write with context.WithTimeout, or set a policy timeout for its label"
+	if strings.HasPrefix(op, "Tx.") {
+		hint = "bound the write with context.WithTimeout"
+	}
+
+	return db.misuse(op, "ctx has no deadline", hint)
+}
+
+// misuseTxDone returns the misuse of using a finished transaction
+func (tx *Tx) misuseTxDone(op string) error {
+	if !tx.finished || tx.stickyErr != nil {
+		// sticky error tells why it is finished
+		return nil
+	}
+
+	return tx.db.misuse(op, "transaction is already committed or rolled back",
+		"begin a new transaction for the operations after Commit")
+}
@@ -0,0 +1,100 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithStrictMode(t *testing.T) {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithStrictMode(false),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	isMisuse := func(err error) bool {
+		m, ok := err.(*MisuseError)
+		return ok && m.Is(ErrMisuse)
+	}
+
+	if _, err := db.Exec(context.Background(), "SELECT 1"); !isMisuse(err) {
+		t.Fatalf("expected the misuse of Exec without deadline, got: %v", err)
+	}
+
+	// default timeout bounds the writes
+	db.SetDefaultTimeout(time.Second * 5)
+	if _, err := db.Exec(context.Background(), "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	db.SetDefaultTimeout(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	rows, err := db.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var n int
+	if err := rows.Scan(ctx, &n); !isMisuse(err) {
+		t.Fatalf("expected the misuse of Scan without Next, got: %v", err)
+	}
+
+	if !rows.Next(ctx) {
+		t.Fatalf("expected a row, got: %v", rows.Err())
+	}
+
+	if err := rows.Scan(ctx, &n); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if rows.Next(ctx) || !isMisuse(rows.Err()) {
+		t.Fatalf("expected the misuse of Next after Close, got: %v", rows.Err())
+	}
+
+	if err := rows.Close(ctx); !isMisuse(err) {
+		t.Fatalf("expected the misuse of Close called twice, got: %v", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT 1"); !isMisuse(err) {
+		t.Fatalf("expected the misuse of Tx after Commit, got: %v", err)
+	}
+
+	// deferred Rollback after Commit is not a misuse
+	if err := tx.Rollback(ctx); isMisuse(err) {
+		t.Fatalf("expected Rollback after Commit not to be a misuse, got: %v", err)
+	}
+}
+
+func TestWithStrictModePanics(t *testing.T) {
+	db := &DB{}
+	WithStrictMode(true)(db)
+
+	defer func() {
+		if _, ok := recover().(*MisuseError); !ok {
+			t.Fatalf("expected a MisuseError panic")
+		}
+	}()
+
+	db.misuseNoDeadline(context.Background(), "Exec")
+}
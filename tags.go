@@ -0,0 +1,74 @@
+package ctxdb
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// WithTag returns a copy of the parent context which tags the operations
+// using it with the given key and value, overriding the value of the same key
+// set by the parent. Tags are appended to the statements as a trailing
+// sqlcommenter style comment, e.g. /*route='%2Fusers'*/, so the load can be
+// attributed in pg_stat_activity. Statements run by the pinned prepared
+// statements are not tagged.
+func WithTag(parent context.Context, key, value string) context.Context {
+	tags := make(map[string]string)
+	for k, v := range TagsFromContext(parent) {
+		tags[k] = v
+	}
+	tags[key] = value
+
+	return context.WithValue(parent, tagsKey, tags)
+}
+
+// TagsFromContext returns a copy of the tags set by WithTag, nil if none is
+// set.
+func TagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsKey).(map[string]string)
+	if tags == nil {
+		return nil
+	}
+
+	m := make(map[string]string, len(tags))
+	for k, v := range tags {
+		m[k] = v
+	}
+
+	return m
+}
+
+// tagQuery appends the tags of the ctx to the query as a comment, before the
+// trailing semicolon if the query has one
+func tagQuery(ctx context.Context, query string) string {
+	tags, _ := ctx.Value(tagsKey).(map[string]string)
+	if len(tags) == 0 {
+		return query
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = escapeTag(k) + "='" + escapeTag(tags[k]) + "'"
+	}
+
+	comment := "/*" + strings.Join(pairs, ",") + "*/"
+
+	trimmed := strings.TrimRight(query, " \t\r\n")
+	if strings.HasSuffix(trimmed, ";") {
+		return trimmed[:len(trimmed)-1] + " " + comment + ";"
+	}
+
+	return trimmed + " " + comment
+}
+
+// escapeTag url encodes s, so it can not close the comment or the quotes
+func escapeTag(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
@@ -0,0 +1,73 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTagQuery(t *testing.T) {
+	ctx := WithTag(context.Background(), "route", "/users")
+	ctx = WithTag(ctx, "action", "it's */ done")
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    "SELECT 1",
+			expected: "SELECT 1 /*action='it%27s%20%2A%2F%20done',route='%2Fusers'*/",
+		},
+		{
+			query:    "SELECT 1;\n",
+			expected: "SELECT 1 /*action='it%27s%20%2A%2F%20done',route='%2Fusers'*/;",
+		},
+	}
+
+	for _, test := range tests {
+		if got := tagQuery(ctx, test.query); got != test.expected {
+			t.Fatalf("expected %q, got: %q", test.expected, got)
+		}
+	}
+
+	if got := tagQuery(context.Background(), "SELECT 1"); got != "SELECT 1" {
+		t.Fatalf("expected untagged query, got: %q", got)
+	}
+
+	if tags := TagsFromContext(WithTag(ctx, "route", "/orders")); tags["route"] != "/orders" || tags["action"] == "" {
+		t.Fatalf("expected overridden route tag, got: %v", tags)
+	}
+
+	if tags := TagsFromContext(ctx); tags["route"] != "/users" {
+		t.Fatalf("expected parent tags to be kept, got: %v", tags)
+	}
+}
+
+func TestWithTag(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := WithTag(context.Background(), "route", "/users")
+
+	var query string
+	if err := db.QueryRow(ctx, "SELECT current_query()").Scan(ctx, &query); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if expected := "SELECT current_query() /*route='%2Fusers'*/"; query != expected {
+		t.Fatalf("expected %q, got: %q", expected, query)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, "SELECT current_query()").Scan(ctx, &query); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if expected := "SELECT current_query() /*route='%2Fusers'*/"; query != expected {
+		t.Fatalf("expected %q in the transaction, got: %q", expected, query)
+	}
+}
@@ -0,0 +1,183 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrUnknownTemplate is returned by ExecTemplate when the transaction
+	// template is not registered.
+	ErrUnknownTemplate = errors.New("unknown transaction template")
+
+	// ErrTemplateArgs is returned by ExecTemplate when more argument lists
+	// than the steps of the template are given.
+	ErrTemplateArgs = errors.New("too many arguments for the transaction template")
+)
+
+// TxStep is a named statement of a transaction template. If Query is empty,
+// the query registered with the name of the step in the query registry is
+// used, see WithQueries.
+type TxStep struct {
+	Name  string
+	Query string
+}
+
+// TxStepError is returned by ExecTemplate when a step of a transaction
+// template fails.
+type TxStepError struct {
+	Template string
+	Step     string
+	Err      error
+}
+
+func (e *TxStepError) Error() string {
+	return fmt.Sprintf("transaction %s: step %s: %s", e.Template, e.Step, e.Err)
+}
+
+// Unwrap returns the error of the step.
+func (e *TxStepError) Unwrap() error {
+	return e.Err
+}
+
+// WithTxTemplate registers a transaction template, a sequence of statements
+// executed in a single transaction by ExecTemplate, so a hot transactional
+// path is validated, labeled and reported as one named operation.
+func WithTxTemplate(name string, steps ...TxStep) Option {
+	return func(db *DB) {
+		if db.txTemplates == nil {
+			db.txTemplates = make(map[string][]TxStep)
+		}
+
+		db.txTemplates[name] = steps
+	}
+}
+
+// ExecTemplate executes the steps of the registered transaction template of
+// the given name in a transaction, passing the argument lists to the steps in
+// order, and commits it. Steps without an argument list are executed without
+// arguments. Returns the results of the steps, or the first failure as a
+// *TxStepError after rolling the transaction back.
+//
+// Operations are labeled with the template name unless ctx has a label, and
+// the transaction is reported with the template name as its operation, see
+// WithOpReport.
+func (db *DB) ExecTemplate(ctx context.Context, name string, args ...[]interface{}) ([]*Result, error) {
+	if err := db.check(); err != nil {
+		return nil, err
+	}
+
+	steps, ok := db.txTemplates[name]
+	if !ok {
+		return nil, ErrUnknownTemplate
+	}
+
+	if len(args) > len(steps) {
+		return nil, ErrTemplateArgs
+	}
+
+	if LabelFromContext(ctx) == "" {
+		ctx = WithLabel(ctx, name)
+	}
+
+	start := time.Now()
+	results, err := db.execTemplate(ctx, name, steps, args)
+	db.reportOp(name, start, err)
+
+	return results, err
+}
+
+func (db *DB) execTemplate(ctx context.Context, name string, steps []TxStep, args [][]interface{}) ([]*Result, error) {
+	queries := make([]string, len(steps))
+	for i, step := range steps {
+		query, err := db.stepQuery(step)
+		if err != nil {
+			return nil, &TxStepError{Template: name, Step: step.Name, Err: err}
+		}
+
+		queries[i] = query
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*Result, 0, len(steps))
+	for i, step := range steps {
+		var stepArgs []interface{}
+		if i < len(args) {
+			stepArgs = args[i]
+		}
+
+		res, err := tx.Exec(ctx, queries[i], stepArgs...)
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, &TxStepError{Template: name, Step: step.Name, Err: err}
+		}
+
+		results = append(results, res)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// stepQuery returns the query of the step, looking it up in the query registry
+// if the step does not have one
+func (db *DB) stepQuery(step TxStep) (string, error) {
+	if step.Query != "" {
+		return step.Query, nil
+	}
+
+	if db.queries == nil {
+		return "", ErrUnknownQuery
+	}
+
+	query, ok := db.queries.Get(step.Name)
+	if !ok {
+		return "", ErrUnknownQuery
+	}
+
+	return query, nil
+}
+
+// ValidateTemplates prepares every step of the registered transaction
+// templates on the database, for detecting the invalid ones at startup.
+// Returns the first failure as a *TxStepError, templates are validated in the
+// order of their names.
+func (db *DB) ValidateTemplates(ctx context.Context) error {
+	if err := db.check(); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(db.txTemplates))
+	for name := range db.txTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, step := range db.txTemplates[name] {
+			query, err := db.stepQuery(step)
+			if err == nil {
+				var stmt *Stmt
+				if stmt, err = db.Prepare(ctx, query); err == nil {
+					err = stmt.Close(ctx)
+				}
+			}
+
+			if err != nil {
+				return &TxStepError{Template: name, Step: step.Name, Err: err}
+			}
+		}
+	}
+
+	return nil
+}
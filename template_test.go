@@ -0,0 +1,78 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestExecTemplate(t *testing.T) {
+	queries := NewQueries()
+	queries.Add("orders/insert_item", "INSERT INTO template_items (order_id, sku) VALUES ($1, $2)")
+
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithQueries(queries),
+		WithTxTemplate("place_order",
+			TxStep{Name: "insert_order", Query: "INSERT INTO template_orders (id) VALUES ($1)"},
+			TxStep{Name: "orders/insert_item"},
+		),
+		WithTxTemplate("invalid",
+			TxStep{Name: "missing"},
+		),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS template_orders (id int PRIMARY KEY)",
+		"CREATE TABLE IF NOT EXISTS template_items (order_id int, sku text)",
+		"TRUNCATE template_orders, template_items",
+	} {
+		if _, err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	results, err := db.ExecTemplate(ctx, "place_order", []interface{}{1}, []interface{}{1, "sku-1"})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected the results of 2 steps, got: %d", len(results))
+	}
+
+	// duplicate order fails the first step, the transaction is rolled back
+	_, err = db.ExecTemplate(ctx, "place_order", []interface{}{1}, []interface{}{1, "sku-2"})
+	stepErr, ok := err.(*TxStepError)
+	if !ok || stepErr.Step != "insert_order" {
+		t.Fatalf("expected the error of insert_order, got: %v", err)
+	}
+
+	var items int
+	if err := db.QueryRow(ctx, "SELECT count(*) FROM template_items").Scan(ctx, &items); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if items != 1 {
+		t.Fatalf("expected 1 item, got: %d", items)
+	}
+
+	if _, err := db.ExecTemplate(ctx, "unknown"); err != ErrUnknownTemplate {
+		t.Fatalf("expected ErrUnknownTemplate, got: %v", err)
+	}
+
+	if _, err := db.ExecTemplate(ctx, "invalid", nil, nil); err != ErrTemplateArgs {
+		t.Fatalf("expected ErrTemplateArgs, got: %v", err)
+	}
+
+	err = db.ValidateTemplates(ctx)
+	if stepErr, ok := err.(*TxStepError); !ok || stepErr.Template != "invalid" || stepErr.Err != ErrUnknownQuery {
+		t.Fatalf("expected ErrUnknownQuery for the invalid template, got: %v", err)
+	}
+}
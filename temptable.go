@@ -0,0 +1,35 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// WithTempTable checks out a connection, creates a temporary table on it with
+// the given CREATE TEMPORARY TABLE statement, and calls f with the connection,
+// so f can fill the table and use it in later statements, e.g. with a COPY
+// followed by a joined UPDATE. Underlying *sql.DB holds a single connection,
+// all statements of f run on the session of the table. f must not retain the
+// *sql.DB after it returns.
+//
+// Temporary tables of the session are dropped with DISCARD TEMP after f
+// returns. Uses the given ctx and its deadline to signal timeouts. On timeout
+// or cancel case, closes the underlying connection, which drops the tables
+// with the session. The database must support DISCARD TEMP, e.g. postgres.
+func (db *DB) WithTempTable(ctx context.Context, schemaSQL string, f func(sqldb *sql.DB) error) error {
+	return db.Raw(ctx, func(sqldb *sql.DB) error {
+		if _, err := sqldb.Exec(schemaSQL); err != nil {
+			return err
+		}
+
+		err := f(sqldb)
+
+		if _, discardErr := sqldb.Exec(discardTempSQLStatement); discardErr != nil && err == nil {
+			err = discardErr
+		}
+
+		return err
+	})
+}
+
+const discardTempSQLStatement = `DISCARD TEMP`
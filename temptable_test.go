@@ -0,0 +1,41 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestWithTempTable(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	var sum int64
+	err := db.WithTempTable(ctx, "CREATE TEMPORARY TABLE reconcile (id BIGINT)", func(sqldb *sql.DB) error {
+		if _, err := sqldb.Exec("INSERT INTO reconcile SELECT generate_series(1, 10)"); err != nil {
+			return err
+		}
+
+		return sqldb.QueryRow("SELECT SUM(id) FROM reconcile").Scan(&sum)
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if sum != 55 {
+		t.Fatalf("expected 55, got: %d", sum)
+	}
+
+	// table is dropped before the connection is put back
+	var exists bool
+	err = db.QueryRow(ctx, "SELECT to_regclass('pg_temp.reconcile') IS NOT NULL").Scan(ctx, &exists)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if exists {
+		t.Fatal("expected temporary table to be dropped")
+	}
+}
@@ -0,0 +1,71 @@
+package ctxdb
+
+import "context"
+
+// Tracer starts the spans of the operations, set with WithTracer. See the
+// oteltrace package for an OpenTelemetry implementation.
+type Tracer interface {
+	// Start starts the span of the operation op, e.g. "Exec", "Tx" for a
+	// transaction or "Tx.Exec" for an operation of it, with its statement,
+	// which is empty for transactions. For the operations of a transaction,
+	// parent is the ctx returned for the transaction, it is nil otherwise.
+	// Operation runs with the returned ctx, and calls end with the number of
+	// the affected rows, -1 if it is unknown, and its error.
+	Start(ctx, parent context.Context, op, query string) (spanCtx context.Context, end func(rowsAffected int64, err error))
+}
+
+// WithTracer starts a span for every Exec, Query and QueryRow of the DB, and
+// for every transaction with the spans of its Exec, Query and QueryRow under
+// it. Spans of QueryRow end with Row.Scan, spans of transactions end with
+// Commit or Rollback.
+func WithTracer(t Tracer) Option {
+	return func(db *DB) {
+		db.tracer = t
+	}
+}
+
+// endNoop is the end of the spans when tracing is not enabled
+func endNoop(rowsAffected int64, err error) {}
+
+// startSpan starts the span of an operation if a tracer is set
+func (db *DB) startSpan(ctx, parent context.Context, op, query string) (context.Context, func(int64, error)) {
+	if db.tracer == nil {
+		return ctx, endNoop
+	}
+
+	return db.tracer.Start(ctx, parent, op, query)
+}
+
+// traceWith ends the span of the row with end when it is scanned, or now if
+// the query failed
+func (r *Row) traceWith(end func(int64, error)) {
+	if r.row == nil {
+		end(-1, r.err)
+		return
+	}
+
+	r.spanEnd = end
+}
+
+// endSpan ends the span of the row if it has one
+func (r *Row) endSpan(err error) {
+	if r.spanEnd != nil {
+		r.spanEnd(-1, err)
+		r.spanEnd = nil
+	}
+}
+
+// rowsAffected returns the number of the rows affected by the result, -1 if it
+// is unknown
+func (r *Result) rowsAffected() int64 {
+	if r == nil || r.res == nil {
+		return -1
+	}
+
+	n, err := r.res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
@@ -0,0 +1,87 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+)
+
+type spanKey struct{}
+
+type span struct {
+	op, query string
+	parent    *span
+	rows      int64
+	err       error
+	ended     bool
+}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+func (t *recordingTracer) Start(ctx, parent context.Context, op, query string) (context.Context, func(int64, error)) {
+	s := &span{op: op, query: query}
+	if parent != nil {
+		s.parent, _ = parent.Value(spanKey{}).(*span)
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, s), func(rows int64, err error) {
+		s.rows, s.err, s.ended = rows, err, true
+	}
+}
+
+func TestWithTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithTracer(tracer),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var n int
+	if err := tx.QueryRow(ctx, "SELECT 1").Scan(ctx, &n); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if len(tracer.spans) != 3 {
+		t.Fatalf("expected 3 spans, got: %d", len(tracer.spans))
+	}
+
+	exec, txSpan, row := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	if exec.op != "Exec" || exec.query != "SELECT 1" || exec.rows != 1 || !exec.ended {
+		t.Fatalf("expected the ended span of Exec, got: %+v", exec)
+	}
+
+	if txSpan.op != "Tx" || !txSpan.ended || txSpan.err != nil {
+		t.Fatalf("expected the ended span of the transaction, got: %+v", txSpan)
+	}
+
+	if row.op != "Tx.QueryRow" || row.parent != txSpan || !row.ended {
+		t.Fatalf("expected the span of QueryRow under the transaction, got: %+v", row)
+	}
+}
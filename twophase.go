@@ -0,0 +1,59 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrNestedPrepare is returned by PrepareTransaction for the nested
+// transactions, savepoints can not be prepared on their own.
+var ErrNestedPrepare = errors.New("nested transaction can not be prepared")
+
+// PrepareTransaction prepares the transaction for a two-phase commit with the
+// given global id, using postgres PREPARE TRANSACTION. Prepared transaction
+// is dissociated from the connection, so tx is finished and its connection
+// is given back. It is committed or rolled back later with CommitPrepared or
+// RollbackPrepared, with any connection. Returns nil once the transaction is
+// prepared, even if ending tx fails afterwards, since the prepared transaction
+// outlives the connection. Server must allow the prepared transactions, see
+// max_prepared_transactions.
+func (tx *Tx) PrepareTransaction(ctx context.Context, gid string) error {
+	if err := tx.check(); err != nil {
+		return err
+	}
+
+	if tx.parent != nil {
+		return ErrNestedPrepare
+	}
+
+	if _, err := tx.exec(ctx, "PREPARE TRANSACTION "+quoteLiteral(gid)); err != nil {
+		return err
+	}
+
+	// there is no transaction in progress on the connection anymore, Commit
+	// only ends the sql.Tx and gives the connection back; its failures do not
+	// affect the prepared transaction
+	tx.Commit(ctx)
+	return nil
+}
+
+// CommitPrepared commits the transaction prepared with the given global id by
+// PrepareTransaction.
+func CommitPrepared(ctx context.Context, db *DB, gid string) error {
+	_, err := db.Exec(ctx, "COMMIT PREPARED "+quoteLiteral(gid))
+	return err
+}
+
+// RollbackPrepared rolls back the transaction prepared with the given global
+// id by PrepareTransaction.
+func RollbackPrepared(ctx context.Context, db *DB, gid string) error {
+	_, err := db.Exec(ctx, "ROLLBACK PREPARED "+quoteLiteral(gid))
+	return err
+}
+
+// quoteLiteral quotes s as a string literal, since the global ids of the
+// prepared transactions can not be passed as parameters
+func quoteLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
@@ -0,0 +1,62 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPrepareTransaction(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS two_phase (id int)",
+		"TRUNCATE two_phase",
+	} {
+		if _, err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	var max int
+	if err := db.QueryRow(ctx, "SELECT current_setting('max_prepared_transactions')::int").Scan(ctx, &max); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if max == 0 {
+		t.Skip("prepared transactions are disabled on the server")
+	}
+
+	for i, gid := range []string{"ctxdb-'committed'", "ctxdb-rolled-back"} {
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+
+		if _, err := tx.Exec(ctx, "INSERT INTO two_phase VALUES ($1)", i+1); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+
+		if err := tx.PrepareTransaction(ctx, gid); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	if err := CommitPrepared(ctx, db, "ctxdb-'committed'"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := RollbackPrepared(ctx, db, "ctxdb-rolled-back"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	var sum int
+	if err := db.QueryRow(ctx, "SELECT coalesce(sum(id), 0) FROM two_phase").Scan(ctx, &sum); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if sum != 1 {
+		t.Fatalf("expected only the committed row, got sum: %d", sum)
+	}
+}
@@ -1,10 +1,9 @@
 package ctxdb
 
 import (
+	"context"
 	"database/sql"
 	"sync"
-
-	"golang.org/x/net/context"
 )
 
 // Tx is an in-progress database transaction.
@@ -17,14 +16,67 @@ import (
 // The statements prepared for a transaction by calling the transaction's
 // Prepare or Stmt methods are closed by the call to Commit or Rollback.
 type Tx struct {
+	id        uint64
 	tx        *sql.Tx
 	sqldb     *sql.DB
 	db        *DB
 	stickyErr error
+	journal   *TxJournal
+	stats     *txStats
+	guard     guard
+	attrs     *ConnAttributes
+
+	spanCtx context.Context    // ctx of the span of the transaction
+	spanEnd func(int64, error) // ends the span of the transaction
+	scope   *Scope             // scope tracking the transaction
+
+	finished  bool // committed or rolled back, guarded by the lock of the tx
+	committed bool // committed successfully, guarded by the lock of the tx
+
+	parent    *Tx    // enclosing transaction of a nested one
+	savepoint string // savepoint of a nested transaction
+
+	done chan struct{} // closed when the transaction is finished, stops the watchdog
+	idle *txIdle       // idle-in-transaction timer, shared with the nested ones
 
 	sync.Mutex
 }
 
+// check returns ErrNotInitialized if tx is nil or not created by a Begin
+func (tx *Tx) check() error {
+	if tx == nil || tx.tx == nil || tx.db == nil {
+		return ErrNotInitialized
+	}
+
+	return nil
+}
+
+// finish ends the journal and the report of the transaction
+func (tx *Tx) finish(committed bool, err error) {
+	if tx.finished {
+		// rolled back by the watchdog
+		return
+	}
+
+	tx.finished = true
+	tx.committed = committed && err == nil
+	if tx.done != nil {
+		close(tx.done)
+	}
+	if tx.parent == nil {
+		tx.idle.stop()
+	}
+	tx.finishJournal(committed, err)
+	tx.report(committed, err)
+
+	if tx.spanEnd != nil {
+		tx.spanEnd(-1, err)
+		tx.spanEnd = nil
+	}
+
+	tx.scope.untrack(nil, nil, tx)
+}
+
 func (tx *Tx) shutdown() error {
 	rollbackErr := tx.tx.Rollback()
 	return tx.db.restoreOrClose(rollbackErr, tx.sqldb)
@@ -36,10 +88,30 @@ func (tx *Tx) shutdown() error {
 // given ctx and its deadline to signal timeouts. On timeout or cancel case,
 // closes the underlying connection.
 func (tx *Tx) Commit(ctx context.Context) error {
+	if err := tx.check(); err != nil {
+		return err
+	}
+
+	if tx.parent != nil {
+		return tx.endSavepoint(ctx, true)
+	}
+
+	tx.db.observe(ctx, "Tx.Commit")
+
+	if err := tx.guard.enter(); err != nil {
+		return err
+	}
+	defer tx.guard.leave()
+
 	tx.Lock()
 	defer tx.Unlock()
 
+	if err := tx.misuseTxDone("Tx.Commit"); err != nil {
+		return err
+	}
+
 	if tx.stickyErr != nil {
+		tx.finish(true, tx.stickyErr)
 		return tx.stickyErr
 	}
 
@@ -52,10 +124,12 @@ func (tx *Tx) Commit(ctx context.Context) error {
 	}
 
 	if err := tx.db.processWithGivenSQL(ctx, f, done, tx.sqldb); err != nil {
+		tx.finish(true, err)
 		return err
 	}
 
-	return err
+	tx.finish(true, err)
+	return tx.db.mapDriverError(err)
 }
 
 // Exec executes a query that doesn't return rows. For example: an INSERT and
@@ -67,36 +141,104 @@ func (tx *Tx) Commit(ctx context.Context) error {
 // connection. Transaction Rollback error is omitted if the Connection Close
 // returns an error. Operation error is omitted if the Rollback operation
 // returns an error.
-func (tx *Tx) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+func (tx *Tx) Exec(ctx context.Context, query string, args ...interface{}) (*Result, error) {
+	if err := tx.check(); err != nil {
+		return nil, err
+	}
+
+	op := &Op{Name: "Tx.Exec", Query: query, Args: args}
+	err := tx.db.intercept(ctx, op, func(ctx context.Context, op *Op) error {
+		ctx, end := tx.db.startOp(ctx, tx.spanCtx, op.Name, op.Query, op.Args)
+
+		var err error
+		op.Result, err = tx.execSavepoint(ctx, op.Query, op.Args...)
+		end(op.Result.rowsAffected(), err)
+		return err
+	})
+
+	return op.Result, tx.db.mapDriverError(err)
+}
+
+// exec is Exec without the span
+func (tx *Tx) exec(ctx context.Context, query string, args ...interface{}) (*Result, error) {
+	return tx.execWith(ctx, query, args, func() (sql.Result, error) {
+		return txExec(ctx, tx.tx, tagQuery(ctx, query), args)
+	})
+}
+
+// execWith runs the Exec of the query with run, which executes it on the
+// connection of the transaction
+func (tx *Tx) execWith(ctx context.Context, query string, args []interface{}, run func() (sql.Result, error)) (*Result, error) {
+	if err := tx.check(); err != nil {
+		return nil, err
+	}
+
+	tx.db.observe(ctx, "Tx.Exec")
+
+	if err := tx.guard.enter(); err != nil {
+		return nil, err
+	}
+	defer tx.guard.leave()
+
+	if err := prepareArgs(args); err != nil {
+		return nil, err
+	}
+
+	if err := tx.db.guardDryRun(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.db.guardDDL(ctx, query); err != nil {
+		return nil, err
+	}
+
+	if err := tx.db.misuseNoDeadline(ctx, "Tx.Exec"); err != nil {
+		return nil, err
+	}
+
 	tx.Lock()
 	defer tx.Unlock()
 
-	if tx.stickyErr != nil {
-		return nil, tx.stickyErr
+	if err := tx.misuseTxDone("Tx.Exec"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.sticky(); err != nil {
+		return nil, err
 	}
 
+	tx.idle.stop()
+	defer tx.idle.reset()
+
 	done := make(chan struct{}, 1)
 
 	var res sql.Result
 	var err error
 
 	go func() {
-		res, err = tx.tx.Exec(query, args)
+		res, err = run()
 		close(done)
 	}()
 
-	select {
-	case <-ctx.Done():
-		if err := tx.shutdown(); err != nil {
-			tx.stickyErr = err
-			return nil, err
-		}
+	tx.watch(done)
 
-		tx.stickyErr = ctx.Err()
-		return nil, tx.stickyErr
-	case <-done:
-		return res, err
+	opErr := wait(ctx, done)
+	if opErr == nil && err != nil && err == ctx.Err() {
+		// stopped by the driver on timeout or cancel case
+		opErr = err
+	}
+
+	if opErr != nil {
+		tx.record(query, args, opErr)
+		return nil, tx.fail(opErr)
+	}
+
+	tx.record(query, args, err)
+	if err != nil {
+		return nil, err
 	}
+
+	return &Result{id: tx.id, res: res, query: query}, nil
 }
 
 // Prepare creates a prepared statement for use within a transaction.
@@ -113,11 +255,24 @@ func (tx *Tx) Exec(ctx context.Context, query string, args ...interface{}) (sql.
 // returns an error. Operation error is omitted if the Rollback operation
 // returns an error.
 func (tx *Tx) Prepare(ctx context.Context, query string) (*Stmt, error) {
+	if err := tx.check(); err != nil {
+		return nil, err
+	}
+
+	tx.db.observe(ctx, "Tx.Prepare")
+
+	query, names := tx.db.bindStmt(query)
+
+	if err := tx.guard.enter(); err != nil {
+		return nil, err
+	}
+	defer tx.guard.leave()
+
 	tx.Lock()
 	defer tx.Unlock()
 
-	if tx.stickyErr != nil {
-		return nil, tx.stickyErr
+	if err := tx.sticky(); err != nil {
+		return nil, err
 	}
 
 	done := make(chan struct{}, 1)
@@ -126,22 +281,25 @@ func (tx *Tx) Prepare(ctx context.Context, query string) (*Stmt, error) {
 	var err error
 
 	go func() {
-		res, err = tx.tx.Prepare(query)
+		res, err = txPrepare(ctx, tx.tx, query)
 		close(done)
 	}()
 
-	select {
-	case <-ctx.Done():
-		if err := tx.shutdown(); err != nil {
-			tx.stickyErr = err
-			return nil, err
-		}
+	opErr := wait(ctx, done)
+	if opErr == nil && err != nil && err == ctx.Err() {
+		// stopped by the driver on timeout or cancel case
+		opErr = err
+	}
 
-		tx.stickyErr = ctx.Err()
-		return nil, tx.stickyErr
-	case <-done:
-		return &Stmt{stmt: res}, err
+	if opErr != nil {
+		return nil, tx.fail(opErr)
+	}
+
+	if err != nil {
+		return nil, err
 	}
+
+	return &Stmt{id: tx.id, stmt: res, query: query, names: names, sqldb: tx.sqldb, db: tx.db, tx: tx}, nil
 }
 
 // Query executes a query that returns rows, typically a SELECT. The args are
@@ -154,41 +312,99 @@ func (tx *Tx) Prepare(ctx context.Context, query string) (*Stmt, error) {
 // returns an error. Operation error is omitted if the Rollback operation
 // returns an error.
 func (tx *Tx) Query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	if err := tx.check(); err != nil {
+		return nil, err
+	}
+
+	op := &Op{Name: "Tx.Query", Query: query, Args: args}
+	err := tx.db.intercept(ctx, op, func(ctx context.Context, op *Op) error {
+		ctx, end := tx.db.startOp(ctx, tx.spanCtx, op.Name, op.Query, op.Args)
+
+		var err error
+		op.Rows, err = tx.query(ctx, op.Query, op.Args...)
+		end(-1, err)
+		trackRows(ctx, op.Rows)
+		return err
+	})
+
+	return op.Rows, tx.db.mapDriverError(err)
+}
+
+// query is Query without the span
+func (tx *Tx) query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	return tx.queryWith(ctx, query, args, func() (*sql.Rows, error) {
+		return tx.tx.Query(tagQuery(ctx, query), args...)
+	})
+}
+
+// queryWith runs the Query of the query with run, which queries it on the
+// connection of the transaction
+func (tx *Tx) queryWith(ctx context.Context, query string, args []interface{}, run func() (*sql.Rows, error)) (*Rows, error) {
+	if err := tx.check(); err != nil {
+		return nil, err
+	}
+
+	tx.db.observe(ctx, "Tx.Query")
+
+	if err := tx.guard.enter(); err != nil {
+		return nil, err
+	}
+	defer tx.guard.leave()
+
+	if err := prepareArgs(args); err != nil {
+		return nil, err
+	}
+
+	if err := tx.db.guardDryRun(ctx); err != nil {
+		return nil, err
+	}
+
 	tx.Lock()
 	defer tx.Unlock()
 
-	if tx.stickyErr != nil {
-		return nil, tx.stickyErr
+	if err := tx.misuseTxDone("Tx.Query"); err != nil {
+		return nil, err
 	}
 
+	if err := tx.sticky(); err != nil {
+		return nil, err
+	}
+
+	tx.idle.stop()
+	defer tx.idle.reset()
+
 	done := make(chan struct{}, 1)
 
 	var res *sql.Rows
 	var err error
 
 	go func() {
-		res, err = tx.tx.Query(query, args...)
+		res, err = run()
 		close(done)
 	}()
 
+	tx.watch(done)
+
 	select {
 	case <-ctx.Done():
-		if err := tx.shutdown(); err != nil {
-			tx.stickyErr = err
-			return nil, err
-		}
-
-		tx.stickyErr = ctx.Err()
-		return nil, tx.stickyErr
+		tx.record(query, args, ctx.Err())
+		return nil, tx.fail(ctx.Err())
 	case <-done:
+		tx.record(query, args, err)
 		if err != nil {
 			return nil, err
 		}
 
+		// transaction is not idle while its rows are iterated
+		tx.idle.rowsOpened()
+
 		return &Rows{
-			rows:  res,
-			sqldb: tx.sqldb,
-			db:    tx.db,
+			id:     tx.id,
+			rows:   res,
+			sqldb:  tx.sqldb,
+			db:     tx.db,
+			origin: ctx,
+			idle:   tx.idle,
 		}, nil
 	}
 }
@@ -204,35 +420,89 @@ func (tx *Tx) Query(ctx context.Context, query string, args ...interface{}) (*Ro
 // returns an error. Operation error is omitted if the Rollback operation
 // returns an error.
 func (tx *Tx) QueryRow(ctx context.Context, query string, args ...interface{}) *Row {
+	if err := tx.check(); err != nil {
+		return &Row{err: err}
+	}
+
+	op := &Op{Name: "Tx.QueryRow", Query: query, Args: args}
+	err := tx.db.intercept(ctx, op, func(ctx context.Context, op *Op) error {
+		ctx, end := tx.db.startOp(ctx, tx.spanCtx, op.Name, op.Query, op.Args)
+
+		op.Row = tx.queryRow(ctx, op.Query, op.Args...)
+		op.Row.traceWith(end)
+		return op.Row.err
+	})
+
+	if op.Row == nil {
+		// stopped by a middleware
+		return &Row{id: nextOperationID(), err: err}
+	}
+
+	return op.Row
+}
+
+// queryRow is QueryRow without the span
+func (tx *Tx) queryRow(ctx context.Context, query string, args ...interface{}) *Row {
+	return tx.queryRowWith(ctx, query, args, func() *sql.Row {
+		return tx.tx.QueryRow(tagQuery(ctx, query), args...)
+	})
+}
+
+// queryRowWith runs the QueryRow of the query with run, which queries it on
+// the connection of the transaction
+func (tx *Tx) queryRowWith(ctx context.Context, query string, args []interface{}, run func() *sql.Row) *Row {
+	if err := tx.check(); err != nil {
+		return &Row{err: err}
+	}
+
+	tx.db.observe(ctx, "Tx.QueryRow")
+
+	if err := tx.guard.enter(); err != nil {
+		return &Row{id: tx.id, err: err}
+	}
+	defer tx.guard.leave()
+
+	if err := prepareArgs(args); err != nil {
+		return &Row{id: tx.id, err: err}
+	}
+
+	if err := tx.db.guardDryRun(ctx); err != nil {
+		return &Row{id: tx.id, err: err}
+	}
+
 	tx.Lock()
 	defer tx.Unlock()
 
-	if tx.stickyErr != nil {
-		return &Row{sqldb: tx.sqldb, db: tx.db, err: tx.stickyErr}
+	if err := tx.misuseTxDone("Tx.QueryRow"); err != nil {
+		return &Row{id: tx.id, err: err}
 	}
 
+	if err := tx.sticky(); err != nil {
+		return &Row{id: tx.id, sqldb: tx.sqldb, db: tx.db, err: err}
+	}
+
+	tx.idle.stop()
+	defer tx.idle.reset()
+
 	done := make(chan struct{}, 1)
 	var res *sql.Row
 	go func() {
-		res = tx.tx.QueryRow(query, args...)
+		res = run()
 		close(done)
 	}()
 
+	tx.watch(done)
+
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
+		tx.record(query, args, err)
 		// prepare non-nil Query
-		r := &Row{sqldb: tx.sqldb, db: tx.db, err: err}
-		tx.stickyErr = err
-
-		if err := tx.shutdown(); err != nil {
-			tx.stickyErr = err
-			r.err = err
-		}
-
-		return r
+		return &Row{id: tx.id, sqldb: tx.sqldb, db: tx.db, err: tx.fail(err)}
 	case <-done:
+		tx.record(query, args, nil)
 		return &Row{
+			id:    tx.id,
 			row:   res,
 			sqldb: tx.sqldb,
 			db:    tx.db,
@@ -242,10 +512,26 @@ func (tx *Tx) QueryRow(ctx context.Context, query string, args ...interface{}) *
 
 // Rollback aborts the transaction.
 func (tx *Tx) Rollback(ctx context.Context) error {
+	if err := tx.check(); err != nil {
+		return err
+	}
+
+	if tx.parent != nil {
+		return tx.endSavepoint(ctx, false)
+	}
+
+	tx.db.observe(ctx, "Tx.Rollback")
+
+	if err := tx.guard.enter(); err != nil {
+		return err
+	}
+	defer tx.guard.leave()
+
 	tx.Lock()
 	defer tx.Unlock()
 
 	if tx.stickyErr != nil {
+		tx.finish(false, tx.stickyErr)
 		return tx.stickyErr
 	}
 
@@ -259,9 +545,11 @@ func (tx *Tx) Rollback(ctx context.Context) error {
 	}
 
 	if err := tx.db.processWithGivenSQL(ctx, f, done, tx.sqldb); err != nil {
+		tx.finish(false, err)
 		return err
 	}
 
+	tx.finish(false, err)
 	return err
 }
 
@@ -280,13 +568,42 @@ func (tx *Tx) Rollback(ctx context.Context) error {
 // The returned statement operates within the transaction and can no longer be
 // used once the transaction has been committed or rolled back.
 func (tx *Tx) Stmt(ctx context.Context, stmt *Stmt) *Stmt {
+	if err := tx.check(); err != nil {
+		return &Stmt{err: err}
+	}
+
+	if err := stmt.check(); err != nil {
+		return &Stmt{id: tx.id, err: err}
+	}
+
+	if stmt.err != nil {
+		return &Stmt{id: tx.id, err: stmt.err}
+	}
+
+	if stmt.sqldb != tx.sqldb {
+		// statement is prepared on another connection of the pool
+		s, err := tx.Prepare(ctx, stmt.query)
+		if err != nil {
+			return &Stmt{id: tx.id, err: err}
+		}
+
+		// query is already bound
+		s.names = stmt.names
+		return s
+	}
+
+	if err := tx.guard.enter(); err != nil {
+		return &Stmt{id: tx.id, err: err}
+	}
+	defer tx.guard.leave()
+
 	tx.Lock()
 	defer tx.Unlock()
 
-	if tx.stickyErr != nil {
-		return &Stmt{err: tx.stickyErr}
+	if err := tx.sticky(); err != nil {
+		return &Stmt{id: tx.id, err: err}
 	}
 
 	s := tx.tx.Stmt(stmt.stmt)
-	return &Stmt{stmt: s}
+	return &Stmt{id: tx.id, stmt: s, query: stmt.query, names: stmt.names, sqldb: tx.sqldb, db: tx.db, tx: tx}
 }
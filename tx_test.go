@@ -1,11 +1,10 @@
 package ctxdb
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
-
-	"golang.org/x/net/context"
 )
 
 func TestTx(t *testing.T) {
@@ -269,3 +268,57 @@ func TestTxExecWithStickyError(t *testing.T) {
 		t.Fatalf("err should be  stickyErr while rolling back the tx: got err : %s", err)
 	}
 }
+
+func TestTxStmt(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS tx_stmts (id int)",
+		"TRUNCATE tx_stmts",
+	} {
+		if _, err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	stmt, err := db.Prepare(ctx, "INSERT INTO tx_stmts VALUES ($1)")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer stmt.Close(ctx)
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Stmt(ctx, stmt).Exec(ctx, 1); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	count, err := tx.Prepare(ctx, "SELECT count(*) FROM tx_stmts")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// statement runs in the transaction, so it sees the uncommitted row
+	var n int
+	if err := count.QueryRow(ctx).Scan(ctx, &n); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if n != 1 {
+		t.Fatalf("expected 1 row in the transaction, got: %d", n)
+	}
+
+	if err := count.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
@@ -0,0 +1,146 @@
+package ctxdb
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTxIdleTimeout is the sticky error of a transaction rolled back since no
+// statement is executed on it for the idle-in-transaction timeout.
+var ErrTxIdleTimeout = errors.New("transaction is idle for too long")
+
+// SetTxIdleTimeout sets the idle-in-transaction timeout of the transactions
+// begun afterwards. If no statement is executed on an open transaction for
+// the given duration, it is rolled back, its connection is closed, and its
+// later operations fail with ErrTxIdleTimeout. Transactions can override it
+// with Tx.SetIdleTimeout. Zero disables the timeout.
+func (db *DB) SetTxIdleTimeout(d time.Duration) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.txIdleTimeout = d
+	db.mu.Unlock()
+}
+
+// SetIdleTimeout overrides the idle-in-transaction timeout of the DB for the
+// transaction, restarting its idle time. Zero disables the timeout. Nested
+// transactions share the timeout of their enclosing one.
+func (tx *Tx) SetIdleTimeout(d time.Duration) {
+	if tx.check() != nil || tx.idle == nil {
+		return
+	}
+
+	tx.idle.mu.Lock()
+	tx.idle.timeout = d
+	tx.idle.mu.Unlock()
+
+	tx.idle.reset()
+}
+
+// txIdle holds the idle timer of a transaction, shared with its nested ones
+type txIdle struct {
+	tx *Tx // outermost transaction
+
+	mu      sync.Mutex
+	timeout time.Duration
+	timer   *time.Timer
+	gen     int // incremented on every stop, invalidates the fired timers
+	rows    int // open rows of the transaction, the timer is stopped while any
+}
+
+// startIdle starts the idle timer of the transaction with the timeout of the
+// db
+func (tx *Tx) startIdle() {
+	tx.db.mu.Lock()
+	timeout := tx.db.txIdleTimeout
+	tx.db.mu.Unlock()
+
+	tx.idle = &txIdle{tx: tx, timeout: timeout}
+	tx.idle.reset()
+}
+
+// stop stops the timer while a statement runs or when the transaction is
+// finished
+func (i *txIdle) stop() {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	i.gen++
+	if i.timer != nil {
+		i.timer.Stop()
+		i.timer = nil
+	}
+	i.mu.Unlock()
+}
+
+// reset restarts the idle time of the transaction, unless it has open rows
+func (i *txIdle) reset() {
+	if i == nil {
+		return
+	}
+
+	i.stop()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.timeout <= 0 || i.rows > 0 {
+		return
+	}
+
+	gen := i.gen
+	i.timer = time.AfterFunc(i.timeout, func() { i.fire(gen) })
+}
+
+// rowsOpened stops the timer while the opened rows are iterated
+func (i *txIdle) rowsOpened() {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	i.rows++
+	i.mu.Unlock()
+
+	i.stop()
+}
+
+// rowsClosed restarts the idle time once the last open rows are closed
+func (i *txIdle) rowsClosed() {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	i.rows--
+	open := i.rows
+	i.mu.Unlock()
+
+	if open == 0 {
+		i.reset()
+	}
+}
+
+// fire rolls back the transaction, unless a statement is executed since the
+// timer of gen is started
+func (i *txIdle) fire(gen int) {
+	tx := i.tx
+
+	tx.Lock()
+	defer tx.Unlock()
+
+	i.mu.Lock()
+	stale := gen != i.gen
+	i.mu.Unlock()
+
+	if stale {
+		return
+	}
+
+	tx.abortLocked(ErrTxIdleTimeout, true)
+}
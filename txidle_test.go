@@ -0,0 +1,96 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTxIdleTimeout(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	db.SetTxIdleTimeout(time.Millisecond * 100)
+
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// statements restart the idle time
+	for i := 0; i < 3; i++ {
+		time.Sleep(time.Millisecond * 50)
+		if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	time.Sleep(time.Millisecond * 200)
+
+	if s := db.PoolStats(); s.InUse != 0 || s.OpenConns != 0 {
+		t.Fatalf("expected the connection to be closed, got: %+v", s)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != ErrTxIdleTimeout {
+		t.Fatalf("expected ErrTxIdleTimeout, got: %v", err)
+	}
+
+	if err := tx.Rollback(ctx); err != ErrTxIdleTimeout {
+		t.Fatalf("expected ErrTxIdleTimeout, got: %v", err)
+	}
+
+	// transactions override the timeout of the db
+	tx, err = db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	tx.SetIdleTimeout(0)
+	time.Sleep(time.Millisecond * 200)
+
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+}
+
+func TestTxIdleTimeoutWithOpenRows(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	db.SetTxIdleTimeout(time.Millisecond * 100)
+
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// transaction is not idle while its rows are open
+	time.Sleep(time.Millisecond * 200)
+
+	if err := tx.Err(); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := rows.Close(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	// idle time restarts when the rows are closed
+	time.Sleep(time.Millisecond * 200)
+
+	if err := tx.Err(); err != ErrTxIdleTimeout {
+		t.Fatalf("expected ErrTxIdleTimeout, got: %v", err)
+	}
+}
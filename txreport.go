@@ -0,0 +1,136 @@
+package ctxdb
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// lockProbeInterval is the interval of checking whether a running statement of
+// a reported transaction is waiting for a lock.
+const lockProbeInterval = 100 * time.Millisecond
+
+// TxReport holds the statistics of a transaction, passed to the report func
+// set with WithTxReport.
+type TxReport struct {
+	OperationID uint64
+	Duration    time.Duration // from Begin to the end of Commit or Rollback
+	Statements  int
+
+	// LockWaits is the number of statements observed waiting for a lock, and
+	// LockWait is their approximate total wait. Lock waits are sampled at
+	// every 100ms while a statement is running, on postgres only.
+	LockWaits int
+	LockWait  time.Duration
+
+	Committed bool
+	Err       error
+}
+
+// WithTxReport enables the transaction reports, f is called with the report of
+// every transaction lasting at least threshold, after it's committed or rolled
+// back. Lock waits are probed with a separate connection of the pool.
+func WithTxReport(threshold time.Duration, f func(r *TxReport)) Option {
+	return func(db *DB) {
+		db.txReportThreshold = threshold
+		db.txReport = f
+	}
+}
+
+// txStats is the bookkeeping of a reported transaction
+type txStats struct {
+	started    time.Time
+	pid        int // backend pid of the transaction, zero if unknown
+	statements int32
+	lockWaits  int32
+	lockWait   int64 // in nanoseconds
+}
+
+// watch counts the statement signalling done, and probes its lock waits until
+// done is closed
+func (tx *Tx) watch(done chan struct{}) {
+	s := tx.stats
+	if s == nil {
+		return
+	}
+
+	atomic.AddInt32(&s.statements, 1)
+
+	if s.pid == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(lockProbeInterval)
+		defer ticker.Stop()
+
+		waited := false
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			if !tx.db.isWaitingLock(s.pid) {
+				continue
+			}
+
+			if !waited {
+				waited = true
+				atomic.AddInt32(&s.lockWaits, 1)
+			}
+
+			atomic.AddInt64(&s.lockWait, int64(lockProbeInterval))
+		}
+	}()
+}
+
+// isWaitingLock reports whether the backend with the given pid is waiting for
+// a lock, probe errors are reported as not waiting
+func (db *DB) isWaitingLock(pid int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var waiting bool
+	err := db.QueryRow(ctx, lockWaitSQLStatement, pid).Scan(ctx, &waiting)
+	return err == nil && waiting
+}
+
+// report passes the report of the transaction to the report func of db if it
+// lasted at least the threshold. Report is passed at most once.
+func (tx *Tx) report(committed bool, err error) {
+	s := tx.stats
+	if s == nil {
+		return
+	}
+
+	tx.stats = nil
+
+	r := &TxReport{
+		OperationID: tx.id,
+		Duration:    time.Since(s.started),
+		Statements:  int(atomic.LoadInt32(&s.statements)),
+		LockWaits:   int(atomic.LoadInt32(&s.lockWaits)),
+		LockWait:    time.Duration(atomic.LoadInt64(&s.lockWait)),
+		Committed:   committed,
+		Err:         err,
+	}
+
+	tx.db.mu.Lock()
+	f := tx.db.txReport
+	threshold := tx.db.txReportThreshold
+	tx.db.mu.Unlock()
+
+	if f == nil || r.Duration < threshold {
+		return
+	}
+
+	f(r)
+}
+
+const (
+	backendPIDSQLStatement = `SELECT pg_backend_pid()`
+	lockWaitSQLStatement   = `SELECT COALESCE(bool_or(wait_event_type = 'Lock'), false)
+    FROM pg_stat_activity WHERE pid = $1`
+)
@@ -0,0 +1,107 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func openWithTxReport(t *testing.T, reports chan *TxReport) *DB {
+	db, err := Open(
+		os.Getenv("NISQL_TEST_DIALECT"),
+		os.Getenv("NISQL_TEST_DSN"),
+		WithMaxOpenConns(3),
+		WithTxReport(0, func(r *TxReport) { reports <- r }),
+	)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	return db
+}
+
+func TestTxReport(t *testing.T) {
+	reports := make(chan *TxReport, 1)
+	db := openWithTxReport(t, reports)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+			t.Fatalf("expected nil, got: %s", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	r := <-reports
+	if r.Statements != 2 || !r.Committed || r.Err != nil {
+		t.Fatalf("expected 2 committed statements, got: %+v", r)
+	}
+
+	if r.OperationID != tx.OperationID() {
+		t.Fatalf("expected operation id %d, got: %d", tx.OperationID(), r.OperationID)
+	}
+
+	if r.LockWaits != 0 {
+		t.Fatalf("expected no lock waits, got: %d", r.LockWaits)
+	}
+}
+
+func TestTxReportWithLockWait(t *testing.T) {
+	reports := make(chan *TxReport, 2)
+	db := openWithTxReport(t, reports)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	lock := "SELECT pg_advisory_xact_lock(4242)"
+
+	holder, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := holder.Exec(ctx, lock); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	waiter, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := waiter.Exec(ctx, lock)
+		errc <- err
+	}()
+
+	time.Sleep(lockProbeInterval * 5)
+
+	if err := holder.Rollback(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	<-reports
+
+	if err := <-errc; err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := waiter.Rollback(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	r := <-reports
+	if r.LockWaits != 1 || r.LockWait == 0 {
+		t.Fatalf("expected a lock wait, got: %+v", r)
+	}
+}
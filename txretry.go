@@ -0,0 +1,87 @@
+package ctxdb
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// txRetryAttempts is the max number of attempts of RunInTxRetry if the ctx
+	// has no deadline and no retries
+	txRetryAttempts = 10
+
+	// backoffs of RunInTxRetry
+	txRetryBackoff    = time.Millisecond * 10
+	txRetryMaxBackoff = time.Second
+)
+
+// RunInTx runs f in a transaction, which is committed if f returns nil, and
+// rolled back if it returns an error or panics. Error of f is returned as it
+// is, the error of the rollback is omitted.
+func (db *DB) RunInTx(ctx context.Context, f func(ctx context.Context, tx *Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	if err := f(ctx, tx); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RunInTxRetry runs f with RunInTx, rolling back and re-running it when the
+// transaction fails with a serialization failure or a deadlock, see
+// ErrSerializationFailure. Reruns wait with a capped exponential backoff
+// within the deadline of the ctx, at most the retries of the ctx set with
+// WithRetries, or 10 attempts if the ctx has neither of them. f must not have
+// side effects outside of the transaction, since it may run more than once.
+func (db *DB) RunInTxRetry(ctx context.Context, f func(ctx context.Context, tx *Tx) error) error {
+	p := &RetryPolicy{
+		MaxAttempts: txRetryAttempts,
+		Backoff:     txRetryBackoff,
+		MaxBackoff:  txRetryMaxBackoff,
+		Jitter:      0.2,
+	}
+
+	if _, ok := ctx.Deadline(); ok {
+		// bounded by the deadline
+		p.MaxAttempts = 0
+	}
+
+	if n, ok := RetriesFromContext(ctx); ok {
+		p.MaxAttempts = n + 1
+	}
+
+	for n := 1; ; n++ {
+		err := db.RunInTx(ctx, f)
+		if err == nil || !isSerializationFailure(err) {
+			return err
+		}
+
+		if p.MaxAttempts > 0 && n >= p.MaxAttempts {
+			return err
+		}
+
+		if !p.wait(ctx, n) {
+			return err
+		}
+	}
+}
+
+// isSerializationFailure reports whether err or any error it wraps is a
+// serialization failure or a deadlock
+func isSerializationFailure(err error) bool {
+	return matchError(err, func(err error) bool {
+		return kindOf(err) == ErrSerializationFailure
+	})
+}
@@ -0,0 +1,55 @@
+package ctxdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunInTxRetry(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	calls := 0
+	err := db.RunInTxRetry(ctx, func(ctx context.Context, tx *Tx) error {
+		calls++
+		if calls < 3 {
+			return &codeError{"40001"}
+		}
+
+		_, err := tx.Exec(ctx, "SELECT 1")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got: %d", calls)
+	}
+
+	// other errors are not retried
+	calls = 0
+	other := errors.New("other")
+	err = db.RunInTxRetry(ctx, func(ctx context.Context, tx *Tx) error {
+		calls++
+		return other
+	})
+	if err != other || calls != 1 {
+		t.Fatalf("expected the error without retries, got: %v after %d calls", err, calls)
+	}
+
+	// retries of the ctx bound the attempts
+	calls = 0
+	err = db.RunInTxRetry(WithRetries(ctx, 1), func(ctx context.Context, tx *Tx) error {
+		calls++
+		return &codeError{"40P01"}
+	})
+	if !isSerializationFailure(err) || calls != 2 {
+		t.Fatalf("expected the deadlock after 2 calls, got: %v after %d calls", err, calls)
+	}
+}
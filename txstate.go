@@ -0,0 +1,106 @@
+package ctxdb
+
+import "fmt"
+
+// TxState is the state of a transaction, returned by Tx.State.
+type TxState int
+
+// States of a transaction
+const (
+	// TxActive is an open transaction without a sticky error.
+	TxActive TxState = iota
+
+	// TxCommitted is a successfully committed transaction.
+	TxCommitted
+
+	// TxRolledBack is a transaction rolled back by Rollback, or a failed
+	// Commit.
+	TxRolledBack
+
+	// TxAborted is a transaction rolled back by ctxdb, e.g. when its ctx is
+	// done or it is idle for too long. Err returns the cause.
+	TxAborted
+)
+
+func (s TxState) String() string {
+	switch s {
+	case TxActive:
+		return "active"
+	case TxCommitted:
+		return "committed"
+	case TxRolledBack:
+		return "rolled back"
+	case TxAborted:
+		return "aborted"
+	default:
+		return fmt.Sprintf("TxState(%d)", int(s))
+	}
+}
+
+// Err returns the sticky error of the transaction, the error which aborted
+// it, e.g. the ctx error of a timed out statement or ErrTxIdleTimeout. Nil if
+// the transaction is not aborted. Sticky errors are terminal, since the
+// connection of the transaction is already recycled when they are set; retry
+// frameworks begin a new transaction instead. Nested transactions return the
+// sticky error of their parents too.
+func (tx *Tx) Err() error {
+	if err := tx.check(); err != nil {
+		return err
+	}
+
+	tx.Lock()
+	err := tx.stickyErr
+	tx.Unlock()
+
+	if err == nil && tx.parent != nil {
+		return tx.parent.Err()
+	}
+
+	return err
+}
+
+// State returns the state of the transaction, TxAborted if tx is not
+// initialized.
+func (tx *Tx) State() TxState {
+	if tx.Err() != nil {
+		return TxAborted
+	}
+
+	tx.Lock()
+	defer tx.Unlock()
+
+	switch {
+	case !tx.finished:
+		return TxActive
+	case tx.committed:
+		return TxCommitted
+	default:
+		return TxRolledBack
+	}
+}
+
+// ConnReleased reports whether the connection of the transaction is already
+// put back to the pool or closed, true once the transaction is finished or
+// aborted. Operations of a transaction with a released connection never reach
+// the database.
+func (tx *Tx) ConnReleased() bool {
+	if tx.check() != nil {
+		return true
+	}
+
+	tx.Lock()
+	finished, aborted := tx.finished, tx.stickyErr != nil
+	tx.Unlock()
+
+	// nested transactions share the connection of their parents, it is
+	// released once they are aborted, not when they are finished
+	if aborted {
+		return true
+	}
+
+	if tx.parent != nil {
+		return tx.parent.ConnReleased()
+	}
+
+	return finished
+}
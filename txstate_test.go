@@ -0,0 +1,94 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTxState(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if s := tx.State(); s != TxActive || tx.Err() != nil || tx.ConnReleased() {
+		t.Fatalf("expected an active transaction, got: %s, %v", s, tx.Err())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if s := tx.State(); s != TxCommitted || !tx.ConnReleased() {
+		t.Fatalf("expected a committed transaction, got: %s", s)
+	}
+
+	tx, err = db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if s := tx.State(); s != TxRolledBack || tx.Err() != nil {
+		t.Fatalf("expected a rolled back transaction, got: %s, %v", s, tx.Err())
+	}
+
+	// aborted by the ctx of a statement
+	tx, err = db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	nested, err := tx.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer cancel()
+
+	if _, err := tx.Exec(timeoutCtx, "SELECT pg_sleep(1)"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	if s := tx.State(); s != TxAborted || tx.Err() != context.DeadlineExceeded || !tx.ConnReleased() {
+		t.Fatalf("expected an aborted transaction, got: %s, %v", s, tx.Err())
+	}
+
+	if s := nested.State(); s != TxAborted || !nested.ConnReleased() {
+		t.Fatalf("expected the nested transaction to be aborted, got: %s", s)
+	}
+
+	// sticky error of the nested transaction releases its connection
+	tx, err = db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	nested, err = tx.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	nested.Lock()
+	nested.stickyErr = context.Canceled
+	nested.Unlock()
+
+	if !nested.ConnReleased() || tx.ConnReleased() {
+		t.Fatal("expected the connection of the nested transaction to be released")
+	}
+
+	var nilTx *Tx
+	if s := nilTx.State(); s != TxAborted || nilTx.Err() != ErrNotInitialized {
+		t.Fatalf("expected ErrNotInitialized, got: %s, %v", s, nilTx.Err())
+	}
+}
@@ -0,0 +1,28 @@
+package ctxdb
+
+import "database/sql"
+
+// Unwrap returns the underlying *sql.Tx of the transaction, for the driver
+// specific extensions like pq.CopyIn, nil if tx is not initialized. Operations
+// on it run on the connection of the transaction, without the timeouts and the
+// sticky errors of ctxdb; it must not be committed or rolled back, use the
+// Commit and Rollback of tx instead. See Raw for the operations needing a
+// *sql.DB.
+func (tx *Tx) Unwrap() *sql.Tx {
+	if tx.check() != nil {
+		return nil
+	}
+
+	return tx.tx
+}
+
+// Unwrap returns the underlying *sql.Rows, nil if rs is nil or the query
+// failed. Rows must still be closed with the Close of rs, so the connection is
+// put back to the pool.
+func (rs *Rows) Unwrap() *sql.Rows {
+	if rs == nil {
+		return nil
+	}
+
+	return rs.rows
+}
@@ -0,0 +1,42 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUnwrap(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var n int
+	if err := tx.Unwrap().QueryRow("SELECT 1").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("expected 1, got: %d, %v", n, err)
+	}
+
+	rows, err := tx.Query(ctx, "SELECT 2")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer rows.Close(ctx)
+
+	if !rows.Unwrap().Next() {
+		t.Fatalf("expected a row, got: %v", rows.Unwrap().Err())
+	}
+
+	if err := rows.Unwrap().Scan(&n); err != nil || n != 2 {
+		t.Fatalf("expected 2, got: %d, %v", n, err)
+	}
+
+	var nilTx *Tx
+	if nilTx.Unwrap() != nil {
+		t.Fatal("expected nil")
+	}
+}
@@ -0,0 +1,93 @@
+package ctxdb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SetWarmup sets the warm-up queries run on every new connection before it is
+// used, e.g. touching the hot tables. It does not create any connections, see
+// Prefill for that. Warm-up is best-effort, errors of the
+// queries are ignored. Queries should be lightweight, since they run in the
+// path of the operation which required the new connection; they are bounded by
+// its ctx and a short timeout, and the connection is dropped if they do not
+// finish in time.
+func (db *DB) SetWarmup(queries ...string) {
+	if db == nil {
+		return
+	}
+
+	db.mu.Lock()
+	db.warmup = queries
+	db.mu.Unlock()
+}
+
+// warm runs the warm-up queries on the given connection
+func (db *DB) warm(ctx context.Context, conn *sql.DB) {
+	db.mu.Lock()
+	queries := db.warmup
+	db.mu.Unlock()
+
+	for _, query := range queries {
+		// best-effort, errors are ignored
+		dbExec(ctx, conn, query, nil)
+	}
+}
+
+// Prefill eagerly creates and pings n connections, putting them to the pool,
+// so an unreachable database is detected at startup instead of on the first
+// operation. The warm-up queries set with SetWarmup are run on each of them.
+// n is capped by the max idle conns, since the extra connections would not be
+// kept. Returns the first error, the connections created until then are kept.
+func (db *DB) Prefill(ctx context.Context, n int) error {
+	if err := db.check(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	if n > db.maxIdleConns {
+		n = db.maxIdleConns
+	}
+	db.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		if err := db.prefillConn(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prefillConn creates and pings a connection while holding a sem, and puts it
+// to the pool
+func (db *DB) prefillConn(ctx context.Context) error {
+	if db.getConns() == nil {
+		return ErrClosed
+	}
+
+	if err := db.acquire(ctx); err != nil {
+		return err
+	}
+
+	conn, err := db.newConn(ctx)
+	if err != nil {
+		db.release()
+		return err
+	}
+
+	done := make(chan struct{}, 1)
+	var pingErr error
+	f := func() {
+		pingErr = dbPing(ctx, conn)
+		close(done)
+	}
+
+	err = db.handleWithGivenSQL(ctx, f, done, conn)
+	if err == nil {
+		err = pingErr
+	}
+
+	// gives the sem back, closing the conn on error
+	return db.restoreOrClose(err, conn)
+}
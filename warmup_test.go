@@ -0,0 +1,91 @@
+package ctxdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSetWarmup(t *testing.T) {
+	db := getConn(t)
+	ensureNullableTable(t, db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+
+	if err := db.Close(ctx); err != nil {
+		t.Fatalf("Err while closing the connection: %# v", err)
+	}
+
+	db = getConn(t)
+	db.SetWarmup(
+		"SELECT * FROM non_existing", // errors are ignored
+		"INSERT INTO nullable VALUES (NULL, 'NULLABLE', NULL, 1, NULL, 1, NULL, true, NULL, NOW())",
+	)
+
+	var count int64
+	if err := db.QueryRow(ctx, "SELECT COUNT(*) FROM nullable").Scan(ctx, &count); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected warm-up to run once, got: %d", count)
+	}
+
+	if _, err := db.Exec(ctx, deleteSQLStatement); err != nil {
+		t.Fatalf("err while cleaning the database: %s", err.Error())
+	}
+}
+
+func TestSetWarmupBoundedByCtx(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	db.SetWarmup("SELECT pg_sleep(1)")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	if err := db.Ping(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	// connection of the timed out warm-up is dropped
+	if s := db.PoolStats(); s.InUse != 0 || s.OpenConns != 0 {
+		t.Fatalf("expected the connection to be dropped, got: %+v", s)
+	}
+}
+
+func TestPrefill(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx := context.Background()
+
+	if err := db.Prefill(ctx, maxOpenConns+1); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if n := len(db.getConns()); n != maxOpenConns {
+		t.Fatalf("expected %d idle connections, got: %d", maxOpenConns, n)
+	}
+}
+
+func TestPrefillUnreachable(t *testing.T) {
+	db, err := Open(os.Getenv("NISQL_TEST_DIALECT"), "postgres://localhost:1/ctxdb?sslmode=disable")
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+	defer db.Close(context.Background())
+
+	if err := db.Prefill(context.Background(), 1); err == nil {
+		t.Fatal("expected an error for the unreachable database, got nil")
+	}
+
+	if n := len(db.getConns()); n != 0 {
+		t.Fatalf("expected no idle connections, got: %d", n)
+	}
+}
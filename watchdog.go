@@ -0,0 +1,51 @@
+package ctxdb
+
+import "context"
+
+// watchdog starts the watchdog of the transaction, which rolls it back and
+// recycles its connection when the ctx of Begin is done before the
+// transaction is finished. Later operations of the transaction fail with the
+// ctx error as a sticky error.
+func (tx *Tx) watchdog(ctx context.Context) {
+	if ctx.Done() == nil {
+		// never done
+		return
+	}
+
+	tx.done = make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			tx.abort(ctx.Err())
+		case <-tx.done:
+		}
+	}()
+}
+
+// abort rolls back the transaction with the given sticky error, unless it is
+// finished or already failed
+func (tx *Tx) abort(err error) {
+	tx.Lock()
+	defer tx.Unlock()
+
+	tx.abortLocked(err, false)
+}
+
+// abortLocked rolls back the transaction with the given sticky error, unless
+// it is finished or already failed. Connection is closed instead of recycled if
+// closeConn is set.
+func (tx *Tx) abortLocked(err error, closeConn bool) {
+	if tx.finished || tx.stickyErr != nil {
+		return
+	}
+
+	tx.stickyErr = err
+	if closeConn {
+		tx.tx.Rollback()
+		tx.db.restoreOrClose(err, tx.sqldb)
+	} else if shutdownErr := tx.shutdown(); shutdownErr != nil {
+		tx.stickyErr = shutdownErr
+	}
+
+	tx.finish(false, tx.stickyErr)
+}
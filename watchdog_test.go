@@ -0,0 +1,61 @@
+package ctxdb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTxWatchdog(t *testing.T) {
+	db := getConn(t)
+	defer db.Close(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if _, err := tx.Exec(context.Background(), "SELECT 1"); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if s := db.PoolStats(); s.InUse != 1 {
+		t.Fatalf("expected the connection of the transaction in use, got: %+v", s)
+	}
+
+	// request is canceled between the statements
+	cancel()
+	time.Sleep(time.Millisecond * 100)
+
+	if s := db.PoolStats(); s.InUse != 0 {
+		t.Fatalf("expected the connection to be recycled, got: %+v", s)
+	}
+
+	if _, err := tx.Exec(context.Background(), "SELECT 1"); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+
+	if err := tx.Rollback(context.Background()); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+
+	// finished transactions are not rolled back
+	ctx, cancel = context.WithCancel(context.Background())
+	tx, err = db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("expected nil, got: %s", err)
+	}
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+
+	if s := db.PoolStats(); s.InUse != 0 || s.Idle != 1 {
+		t.Fatalf("expected the connection back in the pool, got: %+v", s)
+	}
+}